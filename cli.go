@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"vault/internal/services"
+)
+
+// runCommand handles administrative subcommands, e.g.
+//
+//	ADMIN_PASSWORD=... go run . create-admin admin@example.com
+//
+// Without ADMIN_PASSWORD an existing user is promoted instead.
+func runCommand(args []string, admin *services.AdminService, audit *services.AuditService) error {
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = audit.Shutdown(ctx)
+	}()
+
+	switch args[0] {
+	case "create-admin":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: create-admin <email>")
+		}
+		id, err := admin.Bootstrap(args[1], os.Getenv("ADMIN_PASSWORD"))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("user %d (%s) is now an administrator\n", id, args[1])
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
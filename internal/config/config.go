@@ -4,26 +4,98 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Port          string
-	DBPath        string
-	JWTSecret     string
-	EncryptionKey string
-	TokenTTL      time.Duration
+	Port           string
+	DBPath         string
+	JWTSecret      string
+	EncryptionKey  string
+	TokenTTL       time.Duration
 	WorkerPoolSize int
+	// ReauthWindow is how long a login counts as recent for sensitive entries
+	ReauthWindow time.Duration
+	// EntryHistoryLimit is how many earlier versions are kept per entry
+	EntryHistoryLimit int
+	// TrashRetention is how long deleted entries stay in the trash
+	TrashRetention time.Duration
+	// PassphraseWordlist is a diceware wordlist used instead of the built-in
+	// EFF large wordlist
+	PassphraseWordlist string
+
+	// OIDC login is enabled when OIDCIssuer is set
+	OIDCIssuer         string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string
+	OIDCAllowedDomains []string
+
+	// AuthBackend is the default credential backend: "local" or "ldap"
+	AuthBackend        string
+	LDAPURL            string
+	LDAPStartTLS       bool
+	LDAPBindDN         string
+	LDAPBindPassword   string
+	LDAPUserDNTemplate string
+	LDAPBaseDN         string
+	LDAPUserFilter     string
+	LDAPEmailAttribute string
+	LDAPRequiredGroup  string
+
+	WebAuthnRPID      string
+	WebAuthnRPName    string
+	WebAuthnRPOrigins []string
+
+	// SendBaseURL prefixes one-time secret links; it should serve a page that
+	// reads the key from the fragment and calls POST /api/send/:id/open
+	SendBaseURL string
+
+	// NotifyWebhookURL receives notifications as JSON posts; when empty they
+	// are only logged
+	NotifyWebhookURL string
 }
 
 func Load() (Config, error) {
 	cfg := Config{
-		Port:          getEnv("PORT", ":8080"),
-		DBPath:        getEnv("DB_PATH", "./data/vault.db"),
-		JWTSecret:     os.Getenv("JWT_SECRET"),
-		EncryptionKey: os.Getenv("VAULT_ENC_KEY"),
-		TokenTTL:      parseDurationMinutes(getEnv("TOKEN_TTL_MIN", "60")),
+		Port:           getEnv("PORT", ":8080"),
+		DBPath:         getEnv("DB_PATH", "./data/vault.db"),
+		JWTSecret:      os.Getenv("JWT_SECRET"),
+		EncryptionKey:  os.Getenv("VAULT_ENC_KEY"),
+		TokenTTL:       parseDurationMinutes(getEnv("TOKEN_TTL_MIN", "60")),
 		WorkerPoolSize: parseInt(getEnv("WORKER_POOL_SIZE", "8"), 8),
+		ReauthWindow:   time.Duration(parseInt(getEnv("REAUTH_WINDOW_MIN", "5"), 5)) * time.Minute,
+
+		EntryHistoryLimit: parseInt(getEnv("ENTRY_HISTORY_LIMIT", "20"), 20),
+		TrashRetention:    time.Duration(parseInt(getEnv("TRASH_RETENTION_DAYS", "30"), 30)) * 24 * time.Hour,
+
+		PassphraseWordlist: os.Getenv("PASSPHRASE_WORDLIST"),
+
+		OIDCIssuer:         strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		OIDCClientID:       os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:    os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCAllowedDomains: parseList(os.Getenv("OIDC_ALLOWED_DOMAINS")),
+
+		AuthBackend:        getEnv("AUTH_BACKEND", "local"),
+		LDAPURL:            os.Getenv("LDAP_URL"),
+		LDAPStartTLS:       os.Getenv("LDAP_START_TLS") == "true",
+		LDAPBindDN:         os.Getenv("LDAP_BIND_DN"),
+		LDAPBindPassword:   os.Getenv("LDAP_BIND_PASSWORD"),
+		LDAPUserDNTemplate: os.Getenv("LDAP_USER_DN_TEMPLATE"),
+		LDAPBaseDN:         os.Getenv("LDAP_BASE_DN"),
+		LDAPUserFilter:     getEnv("LDAP_USER_FILTER", "(uid=%s)"),
+		LDAPEmailAttribute: getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		LDAPRequiredGroup:  os.Getenv("LDAP_REQUIRED_GROUP"),
+
+		WebAuthnRPID:      getEnv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPName:    getEnv("WEBAUTHN_RP_NAME", "Password Vault"),
+		WebAuthnRPOrigins: parseList(getEnv("WEBAUTHN_RP_ORIGINS", "http://localhost:8080")),
+
+		SendBaseURL: getEnv("SEND_BASE_URL", "http://localhost:8080"),
+
+		NotifyWebhookURL: os.Getenv("NOTIFY_WEBHOOK_URL"),
 	}
 
 	if cfg.JWTSecret == "" {
@@ -32,6 +104,26 @@ func Load() (Config, error) {
 	if cfg.EncryptionKey == "" {
 		return Config{}, errors.New("VAULT_ENC_KEY is required")
 	}
+	if cfg.OIDCIssuer != "" && (cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "") {
+		return Config{}, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER is set")
+	}
+	switch cfg.AuthBackend {
+	case "local":
+	case "ldap":
+		if cfg.LDAPURL == "" {
+			return Config{}, errors.New("LDAP_URL is required when AUTH_BACKEND=ldap")
+		}
+	default:
+		return Config{}, errors.New("AUTH_BACKEND must be local or ldap")
+	}
+	if cfg.LDAPURL != "" {
+		if cfg.LDAPBaseDN == "" {
+			return Config{}, errors.New("LDAP_BASE_DN is required when LDAP_URL is set")
+		}
+		if cfg.LDAPBindDN == "" && cfg.LDAPUserDNTemplate == "" {
+			return Config{}, errors.New("LDAP_BIND_DN or LDAP_USER_DN_TEMPLATE is required when LDAP_URL is set")
+		}
+	}
 
 	return cfg, nil
 }
@@ -58,3 +150,14 @@ func parseInt(value string, fallback int) int {
 	}
 	return parsed
 }
+
+// parseList splits a comma-separated value, dropping empty items.
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
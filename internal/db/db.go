@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"vault/internal/config"
 )
@@ -27,12 +29,61 @@ func Open(cfg config.Config) (*sql.DB, error) {
 
 }
 
+// Migrate applies every migrations/*.sql file in name order, recording
+// applied files in schema_migrations so each one runs exactly once.
 func Migrate(db *sql.DB) error {
-	migration, err := os.ReadFile("migrations/001_init.sql")
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec(string(migration))
-	return err
+	files, err := filepath.Glob("migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var applied int
+		if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE name = ?", name).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := applyMigration(db, name, string(migration)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, name, migration string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (name, applied_at) VALUES (?, ?)",
+		name,
+		time.Now().UTC().Format(time.RFC3339),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
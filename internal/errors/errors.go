@@ -23,6 +23,16 @@ const (
 	ErrInvalidInput   = "INVALID_INPUT"
 	ErrDatabaseError  = "DATABASE_ERROR"
 	ErrEncryptionFail = "ENCRYPTION_FAILED"
+	ErrReauthRequired = "REAUTH_REQUIRED"
+	ErrForbidden      = "FORBIDDEN"
+	// ErrApprovalRequired means the entry can only be read with an approved access request
+	ErrApprovalRequired = "APPROVAL_REQUIRED"
+	// ErrCheckoutRequired means the entry can only be read while checked out
+	ErrCheckoutRequired = "CHECKOUT_REQUIRED"
+	ErrConflict         = "CONFLICT"
+	// ErrKeyLocked means a shared entry can only be read after unlocking the
+	// recipient's key pair with their key passphrase
+	ErrKeyLocked = "KEY_LOCKED"
 )
 
 func NewVaultError(code, message string) *VaultError {
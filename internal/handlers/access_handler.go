@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type accessRequestBody struct {
+	Justification   string `json:"justification"`
+	DurationMinutes int    `json:"durationMinutes"`
+}
+
+type accessDecisionBody struct {
+	Note string `json:"note"`
+}
+
+func accessError(c *fiber.Ctx, err error, fallback string) error {
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+func (h *Handler) RequestAccess(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req accessRequestBody
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.accessFor(c).Request(userID, id, req.Justification, time.Duration(req.DurationMinutes)*time.Minute)
+	})
+	if err != nil {
+		return accessError(c, err, "could not request access")
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) ListAccessRequests(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.access.ListMine(userID)
+	})
+	if err != nil {
+		return accessError(c, err, "could not load access requests")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) ListPendingAccessRequests(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.access.ListPending(userID)
+	})
+	if err != nil {
+		return accessError(c, err, "could not load access requests")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) ApproveAccessRequest(c *fiber.Ctx) error {
+	return h.decideAccessRequest(c, true)
+}
+
+func (h *Handler) DenyAccessRequest(c *fiber.Ctx) error {
+	return h.decideAccessRequest(c, false)
+}
+
+func (h *Handler) decideAccessRequest(c *fiber.Ctx, approve bool) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	// The note is optional, so an empty body is fine
+	var req accessDecisionBody
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+		}
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		if approve {
+			return h.access.Approve(userID, id, req.Note)
+		}
+		return h.access.Deny(userID, id, req.Note)
+	})
+	if err != nil {
+		return accessError(c, err, "could not update access request")
+	}
+
+	return c.JSON(res)
+}
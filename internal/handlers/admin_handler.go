@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vault/internal/services"
+)
+
+func (h *Handler) AdminListUsers(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.admin.ListUsers(adminID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load users"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) AdminDisableUser(c *fiber.Ctx) error {
+	return h.adminAction(c, h.admin.DisableUser)
+}
+
+func (h *Handler) AdminEnableUser(c *fiber.Ctx) error {
+	return h.adminAction(c, h.admin.EnableUser)
+}
+
+func (h *Handler) AdminDeleteUser(c *fiber.Ctx) error {
+	return h.adminAction(c, h.admin.DeleteUser)
+}
+
+func (h *Handler) AdminForceLogout(c *fiber.Ctx) error {
+	return h.adminAction(c, h.admin.ForceLogout)
+}
+
+func (h *Handler) AdminResetMFA(c *fiber.Ctx) error {
+	return h.adminAction(c, h.admin.ResetMFA)
+}
+
+func (h *Handler) AdminUserStats(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	userID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.admin.UserStats(adminID, userID)
+	})
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	return c.JSON(res)
+}
+
+// AdminSetAuthBackend pins a user to {"backend":"local"|"ldap"}, or to the
+// default with an empty backend.
+func (h *Handler) AdminSetAuthBackend(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	userID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req struct {
+		Backend string `json:"backend"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	// Pinning to a backend that is not configured would lock the user out
+	if req.Backend != "" && !h.auth.HasBackend(req.Backend) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "auth backend " + req.Backend + " is not configured"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.admin.SetAuthBackend(adminID, userID, req.Backend)
+	})
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// adminAction runs a user-management action that targets :id and has no body
+func (h *Handler) adminAction(c *fiber.Ctx, action func(adminID, userID int64) error) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	userID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, action(adminID, userID)
+	})
+	if err != nil {
+		return adminError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func adminError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrUserNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
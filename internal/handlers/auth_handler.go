@@ -7,11 +7,14 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"modernc.org/sqlite"
+
+	"vault/internal/services"
 )
 
 type authRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string   `json:"email"`
+	Password string   `json:"password"`
+	Scopes   []string `json:"scopes"`
 }
 
 func (h *Handler) Register(c *fiber.Ctx) error {
@@ -44,7 +47,12 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 	}
 
 	res, err := h.runInPool(c.UserContext(), func() (any, error) {
-		token, user, err := h.auth.Login(req.Email, req.Password)
+		token, user, err := h.auth.Login(req.Email, req.Password, req.Scopes)
+		var mfa *services.MFARequiredError
+		if errors.As(err, &mfa) {
+			// Finish with /api/auth/webauthn/login/begin and /finish
+			return fiber.Map{"mfaRequired": true, "mfaToken": mfa.Token}, nil
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -80,3 +88,39 @@ func isUniqueViolation(err error) bool {
 	msg := strings.ToLower(err.Error())
 	return strings.Contains(msg, "unique constraint failed")
 }
+
+type reauthRequest struct {
+	Password string `json:"password"`
+}
+
+// Reauth exchanges the current token for one with a fresh auth_time after
+// re-checking the password. Signing in again with a passkey works as well.
+func (h *Handler) Reauth(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req reauthRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	scopes := scopesFromToken(c)
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		token, err := h.auth.Reauthenticate(userID, req.Password, scopes)
+		var mfa *services.MFARequiredError
+		if errors.As(err, &mfa) {
+			return fiber.Map{"mfaRequired": true, "mfaToken": mfa.Token}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{"token": token}, nil
+	})
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+	}
+
+	return c.JSON(res)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type checkoutRequest struct {
+	LeaseMinutes int `json:"leaseMinutes"`
+}
+
+func checkoutError(c *fiber.Ctx, err error, fallback string) error {
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if isApprovalRequired(err) {
+		return approvalRequired(c)
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+func (h *Handler) GetCheckout(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.checkoutsFor(c).Status(userID, id)
+	})
+	if err != nil {
+		return checkoutError(c, err, "could not load checkout")
+	}
+
+	return c.JSON(fiber.Map{"checkout": res})
+}
+
+func (h *Handler) CheckOutEntry(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	// The lease is optional, so an empty body is fine
+	var req checkoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+		}
+	}
+
+	authTime := authTimeFromToken(c)
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.checkoutsFor(c).CheckOut(userID, id, time.Duration(req.LeaseMinutes)*time.Minute, authTime)
+	})
+	if err != nil {
+		return checkoutError(c, err, "could not check out entry")
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) CheckInEntry(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.checkoutsFor(c).CheckIn(userID, id)
+	})
+	if err != nil {
+		return checkoutError(c, err, "could not check in entry")
+	}
+
+	return c.JSON(res)
+}
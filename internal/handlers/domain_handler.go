@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type domainGroupRequest struct {
+	Domains []string `json:"domains"`
+}
+
+func domainError(c *fiber.Ctx, err error, fallback string) error {
+	if isUniqueViolation(err) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "a domain is already in another group"})
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// MatchEntries lists the entries to offer for autofill on ?url=.
+func (h *Handler) MatchEntries(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	pageURL := c.Query("url")
+	if pageURL == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "url required"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.domainsFor(c).Match(userID, pageURL)
+	})
+	if err != nil {
+		return domainError(c, err, "could not match entries")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) ListDomainGroups(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.domains.Groups(userID)
+	})
+	if err != nil {
+		return domainError(c, err, "could not load domain groups")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) CreateDomainGroup(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req domainGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.domains.CreateGroup(userID, req.Domains)
+	})
+	if err != nil {
+		return domainError(c, err, "could not create domain group")
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) DeleteDomainGroup(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.domains.DeleteGroup(userID, id)
+	})
+	if err != nil {
+		return domainError(c, err, "could not delete domain group")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
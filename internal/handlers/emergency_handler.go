@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type emergencyContactRequest struct {
+	Email    string `json:"email"`
+	WaitDays int    `json:"waitDays"`
+}
+
+type emergencyWaitRequest struct {
+	WaitDays int `json:"waitDays"`
+}
+
+// emergencyParams reads the caller and the :id contact from the request. On
+// failure it has already written the error response.
+func emergencyParams(c *fiber.Ctx) (userID, contactID int64, ok bool) {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		_ = c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	contactID, err = strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		_ = c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return userID, contactID, true
+}
+
+func emergencyError(c *fiber.Ctx, err error, fallback string) error {
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+func (h *Handler) ListEmergencyContacts(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.emergency.ListContacts(userID)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not load contacts")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) AddEmergencyContact(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req emergencyContactRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.emergency.AddContact(userID, req.Email, req.WaitDays)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not add contact")
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) UpdateEmergencyContact(c *fiber.Ctx) error {
+	userID, id, ok := emergencyParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req emergencyWaitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.emergency.SetWaitDays(userID, id, req.WaitDays)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not update contact")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteEmergencyContact(c *fiber.Ctx) error {
+	userID, id, ok := emergencyParams(c)
+	if !ok {
+		return nil
+	}
+
+	_, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.emergency.RemoveContact(userID, id)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not remove contact")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) ApproveEmergencyAccess(c *fiber.Ctx) error {
+	userID, id, ok := emergencyParams(c)
+	if !ok {
+		return nil
+	}
+
+	_, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.emergency.Approve(userID, id)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not approve request")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) RejectEmergencyAccess(c *fiber.Ctx) error {
+	userID, id, ok := emergencyParams(c)
+	if !ok {
+		return nil
+	}
+
+	_, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.emergency.Reject(userID, id)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not reject request")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) ListEmergencyGrants(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.emergency.ListGrants(userID)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not load grants")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) RequestEmergencyAccess(c *fiber.Ctx) error {
+	userID, id, ok := emergencyParams(c)
+	if !ok {
+		return nil
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.emergency.Request(userID, id)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not request access")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) ListEmergencyEntries(c *fiber.Ctx) error {
+	userID, id, ok := emergencyParams(c)
+	if !ok {
+		return nil
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.emergency.Entries(userID, id)
+	})
+	if err != nil {
+		return emergencyError(c, err, "could not load entries")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) GetEmergencyEntry(c *fiber.Ctx) error {
+	userID, id, ok := emergencyParams(c)
+	if !ok {
+		return nil
+	}
+
+	entryID, err := strconv.ParseInt(c.Params("entryId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid entry id"})
+	}
+
+	authTime := authTimeFromToken(c)
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.emergency.Entry(userID, id, entryID, authTime)
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if err != nil {
+		return emergencyError(c, err, "could not load entry")
+	}
+
+	return c.JSON(res)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type folderRequest struct {
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parentId"`
+}
+
+type folderMoveRequest struct {
+	ParentID *int64 `json:"parentId"`
+}
+
+type entryFolderRequest struct {
+	FolderID *int64 `json:"folderId"`
+}
+
+func folderError(c *fiber.Ctx, err error, fallback string) error {
+	if isUniqueViolation(err) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "a folder with that name already exists here"})
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// ListFolders returns the caller's folder tree with entry counts.
+func (h *Handler) ListFolders(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.folders.Tree(userID)
+	})
+	if err != nil {
+		return folderError(c, err, "could not load folders")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) CreateFolder(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req folderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.folders.Create(userID, req.Name, req.ParentID)
+	})
+	if err != nil {
+		return folderError(c, err, "could not create folder")
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) RenameFolder(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req folderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.folders.Rename(userID, id, req.Name)
+	})
+	if err != nil {
+		return folderError(c, err, "could not rename folder")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) MoveFolder(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req folderMoveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.folders.Move(userID, id, req.ParentID)
+	})
+	if err != nil {
+		return folderError(c, err, "could not move folder")
+	}
+
+	return c.JSON(res)
+}
+
+// DeleteFolder removes a folder and its subfolders, moving their entries to
+// the trash.
+func (h *Handler) DeleteFolder(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.foldersFor(c).Delete(userID, id)
+	})
+	if err != nil {
+		return folderError(c, err, "could not delete folder")
+	}
+
+	return c.JSON(fiber.Map{"trashed": res.(int)})
+}
+
+// SetEntryFolder files an entry in a folder, or unfiles it with null.
+func (h *Handler) SetEntryFolder(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req entryFolderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.vaultFor(c).SetFolder(userID, id, req.FolderID)
+	})
+	if err != nil {
+		return folderError(c, err, "could not file entry")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
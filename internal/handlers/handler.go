@@ -3,17 +3,67 @@ package handlers
 import (
 	"context"
 
+	"github.com/gofiber/fiber/v2"
+
 	"vault/internal/services"
 )
 
 type Handler struct {
-	auth  *services.AuthService
-	vault *services.VaultService
-	pool  *services.WorkerPool
+	auth      *services.AuthService
+	vault     *services.VaultService
+	tokens    *services.TokenService
+	oidc      *services.OIDCService
+	webauthn  *services.WebAuthnService
+	admin     *services.AdminService
+	orgs      *services.OrgService
+	shares    *services.ShareService
+	keys      *services.KeyService
+	sends     *services.SendService
+	emergency *services.EmergencyService
+	access    *services.AccessService
+	checkouts *services.CheckoutService
+	policies  *services.PolicyService
+	folders   *services.FolderService
+	domains   *services.DomainService
+	generator *services.PasswordGenerator
+	pool      *services.WorkerPool
+}
+
+// NewHandler wires the services used by the HTTP layer. oidc may be nil when
+// single sign-on is not configured; its routes are then not registered.
+func NewHandler(auth *services.AuthService, vault *services.VaultService, tokens *services.TokenService, oidc *services.OIDCService, webauthn *services.WebAuthnService, admin *services.AdminService, orgs *services.OrgService, shares *services.ShareService, keys *services.KeyService, sends *services.SendService, emergency *services.EmergencyService, access *services.AccessService, checkouts *services.CheckoutService, policies *services.PolicyService, folders *services.FolderService, domains *services.DomainService, generator *services.PasswordGenerator, pool *services.WorkerPool) *Handler {
+	return &Handler{auth: auth, vault: vault, tokens: tokens, oidc: oidc, webauthn: webauthn, admin: admin, orgs: orgs, shares: shares, keys: keys, sends: sends, emergency: emergency, access: access, checkouts: checkouts, policies: policies, folders: folders, domains: domains, generator: generator, pool: pool}
+}
+
+// vaultFor returns the vault service for the caller. Requests made with an
+// API token also get the policies attached to that token; the helpers below
+// do the same for the services built on VaultService.
+func (h *Handler) vaultFor(c *fiber.Ctx) *services.VaultService {
+	return h.vault.ForToken(tokenIDFromToken(c))
+}
+
+func (h *Handler) sharesFor(c *fiber.Ctx) *services.ShareService {
+	return h.shares.ForToken(tokenIDFromToken(c))
+}
+
+func (h *Handler) sendsFor(c *fiber.Ctx) *services.SendService {
+	return h.sends.ForToken(tokenIDFromToken(c))
+}
+
+func (h *Handler) checkoutsFor(c *fiber.Ctx) *services.CheckoutService {
+	return h.checkouts.ForToken(tokenIDFromToken(c))
+}
+
+func (h *Handler) accessFor(c *fiber.Ctx) *services.AccessService {
+	return h.access.ForToken(tokenIDFromToken(c))
+}
+
+func (h *Handler) foldersFor(c *fiber.Ctx) *services.FolderService {
+	return h.folders.ForToken(tokenIDFromToken(c))
 }
 
-func NewHandler(auth *services.AuthService, vault *services.VaultService, pool *services.WorkerPool) *Handler {
-	return &Handler{auth: auth, vault: vault, pool: pool}
+func (h *Handler) domainsFor(c *fiber.Ctx) *services.DomainService {
+	return h.domains.ForToken(tokenIDFromToken(c))
 }
 
 func (h *Handler) runInPool(ctx context.Context, job func() (any, error)) (any, error) {
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
@@ -27,6 +29,8 @@ func userIDFromToken(c *fiber.Ctx) (int64, error) {
 	}
 
 	switch value := sub.(type) {
+	case int64:
+		return value, nil
 	case float64:
 		return int64(value), nil
 	case string:
@@ -39,3 +43,55 @@ func userIDFromToken(c *fiber.Ctx) (int64, error) {
 		return 0, errors.New("invalid sub type")
 	}
 }
+
+func scopesFromToken(c *fiber.Ctx) []string {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok || token == nil {
+		return nil
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+	scope, _ := claims["scope"].(string)
+	return strings.Fields(scope)
+}
+
+// tokenIDFromToken returns the id of the API token the caller authenticated
+// with, or 0 for JWT sessions.
+func tokenIDFromToken(c *fiber.Ctx) int64 {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok || token == nil {
+		return 0
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0
+	}
+	switch tid := claims["tid"].(type) {
+	case int64:
+		return tid
+	case float64:
+		return int64(tid)
+	default:
+		return 0
+	}
+}
+
+// authTimeFromToken returns when the caller last presented credentials, or
+// the zero time for tokens without an auth_time claim such as API tokens.
+func authTimeFromToken(c *fiber.Ctx) time.Time {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok || token == nil {
+		return time.Time{}
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return time.Time{}
+	}
+	authTime, ok := claims["auth_time"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(authTime), 0)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type keyPassphraseRequest struct {
+	CurrentPassphrase string `json:"currentPassphrase"`
+	Passphrase        string `json:"passphrase"`
+}
+
+type keyUnlockRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+func (h *Handler) GetKeyStatus(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.keys.Status(userID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load key pair"})
+	}
+
+	return c.JSON(res)
+}
+
+// SetKeyPassphrase creates the caller's key pair or changes the passphrase
+// protecting it.
+func (h *Handler) SetKeyPassphrase(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req keyPassphraseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	authTime := authTimeFromToken(c)
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.keys.SetPassphrase(userID, req.CurrentPassphrase, req.Passphrase, authTime)
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not set key passphrase"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) UnlockKey(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req keyUnlockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.keys.Unlock(userID, req.Passphrase)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not unlock key pair"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) LockKey(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	h.keys.Lock(userID)
+	return c.SendStatus(http.StatusNoContent)
+}
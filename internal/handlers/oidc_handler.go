@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// oidcStateCookie ties a pending single sign-on to the browser that started
+// it, so a callback URL from someone else's login is refused.
+const oidcStateCookie = "vault_oidc_state"
+
+// OIDCLogin redirects the browser to the identity provider.
+func (h *Handler) OIDCLogin(c *fiber.Ctx) error {
+	type login struct{ url, state string }
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		url, state, err := h.oidc.LoginURL()
+		if err != nil {
+			return nil, err
+		}
+		return login{url, state}, nil
+	})
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "identity provider unavailable"})
+	}
+
+	l := res.(login)
+	setOIDCStateCookie(c, l.state, time.Time{})
+	return c.Redirect(l.url, http.StatusFound)
+}
+
+// OIDCCallback receives the authorization code from the identity provider
+// and answers with the same payload as Login.
+func (h *Handler) OIDCCallback(c *fiber.Ctx) error {
+	boundState := c.Cookies(oidcStateCookie)
+	setOIDCStateCookie(c, "", time.Unix(0, 0))
+
+	if errCode := c.Query("error"); errCode != "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": errCode, "details": c.Query("error_description")})
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if boundState == "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "single sign-on failed"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		token, user, err := h.oidc.Callback(state, boundState, code)
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{
+			"token": token,
+			"user": fiber.Map{
+				"id":    user.ID,
+				"email": user.Email,
+			},
+		}, nil
+	})
+	if err != nil {
+		log.Printf("oidc callback: %v", err)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "single sign-on failed"})
+	}
+
+	return c.JSON(res)
+}
+
+// setOIDCStateCookie writes the state cookie; a zero expiry keeps it for the
+// browser session and a past one deletes it.
+func setOIDCStateCookie(c *fiber.Ctx, state string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
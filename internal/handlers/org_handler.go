@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type orgRequest struct {
+	Name string `json:"name"`
+}
+
+type orgMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type collectionTeamsRequest struct {
+	TeamIDs []int64 `json:"teamIds"`
+}
+
+func (h *Handler) ListOrgs(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.orgs.List(userID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load organizations"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) CreateOrg(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req orgRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.orgs.Create(userID, req.Name)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"id": res.(int64)})
+}
+
+func (h *Handler) GetOrg(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		org, err := h.orgs.Get(userID, orgID)
+		if err != nil {
+			return nil, err
+		}
+		members, err := h.orgs.Members(userID, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{"organization": org, "members": members}, nil
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) RenameOrg(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req orgRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.Rename(userID, orgID, req.Name)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteOrg(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	_, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.Delete(userID, orgID)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) AddOrgMember(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req orgMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.orgs.AddMember(userID, orgID, req.Email, req.Role)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"userId": res.(int64)})
+}
+
+func (h *Handler) UpdateOrgMember(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	memberID, err := strconv.ParseInt(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	var req orgMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.SetMemberRole(userID, orgID, memberID, req.Role)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) RemoveOrgMember(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	memberID, err := strconv.ParseInt(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.RemoveMember(userID, orgID, memberID)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) ListTeams(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.orgs.Teams(userID, orgID)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) CreateTeam(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req orgRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.orgs.CreateTeam(userID, orgID, req.Name)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"id": res.(int64)})
+}
+
+func (h *Handler) DeleteTeam(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	teamID, err := strconv.ParseInt(c.Params("teamId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid team id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.DeleteTeam(userID, orgID, teamID)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) AddTeamMember(c *fiber.Ctx) error {
+	return h.teamMemberAction(c, h.orgs.AddTeamMember)
+}
+
+func (h *Handler) RemoveTeamMember(c *fiber.Ctx) error {
+	return h.teamMemberAction(c, h.orgs.RemoveTeamMember)
+}
+
+func (h *Handler) teamMemberAction(c *fiber.Ctx, action func(userID, orgID, teamID, memberID int64) error) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	teamID, err := strconv.ParseInt(c.Params("teamId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid team id"})
+	}
+	memberID, err := strconv.ParseInt(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, action(userID, orgID, teamID, memberID)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) ListCollections(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.orgs.Collections(userID, orgID)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) CreateCollection(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req orgRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.orgs.CreateCollection(userID, orgID, req.Name)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"id": res.(int64)})
+}
+
+func (h *Handler) RenameCollection(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	collectionID, err := strconv.ParseInt(c.Params("collectionId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid collection id"})
+	}
+
+	var req orgRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.RenameCollection(userID, orgID, collectionID, req.Name)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteCollection(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	collectionID, err := strconv.ParseInt(c.Params("collectionId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid collection id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.DeleteCollection(userID, orgID, collectionID)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) SetCollectionTeams(c *fiber.Ctx) error {
+	userID, orgID, ok := orgParams(c)
+	if !ok {
+		return nil
+	}
+
+	collectionID, err := strconv.ParseInt(c.Params("collectionId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid collection id"})
+	}
+
+	var req collectionTeamsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.orgs.SetCollectionTeams(userID, orgID, collectionID, req.TeamIDs)
+	})
+	if err != nil {
+		return orgError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// orgParams reads the caller and the :id organization parameter. When ok is
+// false the error response has already been written.
+func orgParams(c *fiber.Ctx) (userID, orgID int64, ok bool) {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		_ = c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return 0, 0, false
+	}
+
+	orgID, err = strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		_ = c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return userID, orgID, true
+}
+
+func orgError(c *fiber.Ctx, err error) error {
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "organization request failed"})
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+)
+
+type policyRequest struct {
+	Name  string              `json:"name"`
+	Rules []models.PolicyRule `json:"rules"`
+}
+
+type policyAttachmentRequest struct {
+	SubjectType string `json:"subjectType"`
+	SubjectID   int64  `json:"subjectId"`
+}
+
+// policyCheckRequest names the entry either by id or by the attributes
+// policies match on, so rules can be tried against entries that do not
+// exist yet.
+type policyCheckRequest struct {
+	UserID     int64  `json:"userId"`
+	TokenID    int64  `json:"tokenId"`
+	Capability string `json:"capability"`
+	EntryID    int64  `json:"entryId"`
+	Entry      *struct {
+		Title        string   `json:"title"`
+		Category     string   `json:"category"`
+		CollectionID *int64   `json:"collectionId"`
+		Folder       string   `json:"folder"` // a path such as "Work/Servers"
+		Tags         []string `json:"tags"`
+	} `json:"entry"`
+}
+
+func policyError(c *fiber.Ctx, err error, fallback string) error {
+	if isUniqueViolation(err) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "policy name already exists"})
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+func (h *Handler) ListPolicies(c *fiber.Ctx) error {
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.policies.List()
+	})
+	if err != nil {
+		return policyError(c, err, "could not load policies")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) GetPolicy(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.policies.Get(id)
+	})
+	if err != nil {
+		return policyError(c, err, "could not load policy")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) CreatePolicy(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req policyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.policies.Create(adminID, req.Name, req.Rules)
+	})
+	if err != nil {
+		return policyError(c, err, "could not create policy")
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) UpdatePolicy(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req policyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.policies.Update(adminID, id, req.Rules)
+	})
+	if err != nil {
+		return policyError(c, err, "could not update policy")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) DeletePolicy(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.policies.Delete(adminID, id)
+	})
+	if err != nil {
+		return policyError(c, err, "could not delete policy")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) AttachPolicy(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req policyAttachmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.policies.Attach(adminID, id, req.SubjectType, req.SubjectID)
+	})
+	if err != nil {
+		return policyError(c, err, "could not attach policy")
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) DetachPolicy(c *fiber.Ctx) error {
+	adminID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+	subjectID, err := strconv.ParseInt(c.Params("subjectId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid subject id"})
+	}
+	subjectType := c.Params("subjectType")
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.policies.Detach(adminID, id, subjectType, subjectID)
+	})
+	if err != nil {
+		return policyError(c, err, "could not detach policy")
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// CheckPolicy is a dry run: it reports whether a capability would be
+// allowed on an entry without performing anything. Users check themselves,
+// by default with the API token they called with; admins may check anyone.
+func (h *Handler) CheckPolicy(c *fiber.Ctx) error {
+	callerID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req policyCheckRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if (req.EntryID == 0) == (req.Entry == nil) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "either entryId or entry required"})
+	}
+
+	userID := req.UserID
+	if userID == 0 {
+		userID = callerID
+	}
+	tokenID := req.TokenID
+	if userID == callerID && tokenID == 0 {
+		tokenID = tokenIDFromToken(c)
+	}
+	if userID != callerID && !h.auth.IsAdmin(callerID) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "admin role required to check other users"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		if tokenID != 0 {
+			if err := h.requireOwnToken(userID, tokenID); err != nil {
+				return nil, err
+			}
+		}
+		if req.Entry != nil {
+			return h.policies.Check(userID, tokenID, req.Capability, &models.VaultEntry{
+				Title:        req.Entry.Title,
+				Category:     req.Entry.Category,
+				CollectionID: req.Entry.CollectionID,
+				Tags:         req.Entry.Tags,
+			}, req.Entry.Folder)
+		}
+		return h.vault.ForToken(tokenID).CheckPolicy(userID, req.EntryID, req.Capability)
+	})
+	if err != nil {
+		return policyError(c, err, "could not check policy")
+	}
+
+	return c.JSON(res)
+}
+
+// requireOwnToken checks that tokenID is one of userID's API tokens.
+func (h *Handler) requireOwnToken(userID, tokenID int64) error {
+	tokens, err := h.tokens.List(userID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if token.ID == tokenID {
+			return nil
+		}
+	}
+	return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "token not found")
+}
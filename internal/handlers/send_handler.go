@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vault/internal/services"
+)
+
+type sendRequest struct {
+	Text           string `json:"text"`
+	MaxViews       int    `json:"maxViews"`
+	ExpiresInHours int    `json:"expiresInHours"`
+	Passphrase     string `json:"passphrase"`
+}
+
+func (r sendRequest) options() services.SendOptions {
+	return services.SendOptions{
+		MaxViews:   r.MaxViews,
+		ExpiresIn:  time.Duration(r.ExpiresInHours) * time.Hour,
+		Passphrase: r.Passphrase,
+	}
+}
+
+type openSendRequest struct {
+	Key        string `json:"key"`
+	Passphrase string `json:"passphrase"`
+}
+
+func (h *Handler) SendEntry(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req sendRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	authTime := authTimeFromToken(c)
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.sendsFor(c).SendEntry(userID, id, authTime, req.options())
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "entry not found"})
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) SendText(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req sendRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.sends.SendText(userID, req.Text, req.options())
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not create send"})
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) ListSends(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.sends.List(userID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load sends"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) DeleteSend(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id := c.Params("sendId")
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.sends.Delete(userID, id)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete send"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// SendInfo is public: it tells a recipient's client whether a send exists
+// and needs a passphrase, without using up a view.
+func (h *Handler) SendInfo(c *fiber.Ctx) error {
+	id := c.Params("sendId")
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		send, err := h.sends.Info(id)
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{
+			"hasPassphrase": send.HasPassphrase,
+			"viewsLeft":     send.MaxViews - send.Views,
+			"expiresAt":     send.ExpiresAt,
+		}, nil
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load send"})
+	}
+
+	return c.JSON(res)
+}
+
+// OpenSend is public. The key comes from the link's URL fragment and is
+// posted in the body so it never appears in request logs.
+func (h *Handler) OpenSend(c *fiber.Ctx) error {
+	id := c.Params("sendId")
+
+	var req openSendRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.sends.Open(id, req.Key, req.Passphrase)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not open send"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(res)
+}
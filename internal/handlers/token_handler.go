@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type tokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+func (h *Handler) ListTokens(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.tokens.List(userID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load tokens"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) CreateToken(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req tokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if req.ExpiresInDays < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "expiresInDays must be positive"})
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	// A token can never be granted more than the session creating it holds
+	granted := scopesFromToken(c)
+	for _, scope := range req.Scopes {
+		if !containsString(granted, scope) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "cannot grant scope " + scope})
+		}
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		raw, token, err := h.tokens.Create(userID, req.Name, req.Scopes, ttl)
+		if err != nil {
+			return nil, err
+		}
+		// The raw token is only ever shown in this response
+		return fiber.Map{"token": raw, "apiToken": token}, nil
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) DeleteToken(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.tokens.Revoke(userID, id)
+	})
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "token not found"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
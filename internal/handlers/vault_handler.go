@@ -1,21 +1,102 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	vaulterrors "vault/internal/errors"
 	"vault/internal/models"
 )
 
 type vaultRequest struct {
-	Title    string `json:"title"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	URL      string `json:"url"`
-	Category string `json:"category"`
-	Notes    string `json:"notes"`
+	Type         string `json:"type"` // fixed at creation
+	Title        string `json:"title"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	URL          string `json:"url"`
+	URLMatch     string `json:"urlMatch"`
+	Category     string `json:"category"`
+	Notes        string `json:"notes"`
+	CollectionID *int64 `json:"collectionId"` // only honored on create; use the move endpoint after
+	FolderID     *int64 `json:"folderId"`     // only honored on create; use the folder endpoint after
+	// The protection flags are off when omitted on create and kept when
+	// omitted on update
+	Sensitive        *bool `json:"sensitive"`
+	RequiresApproval *bool `json:"requiresApproval"`
+	CheckoutRequired *bool `json:"checkoutRequired"`
+	// Fields replaces all custom fields; omit it on update to keep them
+	Fields []models.CustomField `json:"fields"`
+	// URIs replaces all further URIs; omit it on update to keep them
+	URIs []models.EntryURI `json:"uris"`
+	// Generate has the server make the password instead of taking one
+	Generate *models.GeneratorOptions `json:"generate"`
+	models.EntryData
+}
+
+// entryFilterFromQuery reads the list and search filters.
+func entryFilterFromQuery(c *fiber.Ctx) (models.EntryFilter, error) {
+	filter := models.EntryFilter{
+		Type:     c.Query("type"),
+		FolderID: int64(c.QueryInt("folder")),
+		Favorite: c.QueryBool("favorite"),
+		Category: c.Query("category"),
+	}
+	for _, tag := range c.Context().QueryArgs().PeekMulti("tag") {
+		filter.Tags = append(filter.Tags, string(tag))
+	}
+	for _, bound := range []struct {
+		param string
+		at    *time.Time
+	}{
+		{"createdAfter", &filter.CreatedAfter},
+		{"createdBefore", &filter.CreatedBefore},
+		{"updatedAfter", &filter.UpdatedAfter},
+		{"updatedBefore", &filter.UpdatedBefore},
+	} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		at, err := parseQueryTime(value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", bound.param)
+		}
+		*bound.at = at
+	}
+	return filter, nil
+}
+
+// parseQueryTime accepts an RFC 3339 timestamp or a date, read as midnight
+// UTC.
+func parseQueryTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// pageFromQuery reads the paging and sort parameters.
+func pageFromQuery(c *fiber.Ctx) models.PageRequest {
+	return models.PageRequest{
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+		Limit:  c.QueryInt("limit"),
+		Cursor: c.Query("cursor"),
+	}
+}
+
+type moveRequest struct {
+	CollectionID *int64 `json:"collectionId"`
+}
+
+type tagsRequest struct {
+	Tags []string `json:"tags"`
 }
 
 func (h *Handler) ListEntries(c *fiber.Ctx) error {
@@ -24,9 +105,18 @@ func (h *Handler) ListEntries(c *fiber.Ctx) error {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
+	filter, err := entryFilterFromQuery(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	page := pageFromQuery(c)
+
 	res, err := h.runInPool(c.UserContext(), func() (any, error) {
-		return h.vault.List(userID)
+		return h.vaultFor(c).List(userID, filter, page)
 	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load entries"})
 	}
@@ -45,9 +135,23 @@ func (h *Handler) GetEntry(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
 	}
 
+	authTime := authTimeFromToken(c)
+
 	res, err := h.runInPool(c.UserContext(), func() (any, error) {
-		return h.vault.Get(userID, id)
+		return h.vaultFor(c).Get(userID, id, authTime)
 	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if isApprovalRequired(err) {
+		return approvalRequired(c)
+	}
+	if isCheckoutRequired(err) {
+		return checkoutRequired(c)
+	}
+	if isKeyLocked(err) {
+		return keyLocked(c)
+	}
 	if err != nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "entry not found"})
 	}
@@ -67,17 +171,31 @@ func (h *Handler) CreateEntry(c *fiber.Ctx) error {
 	}
 
 	entry := models.VaultEntry{
-		Title:    req.Title,
-		Username: req.Username,
-		Password: req.Password,
-		URL:      req.URL,
-		Category: req.Category,
-		Notes:    req.Notes,
+		Type:             req.Type,
+		Title:            req.Title,
+		Username:         req.Username,
+		Password:         req.Password,
+		Generate:         req.Generate,
+		URL:              req.URL,
+		URLMatch:         req.URLMatch,
+		Category:         req.Category,
+		Notes:            req.Notes,
+		Sensitive:        req.Sensitive != nil && *req.Sensitive,
+		RequiresApproval: req.RequiresApproval != nil && *req.RequiresApproval,
+		CheckoutRequired: req.CheckoutRequired != nil && *req.CheckoutRequired,
+		CollectionID:     req.CollectionID,
+		FolderID:         req.FolderID,
+		Fields:           req.Fields,
+		URIs:             req.URIs,
+		EntryData:        req.EntryData,
 	}
 
 	res, err := h.runInPool(c.UserContext(), func() (any, error) {
-		return h.vault.Create(userID, entry)
+		return h.vaultFor(c).Create(userID, entry)
 	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -102,17 +220,36 @@ func (h *Handler) UpdateEntry(c *fiber.Ctx) error {
 	}
 
 	entry := models.VaultEntry{
-		Title:    req.Title,
-		Username: req.Username,
-		Password: req.Password,
-		URL:      req.URL,
-		Category: req.Category,
-		Notes:    req.Notes,
+		Type:      req.Type,
+		Title:     req.Title,
+		Username:  req.Username,
+		Password:  req.Password,
+		Generate:  req.Generate,
+		URL:       req.URL,
+		URLMatch:  req.URLMatch,
+		Category:  req.Category,
+		Notes:     req.Notes,
+		Fields:    req.Fields,
+		URIs:      req.URIs,
+		EntryData: req.EntryData,
 	}
 
+	flags := models.EntryFlags{
+		Sensitive:        req.Sensitive,
+		RequiresApproval: req.RequiresApproval,
+		CheckoutRequired: req.CheckoutRequired,
+	}
+	authTime := authTimeFromToken(c)
+
 	_, err = h.runInPool(c.UserContext(), func() (any, error) {
-		return nil, h.vault.Update(userID, id, entry)
+		return nil, h.vaultFor(c).Update(userID, id, entry, flags, authTime)
 	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -132,8 +269,181 @@ func (h *Handler) DeleteEntry(c *fiber.Ctx) error {
 	}
 
 	_, err = h.runInPool(c.UserContext(), func() (any, error) {
-		return nil, h.vault.Delete(userID, id)
+		return nil, h.vaultFor(c).Delete(userID, id)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) MoveEntry(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req moveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.vaultFor(c).Move(userID, id, req.CollectionID)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not move entry"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// SetEntryTags replaces an entry's tags.
+func (h *Handler) SetEntryTags(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req tagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.vaultFor(c).SetTags(userID, id, req.Tags)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not save tags"})
+	}
+
+	return c.JSON(fiber.Map{"tags": res})
+}
+
+// FavoriteEntry stars an entry for the caller; DELETE unstars it.
+func (h *Handler) FavoriteEntry(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	favorite := c.Method() != fiber.MethodDelete
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.vaultFor(c).SetFavorite(userID, id, favorite)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not save favorite"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// SuggestTags autocompletes ?prefix= from the tags on the caller's entries.
+func (h *Handler) SuggestTags(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	prefix := c.Query("prefix")
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.vaultFor(c).SuggestTags(userID, prefix)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load tags"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) ListTrash(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.vaultFor(c).ListTrash(userID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load trash"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) RestoreTrashed(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.vaultFor(c).RestoreTrashed(userID, id)
 	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not restore entry"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// PurgeEntry permanently deletes an entry from the trash.
+func (h *Handler) PurgeEntry(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.vaultFor(c).Purge(userID, id)
+	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete"})
 	}
@@ -141,6 +451,76 @@ func (h *Handler) DeleteEntry(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusNoContent)
 }
 
+// EntryHistory lists an entry's earlier versions with their passwords.
+func (h *Handler) EntryHistory(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	authTime := authTimeFromToken(c)
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.vaultFor(c).History(userID, id, authTime)
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if isApprovalRequired(err) {
+		return approvalRequired(c)
+	}
+	if isCheckoutRequired(err) {
+		return checkoutRequired(c)
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load history"})
+	}
+
+	return c.JSON(res)
+}
+
+// RestoreEntry replaces an entry's contents with those of an earlier version.
+func (h *Handler) RestoreEntry(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+	revision, err := strconv.Atoi(c.Params("rev"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid revision"})
+	}
+
+	authTime := authTimeFromToken(c)
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.vaultFor(c).Restore(userID, id, revision, authTime)
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not restore entry"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
 func (h *Handler) SearchEntries(c *fiber.Ctx) error {
 	userID, err := userIDFromToken(c)
 	if err != nil {
@@ -148,13 +528,102 @@ func (h *Handler) SearchEntries(c *fiber.Ctx) error {
 	}
 
 	query := c.Query("q", "")
+	filter, err := entryFilterFromQuery(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	page := pageFromQuery(c)
 
 	res, err := h.runInPool(c.UserContext(), func() (any, error) {
-		return h.vault.Search(c.UserContext(), userID, query)
+		return h.vaultFor(c).Search(c.UserContext(), userID, query, filter, page)
 	})
+	if status, msg, ok := vaultErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "search failed", "details": err.Error()})
+		log.Printf("search: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "search failed"})
 	}
 
 	return c.JSON(res)
 }
+
+func isReauthRequired(err error) bool {
+	var vaultErr *vaulterrors.VaultError
+	return errors.As(err, &vaultErr) && vaultErr.Code == vaulterrors.ErrReauthRequired
+}
+
+// reauthRequired tells the client to prompt for credentials and retry with
+// the token returned by POST /api/auth/reauth.
+func reauthRequired(c *fiber.Ctx) error {
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+		"error": "recent re-authentication required",
+		"code":  vaulterrors.ErrReauthRequired,
+	})
+}
+
+func isApprovalRequired(err error) bool {
+	var vaultErr *vaulterrors.VaultError
+	return errors.As(err, &vaultErr) && vaultErr.Code == vaulterrors.ErrApprovalRequired
+}
+
+// approvalRequired tells the client to submit an access request with
+// POST /api/vault/entries/:id/access-requests and wait for approval.
+func approvalRequired(c *fiber.Ctx) error {
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{
+		"error": "an approved access request is required",
+		"code":  vaulterrors.ErrApprovalRequired,
+	})
+}
+
+func isCheckoutRequired(err error) bool {
+	var vaultErr *vaulterrors.VaultError
+	return errors.As(err, &vaultErr) && vaultErr.Code == vaulterrors.ErrCheckoutRequired
+}
+
+// checkoutRequired tells the client to check the entry out with
+// POST /api/vault/entries/:id/checkout, which returns the password.
+func checkoutRequired(c *fiber.Ctx) error {
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{
+		"error": "entry must be checked out first",
+		"code":  vaulterrors.ErrCheckoutRequired,
+	})
+}
+
+func isKeyLocked(err error) bool {
+	var vaultErr *vaulterrors.VaultError
+	return errors.As(err, &vaultErr) && vaultErr.Code == vaulterrors.ErrKeyLocked
+}
+
+// keyLocked tells a share recipient to unlock their key pair with
+// POST /api/auth/keys/unlock and retry.
+func keyLocked(c *fiber.Ctx) error {
+	return c.Status(http.StatusLocked).JSON(fiber.Map{
+		"error": "unlock your key pair to open shared entries",
+		"code":  vaulterrors.ErrKeyLocked,
+	})
+}
+
+// vaultErrorStatus maps a VaultError to an HTTP status and client message;
+// ok is false for any other error so callers keep their own fallback.
+func vaultErrorStatus(err error) (status int, message string, ok bool) {
+	var vaultErr *vaulterrors.VaultError
+	if !errors.As(err, &vaultErr) {
+		return 0, "", false
+	}
+	switch vaultErr.Code {
+	case vaulterrors.ErrNotFound:
+		return http.StatusNotFound, vaultErr.Message, true
+	case vaulterrors.ErrForbidden, vaulterrors.ErrApprovalRequired, vaulterrors.ErrCheckoutRequired:
+		return http.StatusForbidden, vaultErr.Message, true
+	case vaulterrors.ErrInvalidInput:
+		return http.StatusBadRequest, vaultErr.Message, true
+	case vaulterrors.ErrUnauthorized:
+		return http.StatusUnauthorized, vaultErr.Message, true
+	case vaulterrors.ErrConflict:
+		return http.StatusConflict, vaultErr.Message, true
+	case vaulterrors.ErrKeyLocked:
+		return http.StatusLocked, vaultErr.Message, true
+	}
+	return http.StatusInternalServerError, vaultErr.Message, true
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type webauthnFinishRequest struct {
+	SessionID  string          `json:"sessionId"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+type webauthnLoginRequest struct {
+	Email    string `json:"email"`
+	MFAToken string `json:"mfaToken"`
+}
+
+type webauthnMFARequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (h *Handler) BeginPasskeyRegistration(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	authTime := authTimeFromToken(c)
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		sessionID, options, err := h.webauthn.BeginRegistration(userID, authTime)
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{"sessionId": sessionID, "options": options}, nil
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not start registration"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) FinishPasskeyRegistration(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req webauthnFinishRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.webauthn.FinishRegistration(userID, req.SessionID, req.Name, req.Credential)
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "registration failed", "details": err.Error()})
+	}
+
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+func (h *Handler) BeginPasskeyLogin(c *fiber.Ctx) error {
+	var req webauthnLoginRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+		}
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		sessionID, options, err := h.webauthn.BeginLogin(req.Email, req.MFAToken)
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{"sessionId": sessionID, "options": options}, nil
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) FinishPasskeyLogin(c *fiber.Ctx) error {
+	var req webauthnFinishRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		token, user, err := h.webauthn.FinishLogin(req.SessionID, req.Credential)
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{
+			"token": token,
+			"user": fiber.Map{
+				"id":    user.ID,
+				"email": user.Email,
+			},
+		}, nil
+	})
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) ListPasskeys(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	res, err := h.runInPool(c.UserContext(), func() (any, error) {
+		return h.webauthn.ListCredentials(userID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "could not load passkeys"})
+	}
+
+	return c.JSON(res)
+}
+
+func (h *Handler) DeletePasskey(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	authTime := authTimeFromToken(c)
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.webauthn.DeleteCredential(userID, id, authTime)
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "passkey not found"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *Handler) SetPasskeyMFA(c *fiber.Ctx) error {
+	userID, err := userIDFromToken(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var req webauthnMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+
+	authTime := authTimeFromToken(c)
+
+	_, err = h.runInPool(c.UserContext(), func() (any, error) {
+		return nil, h.webauthn.SetMFA(userID, req.Enabled, authTime)
+	})
+	if isReauthRequired(err) {
+		return reauthRequired(c)
+	}
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
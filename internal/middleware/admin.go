@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vault/internal/services"
+)
+
+// RequireAdmin must follow JWT. The role is read from the database on every
+// request so a demotion takes effect without waiting for tokens to expire.
+func RequireAdmin(auth *services.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _, ok := sessionClaims(c)
+		if !ok || !auth.IsAdmin(userID) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "admin role required"})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+
+	"vault/internal/services"
+)
+
+// Auth accepts either a signed JWT or an API token. API tokens are exposed
+// to handlers as a *jwt.Token under the same "user" key so downstream code,
+// including RequireScope, does not need to care which credential was presented.
+func Auth(secret string, auth *services.AuthService, tokens *services.TokenService) fiber.Handler {
+	verifyJWT := JWT(secret, auth)
+
+	return func(c *fiber.Ctx) error {
+		raw := bearerToken(c)
+		if !strings.HasPrefix(raw, services.APITokenPrefix) {
+			return verifyJWT(c)
+		}
+
+		token, err := tokens.Authenticate(raw)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired token"})
+		}
+		tokens.RecordUse(token, c.Method(), c.Path())
+
+		c.Locals("user", &jwt.Token{
+			Valid: true,
+			Claims: jwt.MapClaims{
+				"sub":   token.UserID,
+				"tid":   token.ID,
+				"scope": strings.Join(token.Scopes, " "),
+				// Lets a forced logout reject tokens created before it
+				"iat": float64(token.CreatedAt.Unix()),
+			},
+		})
+		return checkSession(c, auth)
+	}
+}
+
+func bearerToken(c *fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
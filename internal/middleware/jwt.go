@@ -1,13 +1,69 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	jwtware "github.com/gofiber/jwt/v3"
+	"github.com/golang-jwt/jwt/v4"
+
+	"vault/internal/services"
 )
 
-func JWT(secret string) fiber.Handler {
+// JWT verifies the token signature and then checks the session is still
+// live: disabled users and tokens issued before a forced logout are rejected.
+func JWT(secret string, auth *services.AuthService) fiber.Handler {
 	return jwtware.New(jwtware.Config{
 		SigningKey: []byte(secret),
 		ContextKey: "user",
+		SuccessHandler: func(c *fiber.Ctx) error {
+			return checkSession(c, auth)
+		},
 	})
 }
+
+func checkSession(c *fiber.Ctx, auth *services.AuthService) error {
+	userID, issuedAt, ok := sessionClaims(c)
+	if !ok {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
+	}
+	if err := auth.ValidateSession(userID, issuedAt); err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Next()
+}
+
+func sessionClaims(c *fiber.Ctx) (int64, time.Time, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+
+	var userID int64
+	switch sub := claims["sub"].(type) {
+	case int64:
+		userID = sub
+	case float64:
+		userID = int64(sub)
+	case string:
+		id, err := strconv.ParseInt(sub, 10, 64)
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+		userID = id
+	default:
+		return 0, time.Time{}, false
+	}
+
+	var issuedAt time.Time
+	if iat, ok := claims["iat"].(float64); ok {
+		issuedAt = time.Unix(int64(iat), 0)
+	}
+	return userID, issuedAt, true
+}
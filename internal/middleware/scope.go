@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// RequireScope rejects requests whose token lacks any of the given scopes.
+// It must run after JWT or Auth so the token is already in c.Locals("user").
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		granted := tokenScopes(c)
+		for _, scope := range scopes {
+			if !granted[scope] {
+				return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "insufficient scope", "required": scope})
+			}
+		}
+		return c.Next()
+	}
+}
+
+func tokenScopes(c *fiber.Ctx) map[string]bool {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok || token == nil {
+		return nil
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+	scope, _ := claims["scope"].(string)
+
+	granted := map[string]bool{}
+	for _, s := range strings.Fields(scope) {
+		granted[s] = true
+	}
+	return granted
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Access request states
+const (
+	AccessPending  = "pending"
+	AccessApproved = "approved"
+	AccessDenied   = "denied"
+	AccessExpired  = "expired"
+)
+
+// AccessRequest asks an organization's owners and admins for time-limited
+// read access to an entry that requires approval.
+type AccessRequest struct {
+	ID              int64      `json:"id"`
+	EntryID         int64      `json:"entryId"`
+	EntryTitle      string     `json:"entryTitle"`
+	OrgID           int64      `json:"orgId"`
+	UserID          int64      `json:"userId"`
+	UserEmail       string     `json:"userEmail"`
+	Justification   string     `json:"justification"`
+	DurationMinutes int        `json:"durationMinutes"`
+	Status          string     `json:"status"`
+	DecidedBy       *int64     `json:"decidedBy,omitempty"`
+	DecisionNote    string     `json:"decisionNote,omitempty"`
+	DecidedAt       *time.Time `json:"decidedAt,omitempty"`
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
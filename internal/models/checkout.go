@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Checkout states
+const (
+	CheckoutActive   = "active"
+	CheckoutReturned = "returned"
+	CheckoutExpired  = "expired"
+)
+
+// Checkout is an exclusive, time-boxed lease on an entry's secret. The
+// password is rotated when it is returned or expires.
+type Checkout struct {
+	ID           int64      `json:"id"`
+	EntryID      int64      `json:"entryId"`
+	UserID       int64      `json:"userId"`
+	UserEmail    string     `json:"userEmail"`
+	Status       string     `json:"status"`
+	CheckedOutAt time.Time  `json:"checkedOutAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	CheckedInAt  *time.Time `json:"checkedInAt,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Custom field types
+const (
+	FieldText    = "text"
+	FieldHidden  = "hidden"
+	FieldBoolean = "boolean"
+	FieldURL     = "url"
+	FieldLinked  = "linked"
+)
+
+// CustomField is a named value on an entry. Hidden values are encrypted and
+// left out of lists. A linked field shows a field of another entry, named
+// by LinkedField: username, password, url, title or one of its custom
+// fields.
+type CustomField struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Value         string `json:"value,omitempty"`
+	LinkedEntryID *int64 `json:"linkedEntryId,omitempty"`
+	LinkedField   string `json:"linkedField,omitempty"`
+}
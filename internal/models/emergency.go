@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Emergency access states
+const (
+	EmergencyIdle      = "idle"
+	EmergencyRequested = "requested"
+	EmergencyGranted   = "granted"
+)
+
+// EmergencyContact lets the grantee request read access to the grantor's
+// personal vault, granted automatically after WaitDays unless rejected.
+type EmergencyContact struct {
+	ID           int64      `json:"id"`
+	GrantorID    int64      `json:"grantorId"`
+	GrantorEmail string     `json:"grantorEmail"`
+	GranteeID    int64      `json:"granteeId"`
+	GranteeEmail string     `json:"granteeEmail"`
+	WaitDays     int        `json:"waitDays"`
+	Status       string     `json:"status"`
+	RequestedAt  *time.Time `json:"requestedAt,omitempty"`
+	GrantAt      *time.Time `json:"grantAt,omitempty"`
+	GrantedAt    *time.Time `json:"grantedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// Entry types
+const (
+	EntryTypeLogin    = "login"
+	EntryTypeNote     = "note"
+	EntryTypeCard     = "card"
+	EntryTypeIdentity = "identity"
+	EntryTypeSSHKey   = "ssh_key"
+	EntryTypeAPIKey   = "api_key"
+)
+
+// EntryFilter narrows entry lists and searches.
+type EntryFilter struct {
+	Type string
+	// FolderID selects entries in one of the caller's folders or below it
+	FolderID int64
+	// Tags selects entries carrying every one of the tags
+	Tags []string
+	// Favorite selects the entries the caller starred
+	Favorite bool
+	// Category matches case-insensitively
+	Category string
+	// The time bounds are inclusive below and exclusive above; zero values
+	// leave that side open
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+}
+
+// EntryData holds the type-specific fields of an entry. Only the member
+// matching the entry's type is set, and it is stored encrypted as a whole.
+type EntryData struct {
+	Note     *NoteData     `json:"note,omitempty"`
+	Card     *CardData     `json:"card,omitempty"`
+	Identity *IdentityData `json:"identity,omitempty"`
+	SSHKey   *SSHKeyData   `json:"sshKey,omitempty"`
+	APIKey   *APIKeyData   `json:"apiKey,omitempty"`
+}
+
+// NoteData is the body of a secure note.
+type NoteData struct {
+	Text string `json:"text"`
+}
+
+type CardData struct {
+	Cardholder string `json:"cardholder,omitempty"`
+	Brand      string `json:"brand,omitempty"`
+	Number     string `json:"number"`
+	ExpMonth   int    `json:"expMonth"`
+	ExpYear    int    `json:"expYear"`
+	CVV        string `json:"cvv,omitempty"`
+}
+
+type IdentityData struct {
+	FirstName      string `json:"firstName,omitempty"`
+	MiddleName     string `json:"middleName,omitempty"`
+	LastName       string `json:"lastName,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Phone          string `json:"phone,omitempty"`
+	Company        string `json:"company,omitempty"`
+	Address        string `json:"address,omitempty"`
+	City           string `json:"city,omitempty"`
+	State          string `json:"state,omitempty"`
+	PostalCode     string `json:"postalCode,omitempty"`
+	Country        string `json:"country,omitempty"`
+	BirthDate      string `json:"birthDate,omitempty"`
+	PassportNumber string `json:"passportNumber,omitempty"`
+	LicenseNumber  string `json:"licenseNumber,omitempty"`
+	NationalID     string `json:"nationalId,omitempty"`
+}
+
+// SSHKeyData holds a private key. PublicKey and Fingerprint are derived
+// from it by the server.
+type SSHKeyData struct {
+	PrivateKey  string `json:"privateKey"`
+	Passphrase  string `json:"passphrase,omitempty"`
+	PublicKey   string `json:"publicKey,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+type APIKeyData struct {
+	Key      string `json:"key"`
+	Secret   string `json:"secret,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Folder groups entries in its owner's personal vault. Folders nest;
+// ParentID is nil for top-level folders.
+type Folder struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"userId"`
+	ParentID  *int64    `json:"parentId,omitempty"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FolderNode is a folder in the folder tree. Count is the number of entries
+// filed directly in it and Total includes those in its subfolders.
+type FolderNode struct {
+	Folder
+	Count    int          `json:"count"`
+	Total    int          `json:"total"`
+	Children []FolderNode `json:"children"`
+}
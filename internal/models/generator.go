@@ -0,0 +1,44 @@
+package models
+
+// Generated password kinds
+const (
+	GenerateRandom        = "random"
+	GeneratePassphrase    = "passphrase"
+	GeneratePronounceable = "pronounceable"
+)
+
+// Character sets of random passwords
+const (
+	CharsUpper   = "upper"
+	CharsLower   = "lower"
+	CharsDigits  = "digits"
+	CharsSymbols = "symbols"
+)
+
+// GeneratorOptions describes a password to generate. Zero values take the
+// defaults: a random password of 20 characters from all four sets, or a
+// passphrase of 6 words joined by "-".
+type GeneratorOptions struct {
+	Type string `json:"type"` // random (default), passphrase or pronounceable
+
+	// Random and pronounceable passwords
+	Length int `json:"length"`
+	// Random passwords
+	Sets             []string       `json:"sets"`
+	Minimums         map[string]int `json:"minimums"` // least characters from each set
+	ExcludeAmbiguous bool           `json:"excludeAmbiguous"`
+
+	// Passphrases
+	Words     int     `json:"words"`
+	Separator *string `json:"separator"`
+	// Passphrases and pronounceable passwords
+	Capitalize    bool `json:"capitalize"`
+	IncludeNumber bool `json:"includeNumber"`
+}
+
+// GeneratedPassword is a generated password with an estimate of its
+// strength against an attacker who knows the options used.
+type GeneratedPassword struct {
+	Password    string  `json:"password"`
+	EntropyBits float64 `json:"entropyBits"`
+}
@@ -2,24 +2,82 @@ package models
 
 import "time"
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID           int64     `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"createdAt"`
+	ID                 int64      `json:"id"`
+	Email              string     `json:"email"`
+	PasswordHash       string     `json:"-"`
+	AuthBackend        string     `json:"authBackend,omitempty"`
+	WebAuthnHandle     string     `json:"-"`
+	WebAuthnMFA        bool       `json:"webauthnMfa"`
+	Role               string     `json:"role"`
+	DisabledAt         *time.Time `json:"disabledAt,omitempty"`
+	SessionsValidAfter *time.Time `json:"-"`
+	CreatedAt          time.Time  `json:"createdAt"`
 }
 
-type VaultEntry struct {
-	ID             int64      `json:"id"`
+// UserStats summarizes a user's vault for administrators; no secrets.
+type UserStats struct {
 	UserID         int64      `json:"userId"`
-	Title          string     `json:"title"`
-	Username       string     `json:"username"`
-	Password       string     `json:"password,omitempty"`
-	PasswordEnc    string     `json:"-"`
-	URL            string     `json:"url,omitempty"`
-	Category       string     `json:"category,omitempty"`
-	Notes          string     `json:"notes,omitempty"`
-	CreatedAt      time.Time  `json:"createdAt"`
-	UpdatedAt      time.Time  `json:"updatedAt"`
+	Entries        int        `json:"entries"`
+	Sensitive      int        `json:"sensitive"`
+	APITokens      int        `json:"apiTokens"`
+	Passkeys       int        `json:"passkeys"`
 	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty"`
 }
+
+type VaultEntry struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"userId"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Username    string `json:"username"`
+	Password    string `json:"password,omitempty"`
+	PasswordEnc string `json:"-"`
+	KeyEnc      string `json:"-"`
+	// Generate has Create and Update make the password to these options
+	// instead of taking Password
+	Generate         *GeneratorOptions `json:"-"`
+	URL              string            `json:"url,omitempty"`
+	URLMatch         string            `json:"urlMatch,omitempty"` // empty for the default, base domain
+	Category         string            `json:"category,omitempty"`
+	Notes            string            `json:"notes,omitempty"`
+	Sensitive        bool              `json:"sensitive"`
+	RequiresApproval bool              `json:"requiresApproval"`
+	CheckoutRequired bool              `json:"checkoutRequired"`
+	PasswordVersion  int               `json:"passwordVersion"`
+	CollectionID     *int64            `json:"collectionId,omitempty"`
+	FolderID         *int64            `json:"folderId,omitempty"`
+	DataEnc          string            `json:"-"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	UpdatedAt        time.Time         `json:"updatedAt"`
+	LastAccessedAt   *time.Time        `json:"lastAccessedAt,omitempty"`
+	AccessCount      int               `json:"accessCount"`
+	DeletedAt        *time.Time        `json:"deletedAt,omitempty"` // set while in the trash
+	// Fields is nil when not loaded; on update nil keeps the stored fields
+	Fields []CustomField `json:"fields,omitempty"`
+	// URIs is nil when not loaded; on update nil keeps the stored URIs
+	URIs []EntryURI `json:"uris,omitempty"`
+	// Tags and Favorite are loaded for lists and reads; Favorite is the
+	// caller's own star
+	Tags     []string `json:"tags,omitempty"`
+	Favorite bool     `json:"favorite"`
+	// Snippet is the best matching text of a search hit as HTML: the text
+	// is escaped and the matched terms are between <mark> and </mark>
+	Snippet string `json:"snippet,omitempty"`
+
+	EntryData
+}
+
+// EntryFlags are the protection flags given with an update. A nil flag
+// keeps the stored value.
+type EntryFlags struct {
+	Sensitive        *bool
+	RequiresApproval *bool
+	CheckoutRequired *bool
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Organization membership roles, from most to least privileged
+const (
+	OrgRoleOwner    = "owner"
+	OrgRoleAdmin    = "admin"
+	OrgRoleMember   = "member"
+	OrgRoleReadOnly = "readonly"
+)
+
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy int64     `json:"createdBy"`
+	Role      string    `json:"role,omitempty"` // the caller's role when listed for a user
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type OrgMember struct {
+	OrgID     int64     `json:"orgId"`
+	UserID    int64     `json:"userId"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Team struct {
+	ID        int64     `json:"id"`
+	OrgID     int64     `json:"orgId"`
+	Name      string    `json:"name"`
+	MemberIDs []int64   `json:"memberIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Collection struct {
+	ID        int64     `json:"id"`
+	OrgID     int64     `json:"orgId"`
+	Name      string    `json:"name"`
+	TeamIDs   []int64   `json:"teamIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// Entry list sort keys
+const (
+	SortCreated  = "created"
+	SortUpdated  = "updated"
+	SortTitle    = "title"
+	SortAccessed = "accessed"
+	// SortRelevance ranks search hits by how well they match
+	SortRelevance = "relevance"
+)
+
+// PageRequest selects one page of an entry list. Zero values take the
+// defaults: best match first for searches and newest created first for
+// lists, 50 entries, starting from the top.
+type PageRequest struct {
+	Sort   string
+	Order  string // asc or desc
+	Limit  int
+	Cursor string // NextCursor of the previous page
+}
+
+// EntryCursor is the position after which the next page starts. It is
+// handed to clients opaque and names the sort it belongs to. Lists resume
+// after the last entry's sort key and id; searches, whose ranking moves as
+// entries are used, resume at an offset.
+type EntryCursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d"`
+	Key    string `json:"k,omitempty"` // sort column value of the last entry
+	ID     int64  `json:"i,omitempty"`
+	Offset int    `json:"o,omitempty"`
+}
+
+// EntryPage is one page of an entry list or search. Total counts every
+// matching entry; NextCursor is empty on the last page.
+type EntryPage struct {
+	Items      []VaultEntry `json:"items"`
+	Total      int          `json:"total"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
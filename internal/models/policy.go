@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Policy capabilities. CapabilityDeny overrides every other capability on
+// the entries a rule matches; "*" in a rule grants all but deny.
+const (
+	CapabilityList   = "list"
+	CapabilityRead   = "read"
+	CapabilityReveal = "reveal"
+	CapabilityCreate = "create"
+	CapabilityUpdate = "update"
+	CapabilityDelete = "delete"
+	CapabilityDeny   = "deny"
+)
+
+// Policy subject types
+const (
+	PolicySubjectUser  = "user"
+	PolicySubjectTeam  = "team"
+	PolicySubjectToken = "token"
+)
+
+// Policy grants capabilities on the entries its rules match.
+type Policy struct {
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Rules       []PolicyRule       `json:"rules"`
+	Attachments []PolicyAttachment `json:"attachments,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+// PolicyRule matches entries on every selector it sets. Category and Title
+// are path.Match globs; Collection is a collection id or "personal". Folder
+// is a glob over folder paths such as "Work/Servers" that also covers the
+// folders below a match, and Tag a glob that one of the entry's tags must
+// match.
+type PolicyRule struct {
+	Category     string   `json:"category,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Collection   string   `json:"collection,omitempty"`
+	Folder       string   `json:"folder,omitempty"`
+	Tag          string   `json:"tag,omitempty"`
+	Capabilities []string `json:"capabilities"`
+}
+
+type PolicyAttachment struct {
+	PolicyID    int64     `json:"policyId"`
+	SubjectType string    `json:"subjectType"`
+	SubjectID   int64     `json:"subjectId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// EntryRevision is an earlier version of an entry, saved when it was
+// changed. Entry is the decrypted snapshot, with its password, type-specific
+// data and custom fields as they were then.
+type EntryRevision struct {
+	ID             int64       `json:"-"`
+	EntryID        int64       `json:"entryId"`
+	Revision       int         `json:"revision"`
+	ChangedBy      *int64      `json:"changedBy,omitempty"` // nil for password rotations
+	ChangedByEmail string      `json:"changedByEmail,omitempty"`
+	CreatedAt      time.Time   `json:"createdAt"` // when this version was replaced
+	SnapshotEnc    string      `json:"-"`
+	KeyEnc         string      `json:"-"`
+	Entry          *VaultEntry `json:"entry,omitempty"`
+}
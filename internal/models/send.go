@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Send is a one-time secret link. Ciphertext can only be opened with the key
+// from the link's URL fragment (and the passphrase, when set).
+type Send struct {
+	ID            string `json:"id"`
+	UserID        int64  `json:"userId"`
+	EntryID       *int64 `json:"entryId,omitempty"`
+	Ciphertext    string `json:"-"`
+	HasPassphrase bool   `json:"hasPassphrase"`
+	// KeyCheck and PassphraseSalt are empty for sends created before
+	// passphrases were stretched
+	KeyCheck       string    `json:"-"`
+	PassphraseSalt string    `json:"-"`
+	MaxViews       int       `json:"maxViews"`
+	Views          int       `json:"views"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// SendPayload is what a recipient sees after opening a send. An entry's
+// payload carries its type's data and its custom fields, hidden ones
+// included, with linked fields resolved to their values.
+type SendPayload struct {
+	Text     string        `json:"text,omitempty"`
+	Type     string        `json:"type,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Username string        `json:"username,omitempty"`
+	Password string        `json:"password,omitempty"`
+	URL      string        `json:"url,omitempty"`
+	Fields   []CustomField `json:"fields,omitempty"`
+
+	EntryData
+}
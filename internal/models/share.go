@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Share permissions
+const (
+	SharePermissionRead = "read"
+	SharePermissionEdit = "edit"
+)
+
+type UserKey struct {
+	UserID        int64  `json:"userId"`
+	PublicKey     string `json:"publicKey"`
+	PrivateKeyEnc string `json:"-"`
+	// KDFSalt salts the key passphrase; empty for pairs still wrapped by the server
+	KDFSalt   string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UserKeyStatus tells a user whether they can receive and open shares.
+type UserKeyStatus struct {
+	PublicKey string `json:"publicKey,omitempty"`
+	// PassphraseSet is false until the user protects their key pair with a
+	// key passphrase; shares cannot be opened before that
+	PassphraseSet bool `json:"passphraseSet"`
+	// Unlocked is true while the private key is open in this server's memory
+	Unlocked bool `json:"unlocked"`
+}
+
+// EntryShare grants one user access to another user's personal entry.
+type EntryShare struct {
+	ID             int64     `json:"id"`
+	EntryID        int64     `json:"entryId"`
+	OwnerID        int64     `json:"ownerId"`
+	RecipientID    int64     `json:"recipientId"`
+	RecipientEmail string    `json:"recipientEmail,omitempty"`
+	Permission     string    `json:"permission"`
+	KeySealed      string    `json:"-"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
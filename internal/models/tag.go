@@ -0,0 +1,8 @@
+package models
+
+// TagCount is a tag with the number of entries carrying it, for
+// autocompletion.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// URL match modes, deciding when an entry's URI matches the page being
+// filled
+const (
+	URLMatchDomain     = "domain" // same base domain, the default
+	URLMatchHost       = "host"   // same host name and port
+	URLMatchStartsWith = "starts_with"
+	URLMatchExact      = "exact"
+	URLMatchRegex      = "regex"
+	URLMatchNever      = "never"
+)
+
+// EntryURI is a further address of an entry besides its URL. An empty
+// Match takes the entry's URLMatch.
+type EntryURI struct {
+	URI   string `json:"uri"`
+	Match string `json:"match,omitempty"`
+}
+
+// DomainGroup is a set of base domains its owner treats as one site when
+// matching URLs.
+type DomainGroup struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	Domains   []string  `json:"domains"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// WebAuthnCredential is a registered passkey. Data holds the serialized
+// public key and authenticator state used to verify assertions.
+type WebAuthnCredential struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"userId"`
+	Name         string     `json:"name"`
+	CredentialID string     `json:"credentialId"`
+	Data         string     `json:"-"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+const accessSelect = `SELECT a.id, a.entry_id, e.title, c.org_id, a.user_id, u.email, a.justification,
+		a.duration_minutes, a.status, a.decided_by, a.decision_note, a.decided_at, a.expires_at, a.created_at
+	FROM access_requests a
+	JOIN vault_entries e ON e.id = a.entry_id
+	JOIN collections c ON c.id = e.collection_id
+	JOIN users u ON u.id = a.user_id`
+
+// approverOrgs selects the organizations a user (bound once) approves
+// requests for.
+const approverOrgs = "SELECT org_id FROM org_members WHERE user_id = ? AND role IN ('owner', 'admin')"
+
+type AccessRepository struct {
+	db *sql.DB
+}
+
+func NewAccessRepository(db *sql.DB) *AccessRepository {
+	return &AccessRepository{db: db}
+}
+
+func (r *AccessRepository) Create(entryID, userID int64, justification string, durationMinutes int) (int64, error) {
+	res, err := r.db.Exec(
+		`INSERT INTO access_requests (entry_id, user_id, justification, duration_minutes, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		entryID,
+		userID,
+		justification,
+		durationMinutes,
+		models.AccessPending,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *AccessRepository) GetByID(id int64) (*models.AccessRequest, error) {
+	return scanAccessRequest(r.db.QueryRow(accessSelect+" WHERE a.id = ?", id))
+}
+
+func (r *AccessRepository) ListByUser(userID int64) ([]models.AccessRequest, error) {
+	return r.list(accessSelect+" WHERE a.user_id = ? ORDER BY a.id DESC", userID)
+}
+
+// ListPending returns pending requests in organizations approverID owns or administers.
+func (r *AccessRepository) ListPending(approverID int64) ([]models.AccessRequest, error) {
+	return r.list(
+		accessSelect+" WHERE a.status = ? AND c.org_id IN ("+approverOrgs+") ORDER BY a.id",
+		models.AccessPending,
+		approverID,
+	)
+}
+
+// ListExpired returns approved requests whose access window has closed.
+func (r *AccessRepository) ListExpired(now time.Time) ([]models.AccessRequest, error) {
+	return r.list(
+		accessSelect+" WHERE a.status = ? AND a.expires_at <= ?",
+		models.AccessApproved,
+		now.UTC().Format(time.RFC3339),
+	)
+}
+
+func (r *AccessRepository) list(query string, args ...any) ([]models.AccessRequest, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.AccessRequest{}
+	for rows.Next() {
+		req, err := scanAccessRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *req)
+	}
+	return requests, rows.Err()
+}
+
+// HasOpen reports whether userID already has a pending request or an
+// unexpired grant for entryID.
+func (r *AccessRepository) HasOpen(entryID, userID int64, now time.Time) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM access_requests WHERE entry_id = ? AND user_id = ?
+			AND (status = ? OR (status = ? AND expires_at > ?)))`,
+		entryID,
+		userID,
+		models.AccessPending,
+		models.AccessApproved,
+		now.UTC().Format(time.RFC3339),
+	).Scan(&ok)
+	return ok, err
+}
+
+// HasGrant reports whether userID holds an approved, unexpired request for entryID.
+func (r *AccessRepository) HasGrant(entryID, userID int64, now time.Time) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM access_requests WHERE entry_id = ? AND user_id = ? AND status = ? AND expires_at > ?)",
+		entryID,
+		userID,
+		models.AccessApproved,
+		now.UTC().Format(time.RFC3339),
+	).Scan(&ok)
+	return ok, err
+}
+
+// IsApprover reports whether userID is an owner or admin of the organization
+// holding entryID.
+func (r *AccessRepository) IsApprover(userID, entryID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM vault_entries e JOIN collections c ON c.id = e.collection_id
+			WHERE e.id = ? AND c.org_id IN (`+approverOrgs+`))`,
+		entryID,
+		userID,
+	).Scan(&ok)
+	return ok, err
+}
+
+// Approvers lists the owners and admins of the organization holding entryID.
+func (r *AccessRepository) Approvers(entryID int64) ([]models.OrgMember, error) {
+	rows, err := r.db.Query(
+		`SELECT m.org_id, m.user_id, u.email, m.role, m.created_at
+		FROM vault_entries e
+		JOIN collections c ON c.id = e.collection_id
+		JOIN org_members m ON m.org_id = c.org_id AND m.role IN ('owner', 'admin')
+		JOIN users u ON u.id = m.user_id
+		WHERE e.id = ?`,
+		entryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrgMember{}
+	for rows.Next() {
+		var member models.OrgMember
+		var createdAt string
+		if err := rows.Scan(&member.OrgID, &member.UserID, &member.Email, &member.Role, &createdAt); err != nil {
+			return nil, err
+		}
+		member.CreatedAt = parseTime(createdAt)
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// Approve grants a pending request until expiresAt; it reports false if the
+// request is no longer pending.
+func (r *AccessRepository) Approve(id, approverID int64, note string, decidedAt, expiresAt time.Time) (bool, error) {
+	return r.transition(
+		"UPDATE access_requests SET status = ?, decided_by = ?, decision_note = ?, decided_at = ?, expires_at = ? WHERE id = ? AND status = ?",
+		models.AccessApproved,
+		approverID,
+		note,
+		decidedAt.UTC().Format(time.RFC3339),
+		expiresAt.UTC().Format(time.RFC3339),
+		id,
+		models.AccessPending,
+	)
+}
+
+// Deny turns down a pending request or ends an approved one early.
+func (r *AccessRepository) Deny(id, approverID int64, note string, decidedAt time.Time) (bool, error) {
+	return r.transition(
+		"UPDATE access_requests SET status = ?, decided_by = ?, decision_note = ?, decided_at = ?, expires_at = NULL WHERE id = ? AND status IN (?, ?)",
+		models.AccessDenied,
+		approverID,
+		note,
+		decidedAt.UTC().Format(time.RFC3339),
+		id,
+		models.AccessPending,
+		models.AccessApproved,
+	)
+}
+
+// Expire marks an approved request whose window has closed as expired.
+func (r *AccessRepository) Expire(id int64, now time.Time) (bool, error) {
+	return r.transition(
+		"UPDATE access_requests SET status = ? WHERE id = ? AND status = ? AND expires_at <= ?",
+		models.AccessExpired,
+		id,
+		models.AccessApproved,
+		now.UTC().Format(time.RFC3339),
+	)
+}
+
+func (r *AccessRepository) transition(query string, args ...any) (bool, error) {
+	res, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func scanAccessRequest(row scanner) (*models.AccessRequest, error) {
+	var req models.AccessRequest
+	var decidedBy sql.NullInt64
+	var decidedAt, expiresAt sql.NullString
+	var createdAt string
+
+	err := row.Scan(
+		&req.ID,
+		&req.EntryID,
+		&req.EntryTitle,
+		&req.OrgID,
+		&req.UserID,
+		&req.UserEmail,
+		&req.Justification,
+		&req.DurationMinutes,
+		&req.Status,
+		&decidedBy,
+		&req.DecisionNote,
+		&decidedAt,
+		&expiresAt,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if decidedBy.Valid {
+		req.DecidedBy = &decidedBy.Int64
+	}
+	req.DecidedAt = parseNullableTime(decidedAt)
+	req.ExpiresAt = parseNullableTime(expiresAt)
+	req.CreatedAt = parseTime(createdAt)
+	return &req, nil
+}
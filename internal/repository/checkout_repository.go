@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+const checkoutSelect = `SELECT k.id, k.entry_id, k.user_id, u.email, k.status, k.checked_out_at, k.expires_at, k.checked_in_at
+	FROM entry_checkouts k
+	JOIN users u ON u.id = k.user_id`
+
+type CheckoutRepository struct {
+	db *sql.DB
+}
+
+func NewCheckoutRepository(db *sql.DB) *CheckoutRepository {
+	return &CheckoutRepository{db: db}
+}
+
+// Create starts a checkout of entryID for userID. It reports false when the
+// entry already has an active checkout.
+func (r *CheckoutRepository) Create(entryID, userID int64, now, expiresAt time.Time) (int64, bool, error) {
+	res, err := r.db.Exec(
+		`INSERT INTO entry_checkouts (entry_id, user_id, status, checked_out_at, expires_at)
+		SELECT ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM entry_checkouts WHERE entry_id = ? AND status = ?)`,
+		entryID,
+		userID,
+		models.CheckoutActive,
+		now.UTC().Format(time.RFC3339),
+		expiresAt.UTC().Format(time.RFC3339),
+		entryID,
+		models.CheckoutActive,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, false, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+func (r *CheckoutRepository) GetByID(id int64) (*models.Checkout, error) {
+	return scanCheckout(r.db.QueryRow(checkoutSelect+" WHERE k.id = ?", id))
+}
+
+// Active returns the entry's active checkout, which may have passed its
+// expiry without being swept yet, or sql.ErrNoRows.
+func (r *CheckoutRepository) Active(entryID int64) (*models.Checkout, error) {
+	return scanCheckout(r.db.QueryRow(checkoutSelect+" WHERE k.entry_id = ? AND k.status = ?", entryID, models.CheckoutActive))
+}
+
+// Holds reports whether userID holds an unexpired checkout of entryID.
+func (r *CheckoutRepository) Holds(entryID, userID int64, now time.Time) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM entry_checkouts WHERE entry_id = ? AND user_id = ? AND status = ? AND expires_at > ?)",
+		entryID,
+		userID,
+		models.CheckoutActive,
+		now.UTC().Format(time.RFC3339),
+	).Scan(&ok)
+	return ok, err
+}
+
+// ListExpired returns active checkouts past their expiry.
+func (r *CheckoutRepository) ListExpired(now time.Time) ([]models.Checkout, error) {
+	rows, err := r.db.Query(
+		checkoutSelect+" WHERE k.status = ? AND k.expires_at <= ?",
+		models.CheckoutActive,
+		now.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkouts := []models.Checkout{}
+	for rows.Next() {
+		checkout, err := scanCheckout(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkouts = append(checkouts, *checkout)
+	}
+	return checkouts, rows.Err()
+}
+
+func scanCheckout(row scanner) (*models.Checkout, error) {
+	var checkout models.Checkout
+	var checkedOutAt, expiresAt string
+	var checkedInAt sql.NullString
+
+	err := row.Scan(
+		&checkout.ID,
+		&checkout.EntryID,
+		&checkout.UserID,
+		&checkout.UserEmail,
+		&checkout.Status,
+		&checkedOutAt,
+		&expiresAt,
+		&checkedInAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	checkout.CheckedOutAt = parseTime(checkedOutAt)
+	checkout.ExpiresAt = parseTime(expiresAt)
+	checkout.CheckedInAt = parseNullableTime(checkedInAt)
+	return &checkout, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+type DomainRepository struct {
+	db *sql.DB
+}
+
+func NewDomainRepository(db *sql.DB) *DomainRepository {
+	return &DomainRepository{db: db}
+}
+
+// Create stores a group of equivalent domains. It fails with a unique
+// constraint violation when one of the domains is already in another of
+// the user's groups.
+func (r *DomainRepository) Create(group models.DomainGroup) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"INSERT INTO domain_groups (user_id, created_at) VALUES (?, ?)",
+		group.UserID,
+		group.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, domain := range group.Domains {
+		if _, err := tx.Exec(
+			"INSERT INTO domain_group_members (group_id, user_id, domain) VALUES (?, ?, ?)",
+			id,
+			group.UserID,
+			domain,
+		); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// List returns userID's domain groups, oldest first, each with its domains
+// in alphabetical order.
+func (r *DomainRepository) List(userID int64) ([]models.DomainGroup, error) {
+	rows, err := r.db.Query(
+		`SELECT g.id, g.created_at, m.domain
+		FROM domain_groups g JOIN domain_group_members m ON m.group_id = g.id
+		WHERE g.user_id = ?
+		ORDER BY g.id, m.domain`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.DomainGroup{}
+	for rows.Next() {
+		var id int64
+		var createdAt, domain string
+		if err := rows.Scan(&id, &createdAt, &domain); err != nil {
+			return nil, err
+		}
+		if len(groups) == 0 || groups[len(groups)-1].ID != id {
+			groups = append(groups, models.DomainGroup{ID: id, UserID: userID, CreatedAt: parseTime(createdAt)})
+		}
+		last := &groups[len(groups)-1]
+		last.Domains = append(last.Domains, domain)
+	}
+	return groups, rows.Err()
+}
+
+// Delete removes one of userID's domain groups. It fails with
+// sql.ErrNoRows when userID has no such group.
+func (r *DomainRepository) Delete(userID, id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM domain_groups WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM domain_group_members WHERE group_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
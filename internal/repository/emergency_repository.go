@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+const emergencySelect = `SELECT e.id, e.grantor_id, g.email, e.grantee_id, t.email, e.wait_days, e.status,
+		e.requested_at, e.grant_at, e.granted_at, e.created_at
+	FROM emergency_contacts e
+	JOIN users g ON g.id = e.grantor_id
+	JOIN users t ON t.id = e.grantee_id`
+
+type EmergencyRepository struct {
+	db *sql.DB
+}
+
+func NewEmergencyRepository(db *sql.DB) *EmergencyRepository {
+	return &EmergencyRepository{db: db}
+}
+
+func (r *EmergencyRepository) Create(grantorID, granteeID int64, waitDays int) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO emergency_contacts (grantor_id, grantee_id, wait_days, status, created_at) VALUES (?, ?, ?, ?, ?)",
+		grantorID,
+		granteeID,
+		waitDays,
+		models.EmergencyIdle,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *EmergencyRepository) GetByID(id int64) (*models.EmergencyContact, error) {
+	return scanEmergencyContact(r.db.QueryRow(emergencySelect+" WHERE e.id = ?", id))
+}
+
+func (r *EmergencyRepository) ListByGrantor(grantorID int64) ([]models.EmergencyContact, error) {
+	return r.list(emergencySelect+" WHERE e.grantor_id = ? ORDER BY e.id", grantorID)
+}
+
+func (r *EmergencyRepository) ListByGrantee(granteeID int64) ([]models.EmergencyContact, error) {
+	return r.list(emergencySelect+" WHERE e.grantee_id = ? ORDER BY e.id", granteeID)
+}
+
+// ListDue returns requests whose waiting period has passed.
+func (r *EmergencyRepository) ListDue(now time.Time) ([]models.EmergencyContact, error) {
+	return r.list(
+		emergencySelect+" WHERE e.status = ? AND e.grant_at <= ?",
+		models.EmergencyRequested,
+		now.UTC().Format(time.RFC3339),
+	)
+}
+
+func (r *EmergencyRepository) list(query string, args ...any) ([]models.EmergencyContact, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []models.EmergencyContact{}
+	for rows.Next() {
+		contact, err := scanEmergencyContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, *contact)
+	}
+	return contacts, rows.Err()
+}
+
+// Request moves an idle contact to requested; it reports false if the
+// contact was not idle.
+func (r *EmergencyRepository) Request(id int64, requestedAt, grantAt time.Time) (bool, error) {
+	return r.transition(
+		"UPDATE emergency_contacts SET status = ?, requested_at = ?, grant_at = ? WHERE id = ? AND status = ?",
+		models.EmergencyRequested,
+		requestedAt.UTC().Format(time.RFC3339),
+		grantAt.UTC().Format(time.RFC3339),
+		id,
+		models.EmergencyIdle,
+	)
+}
+
+// Grant moves a requested contact to granted; it reports false if the
+// request was rejected in the meantime.
+func (r *EmergencyRepository) Grant(id int64, grantedAt time.Time) (bool, error) {
+	return r.transition(
+		"UPDATE emergency_contacts SET status = ?, granted_at = ? WHERE id = ? AND status = ?",
+		models.EmergencyGranted,
+		grantedAt.UTC().Format(time.RFC3339),
+		id,
+		models.EmergencyRequested,
+	)
+}
+
+// Reset rejects a pending request or revokes granted access.
+func (r *EmergencyRepository) Reset(id int64) (bool, error) {
+	return r.transition(
+		"UPDATE emergency_contacts SET status = ?, requested_at = NULL, grant_at = NULL, granted_at = NULL WHERE id = ? AND status != ?",
+		models.EmergencyIdle,
+		id,
+		models.EmergencyIdle,
+	)
+}
+
+func (r *EmergencyRepository) SetWaitDays(id int64, waitDays int) error {
+	_, err := r.db.Exec("UPDATE emergency_contacts SET wait_days = ? WHERE id = ?", waitDays, id)
+	return err
+}
+
+func (r *EmergencyRepository) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM emergency_contacts WHERE id = ?", id)
+	return err
+}
+
+func (r *EmergencyRepository) transition(query string, args ...any) (bool, error) {
+	res, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func scanEmergencyContact(row scanner) (*models.EmergencyContact, error) {
+	var contact models.EmergencyContact
+	var requestedAt, grantAt, grantedAt sql.NullString
+	var createdAt string
+
+	err := row.Scan(
+		&contact.ID,
+		&contact.GrantorID,
+		&contact.GrantorEmail,
+		&contact.GranteeID,
+		&contact.GranteeEmail,
+		&contact.WaitDays,
+		&contact.Status,
+		&requestedAt,
+		&grantAt,
+		&grantedAt,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	contact.RequestedAt = parseNullableTime(requestedAt)
+	contact.GrantAt = parseNullableTime(grantAt)
+	contact.GrantedAt = parseNullableTime(grantedAt)
+	contact.CreatedAt = parseTime(createdAt)
+	return &contact, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+const folderColumns = "id, user_id, parent_id, name, created_at, updated_at"
+
+type FolderRepository struct {
+	db *sql.DB
+}
+
+func NewFolderRepository(db *sql.DB) *FolderRepository {
+	return &FolderRepository{db: db}
+}
+
+func (r *FolderRepository) Create(folder models.Folder) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO folders (user_id, parent_id, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		folder.UserID,
+		folder.ParentID,
+		folder.Name,
+		folder.CreatedAt.UTC().Format(time.RFC3339),
+		folder.UpdatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *FolderRepository) GetByID(userID, id int64) (*models.Folder, error) {
+	return scanFolder(r.db.QueryRow("SELECT "+folderColumns+" FROM folders WHERE id = ? AND user_id = ?", id, userID))
+}
+
+// List returns all of userID's folders ordered by name.
+func (r *FolderRepository) List(userID int64) ([]models.Folder, error) {
+	rows, err := r.db.Query("SELECT "+folderColumns+" FROM folders WHERE user_id = ? ORDER BY name COLLATE NOCASE, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, *folder)
+	}
+	return folders, rows.Err()
+}
+
+// Update saves a folder's name and parent.
+func (r *FolderRepository) Update(folder models.Folder) error {
+	res, err := r.db.Exec(
+		"UPDATE folders SET parent_id = ?, name = ?, updated_at = ? WHERE id = ? AND user_id = ?",
+		folder.ParentID,
+		folder.Name,
+		folder.UpdatedAt.UTC().Format(time.RFC3339),
+		folder.ID,
+		folder.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// EntryCounts returns how many live entries of userID's personal vault are
+// filed directly in each folder.
+func (r *FolderRepository) EntryCounts(userID int64) (map[int64]int, error) {
+	rows, err := r.db.Query(
+		`SELECT folder_id, COUNT(*) FROM vault_entries
+		WHERE user_id = ? AND collection_id IS NULL AND deleted_at IS NULL AND folder_id IS NOT NULL
+		GROUP BY folder_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int64]int{}
+	for rows.Next() {
+		var folderID int64
+		var n int
+		if err := rows.Scan(&folderID, &n); err != nil {
+			return nil, err
+		}
+		counts[folderID] = n
+	}
+	return counts, rows.Err()
+}
+
+// ListEntries returns the live personal entries of userID filed in any of
+// folderIDs.
+func (r *FolderRepository) ListEntries(userID int64, folderIDs []int64) ([]models.VaultEntry, error) {
+	in, args := int64Placeholders(folderIDs)
+	rows, err := r.db.Query(
+		"SELECT "+entryColumns+" FROM vault_entries WHERE user_id = ? AND collection_id IS NULL AND deleted_at IS NULL AND folder_id IN ("+in+")",
+		append([]any{userID}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.VaultEntry{}
+	for rows.Next() {
+		entry, err := scanVaultEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+// Delete removes userID's folders folderIDs, moving entries entryIDs to the
+// trash. Any other entry still filed in the folders, including trashed
+// ones, becomes unfiled.
+func (r *FolderRepository) Delete(userID int64, folderIDs, entryIDs []int64, deletedAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(entryIDs) > 0 {
+		in, args := int64Placeholders(entryIDs)
+		if _, err := tx.Exec(
+			"UPDATE vault_entries SET deleted_at = ? WHERE deleted_at IS NULL AND id IN ("+in+")",
+			append([]any{deletedAt.UTC().Format(time.RFC3339)}, args...)...,
+		); err != nil {
+			return err
+		}
+	}
+	in, args := int64Placeholders(folderIDs)
+	if _, err := tx.Exec("UPDATE vault_entries SET folder_id = NULL WHERE folder_id IN ("+in+")", args...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM folders WHERE user_id = ? AND id IN ("+in+")", append([]any{userID}, args...)...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanFolder(row scanner) (*models.Folder, error) {
+	var folder models.Folder
+	var parentID sql.NullInt64
+	var createdAt, updatedAt string
+	if err := row.Scan(&folder.ID, &folder.UserID, &parentID, &folder.Name, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		folder.ParentID = &parentID.Int64
+	}
+	folder.CreatedAt = parseTime(createdAt)
+	folder.UpdatedAt = parseTime(updatedAt)
+	return &folder, nil
+}
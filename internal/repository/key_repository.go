@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+type KeyRepository struct {
+	db *sql.DB
+}
+
+func NewKeyRepository(db *sql.DB) *KeyRepository {
+	return &KeyRepository{db: db}
+}
+
+func (r *KeyRepository) Get(userID int64) (*models.UserKey, error) {
+	var key models.UserKey
+	var salt sql.NullString
+	var createdAt string
+	err := r.db.QueryRow(
+		"SELECT user_id, public_key, private_key_enc, kdf_salt, created_at FROM user_keys WHERE user_id = ?",
+		userID,
+	).Scan(&key.UserID, &key.PublicKey, &key.PrivateKeyEnc, &salt, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	key.KDFSalt = salt.String
+	key.CreatedAt = parseTime(createdAt)
+	return &key, nil
+}
+
+// CreateIfMissing stores key unless the user already has one, so two
+// concurrent first uses settle on a single key pair.
+func (r *KeyRepository) CreateIfMissing(key models.UserKey) error {
+	_, err := r.db.Exec(
+		"INSERT OR IGNORE INTO user_keys (user_id, public_key, private_key_enc, kdf_salt, created_at) VALUES (?, ?, ?, ?, ?)",
+		key.UserID,
+		key.PublicKey,
+		key.PrivateKeyEnc,
+		key.KDFSalt,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// Rewrap replaces the wrapped private key and its salt, but only while the
+// stored copy is still previousEnc so concurrent changes don't overwrite
+// each other. It reports false if the key changed in between.
+func (r *KeyRepository) Rewrap(userID int64, previousEnc, privateKeyEnc, salt string) (bool, error) {
+	res, err := r.db.Exec(
+		"UPDATE user_keys SET private_key_enc = ?, kdf_salt = ? WHERE user_id = ? AND private_key_enc = ?",
+		privateKeyEnc,
+		salt,
+		userID,
+		previousEnc,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
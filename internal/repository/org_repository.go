@@ -0,0 +1,498 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+type OrgRepository struct {
+	db *sql.DB
+}
+
+func NewOrgRepository(db *sql.DB) *OrgRepository {
+	return &OrgRepository{db: db}
+}
+
+// Create inserts an organization with ownerID as its first owner.
+func (r *OrgRepository) Create(name string, ownerID int64) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.Exec(
+		"INSERT INTO organizations (name, created_by, created_at) VALUES (?, ?, ?)",
+		name,
+		ownerID,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	orgID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO org_members (org_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+		orgID,
+		ownerID,
+		models.OrgRoleOwner,
+		now,
+	); err != nil {
+		return 0, err
+	}
+	return orgID, tx.Commit()
+}
+
+func (r *OrgRepository) GetByID(orgID int64) (*models.Organization, error) {
+	var org models.Organization
+	var createdAt string
+	err := r.db.QueryRow(
+		"SELECT id, name, created_by, created_at FROM organizations WHERE id = ?",
+		orgID,
+	).Scan(&org.ID, &org.Name, &org.CreatedBy, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	org.CreatedAt = parseTime(createdAt)
+	return &org, nil
+}
+
+// ListByUser returns the organizations userID belongs to, with their role.
+func (r *OrgRepository) ListByUser(userID int64) ([]models.Organization, error) {
+	rows, err := r.db.Query(
+		`SELECT o.id, o.name, o.created_by, m.role, o.created_at
+		FROM organizations o JOIN org_members m ON m.org_id = o.id
+		WHERE m.user_id = ?
+		ORDER BY o.name`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var org models.Organization
+		var createdAt string
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.Role, &createdAt); err != nil {
+			return nil, err
+		}
+		org.CreatedAt = parseTime(createdAt)
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *OrgRepository) Rename(orgID int64, name string) error {
+	_, err := r.db.Exec("UPDATE organizations SET name = ? WHERE id = ?", name, orgID)
+	return err
+}
+
+// Delete removes an organization with its collections, their entries, teams
+// and memberships. Foreign keys are not enforced on connections, so every
+// dependent table is cleared explicitly.
+func (r *OrgRepository) Delete(orgID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"DELETE FROM access_requests WHERE entry_id IN (SELECT e.id FROM vault_entries e JOIN collections c ON c.id = e.collection_id WHERE c.org_id = ?)",
+		"DELETE FROM entry_checkouts WHERE entry_id IN (SELECT e.id FROM vault_entries e JOIN collections c ON c.id = e.collection_id WHERE c.org_id = ?)",
+		"DELETE FROM entry_fields WHERE entry_id IN (SELECT e.id FROM vault_entries e JOIN collections c ON c.id = e.collection_id WHERE c.org_id = ?)",
+		"DELETE FROM entry_revisions WHERE entry_id IN (SELECT e.id FROM vault_entries e JOIN collections c ON c.id = e.collection_id WHERE c.org_id = ?)",
+		"DELETE FROM entry_tags WHERE entry_id IN (SELECT e.id FROM vault_entries e JOIN collections c ON c.id = e.collection_id WHERE c.org_id = ?)",
+		"DELETE FROM entry_favorites WHERE entry_id IN (SELECT e.id FROM vault_entries e JOIN collections c ON c.id = e.collection_id WHERE c.org_id = ?)",
+		"DELETE FROM entry_uris WHERE entry_id IN (SELECT e.id FROM vault_entries e JOIN collections c ON c.id = e.collection_id WHERE c.org_id = ?)",
+		"DELETE FROM vault_entries WHERE collection_id IN (SELECT id FROM collections WHERE org_id = ?)",
+		"DELETE FROM collection_teams WHERE collection_id IN (SELECT id FROM collections WHERE org_id = ?)",
+		"DELETE FROM collections WHERE org_id = ?",
+		"DELETE FROM team_members WHERE team_id IN (SELECT id FROM teams WHERE org_id = ?)",
+		"DELETE FROM policy_attachments WHERE subject_type = 'team' AND subject_id IN (SELECT id FROM teams WHERE org_id = ?)",
+		"DELETE FROM teams WHERE org_id = ?",
+		"DELETE FROM org_members WHERE org_id = ?",
+		"DELETE FROM organizations WHERE id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, orgID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MemberRole returns userID's role in orgID, or sql.ErrNoRows if they are not a member.
+func (r *OrgRepository) MemberRole(orgID, userID int64) (string, error) {
+	var role string
+	err := r.db.QueryRow(
+		"SELECT role FROM org_members WHERE org_id = ? AND user_id = ?",
+		orgID,
+		userID,
+	).Scan(&role)
+	return role, err
+}
+
+func (r *OrgRepository) ListMembers(orgID int64) ([]models.OrgMember, error) {
+	rows, err := r.db.Query(
+		`SELECT m.org_id, m.user_id, u.email, m.role, m.created_at
+		FROM org_members m JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ?
+		ORDER BY u.email`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrgMember{}
+	for rows.Next() {
+		var member models.OrgMember
+		var createdAt string
+		if err := rows.Scan(&member.OrgID, &member.UserID, &member.Email, &member.Role, &createdAt); err != nil {
+			return nil, err
+		}
+		member.CreatedAt = parseTime(createdAt)
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (r *OrgRepository) AddMember(orgID, userID int64, role string) error {
+	_, err := r.db.Exec(
+		"INSERT INTO org_members (org_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+		orgID,
+		userID,
+		role,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (r *OrgRepository) SetMemberRole(orgID, userID int64, role string) error {
+	res, err := r.db.Exec(
+		"UPDATE org_members SET role = ? WHERE org_id = ? AND user_id = ?",
+		role,
+		orgID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// RemoveMember drops userID from the organization and all of its teams.
+func (r *OrgRepository) RemoveMember(orgID, userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM team_members WHERE user_id = ? AND team_id IN (SELECT id FROM teams WHERE org_id = ?)",
+		userID,
+		orgID,
+	); err != nil {
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM org_members WHERE org_id = ? AND user_id = ?", orgID, userID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *OrgRepository) CountOwners(orgID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM org_members WHERE org_id = ? AND role = ?",
+		orgID,
+		models.OrgRoleOwner,
+	).Scan(&n)
+	return n, err
+}
+
+func (r *OrgRepository) CreateTeam(orgID int64, name string) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO teams (org_id, name, created_at) VALUES (?, ?, ?)",
+		orgID,
+		name,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *OrgRepository) ListTeams(orgID int64) ([]models.Team, error) {
+	rows, err := r.db.Query(
+		"SELECT id, org_id, name, created_at FROM teams WHERE org_id = ? ORDER BY name",
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var team models.Team
+		var createdAt string
+		if err := rows.Scan(&team.ID, &team.OrgID, &team.Name, &createdAt); err != nil {
+			return nil, err
+		}
+		team.CreatedAt = parseTime(createdAt)
+		team.MemberIDs = []int64{}
+		index[team.ID] = len(teams)
+		teams = append(teams, team)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pairs, err := r.db.Query(
+		`SELECT tm.team_id, tm.user_id FROM team_members tm JOIN teams t ON t.id = tm.team_id
+		WHERE t.org_id = ? ORDER BY tm.user_id`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer pairs.Close()
+
+	for pairs.Next() {
+		var teamID, userID int64
+		if err := pairs.Scan(&teamID, &userID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[teamID]; ok {
+			teams[i].MemberIDs = append(teams[i].MemberIDs, userID)
+		}
+	}
+	return teams, pairs.Err()
+}
+
+// TeamInOrg reports whether teamID belongs to orgID.
+func (r *OrgRepository) TeamInOrg(orgID, teamID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM teams WHERE id = ? AND org_id = ?)",
+		teamID,
+		orgID,
+	).Scan(&ok)
+	return ok, err
+}
+
+func (r *OrgRepository) DeleteTeam(orgID, teamID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM teams WHERE id = ? AND org_id = ?", teamID, orgID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM team_members WHERE team_id = ?", teamID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM collection_teams WHERE team_id = ?", teamID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM policy_attachments WHERE subject_type = 'team' AND subject_id = ?", teamID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *OrgRepository) AddTeamMember(teamID, userID int64) error {
+	_, err := r.db.Exec(
+		"INSERT OR IGNORE INTO team_members (team_id, user_id) VALUES (?, ?)",
+		teamID,
+		userID,
+	)
+	return err
+}
+
+func (r *OrgRepository) RemoveTeamMember(teamID, userID int64) error {
+	_, err := r.db.Exec("DELETE FROM team_members WHERE team_id = ? AND user_id = ?", teamID, userID)
+	return err
+}
+
+func (r *OrgRepository) CreateCollection(orgID int64, name string) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO collections (org_id, name, created_at) VALUES (?, ?, ?)",
+		orgID,
+		name,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListCollections returns the collections of orgID that userID can read.
+func (r *OrgRepository) ListCollections(orgID, userID int64) ([]models.Collection, error) {
+	rows, err := r.db.Query(
+		"SELECT id, org_id, name, created_at FROM collections WHERE org_id = ? AND id IN ("+readableCollections+") ORDER BY name",
+		orgID,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var collection models.Collection
+		var createdAt string
+		if err := rows.Scan(&collection.ID, &collection.OrgID, &collection.Name, &createdAt); err != nil {
+			return nil, err
+		}
+		collection.CreatedAt = parseTime(createdAt)
+		collection.TeamIDs = []int64{}
+		index[collection.ID] = len(collections)
+		collections = append(collections, collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pairs, err := r.db.Query(
+		`SELECT ct.collection_id, ct.team_id FROM collection_teams ct JOIN collections c ON c.id = ct.collection_id
+		WHERE c.org_id = ? ORDER BY ct.team_id`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer pairs.Close()
+
+	for pairs.Next() {
+		var collectionID, teamID int64
+		if err := pairs.Scan(&collectionID, &teamID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[collectionID]; ok {
+			collections[i].TeamIDs = append(collections[i].TeamIDs, teamID)
+		}
+	}
+	return collections, pairs.Err()
+}
+
+// CollectionInOrg reports whether collectionID belongs to orgID.
+func (r *OrgRepository) CollectionInOrg(orgID, collectionID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM collections WHERE id = ? AND org_id = ?)",
+		collectionID,
+		orgID,
+	).Scan(&ok)
+	return ok, err
+}
+
+func (r *OrgRepository) RenameCollection(orgID, collectionID int64, name string) error {
+	res, err := r.db.Exec(
+		"UPDATE collections SET name = ? WHERE id = ? AND org_id = ?",
+		name,
+		collectionID,
+		orgID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// DeleteCollection removes a collection and the entries stored in it.
+func (r *OrgRepository) DeleteCollection(orgID, collectionID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM collections WHERE id = ? AND org_id = ?", collectionID, orgID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM collection_teams WHERE collection_id = ?", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM access_requests WHERE entry_id IN (SELECT id FROM vault_entries WHERE collection_id = ?)", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_checkouts WHERE entry_id IN (SELECT id FROM vault_entries WHERE collection_id = ?)", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_fields WHERE entry_id IN (SELECT id FROM vault_entries WHERE collection_id = ?)", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_revisions WHERE entry_id IN (SELECT id FROM vault_entries WHERE collection_id = ?)", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_tags WHERE entry_id IN (SELECT id FROM vault_entries WHERE collection_id = ?)", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_favorites WHERE entry_id IN (SELECT id FROM vault_entries WHERE collection_id = ?)", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_uris WHERE entry_id IN (SELECT id FROM vault_entries WHERE collection_id = ?)", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM vault_entries WHERE collection_id = ?", collectionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetCollectionTeams replaces the teams a collection is restricted to; an
+// empty list opens it to every member of the organization.
+func (r *OrgRepository) SetCollectionTeams(collectionID int64, teamIDs []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM collection_teams WHERE collection_id = ?", collectionID); err != nil {
+		return err
+	}
+	for _, teamID := range teamIDs {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO collection_teams (collection_id, team_id) VALUES (?, ?)",
+			collectionID,
+			teamID,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
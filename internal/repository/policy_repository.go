@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"vault/internal/models"
+)
+
+// policyDocument is the stored form of a policy's rules.
+type policyDocument struct {
+	Rules []models.PolicyRule `json:"rules"`
+}
+
+// subjectTables maps policy subject types to the table holding the subject.
+var subjectTables = map[string]string{
+	models.PolicySubjectUser:  "users",
+	models.PolicySubjectTeam:  "teams",
+	models.PolicySubjectToken: "api_tokens",
+}
+
+type PolicyRepository struct {
+	db *sql.DB
+}
+
+func NewPolicyRepository(db *sql.DB) *PolicyRepository {
+	return &PolicyRepository{db: db}
+}
+
+func (r *PolicyRepository) Create(name string, rules []models.PolicyRule) (int64, error) {
+	document, err := json.Marshal(policyDocument{Rules: rules})
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := r.db.Exec(
+		"INSERT INTO policies (name, document, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		name,
+		string(document),
+		now,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *PolicyRepository) Update(id int64, rules []models.PolicyRule) error {
+	document, err := json.Marshal(policyDocument{Rules: rules})
+	if err != nil {
+		return err
+	}
+	res, err := r.db.Exec(
+		"UPDATE policies SET document = ?, updated_at = ? WHERE id = ?",
+		string(document),
+		time.Now().UTC().Format(time.RFC3339),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetByID returns a policy with its attachments.
+func (r *PolicyRepository) GetByID(id int64) (*models.Policy, error) {
+	policy, err := scanPolicy(r.db.QueryRow("SELECT id, name, document, created_at, updated_at FROM policies WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := r.attachments("WHERE policy_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	policy.Attachments = attachments[id]
+	return policy, nil
+}
+
+// List returns every policy with its attachments.
+func (r *PolicyRepository) List() ([]models.Policy, error) {
+	policies, err := r.list("SELECT id, name, document, created_at, updated_at FROM policies ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := r.attachments("")
+	if err != nil {
+		return nil, err
+	}
+	for i := range policies {
+		policies[i].Attachments = attachments[policies[i].ID]
+	}
+	return policies, nil
+}
+
+// ForUser returns the policies attached to userID directly or through one
+// of their teams.
+func (r *PolicyRepository) ForUser(userID int64) ([]models.Policy, error) {
+	return r.list(
+		`SELECT id, name, document, created_at, updated_at FROM policies WHERE id IN (
+			SELECT policy_id FROM policy_attachments WHERE subject_type = ? AND subject_id = ?
+			UNION
+			SELECT a.policy_id FROM policy_attachments a JOIN team_members tm ON tm.team_id = a.subject_id
+			WHERE a.subject_type = ? AND tm.user_id = ?)
+		ORDER BY name`,
+		models.PolicySubjectUser,
+		userID,
+		models.PolicySubjectTeam,
+		userID,
+	)
+}
+
+// ForToken returns the policies attached to API token tokenID.
+func (r *PolicyRepository) ForToken(tokenID int64) ([]models.Policy, error) {
+	return r.list(
+		`SELECT id, name, document, created_at, updated_at FROM policies WHERE id IN (
+			SELECT policy_id FROM policy_attachments WHERE subject_type = ? AND subject_id = ?)
+		ORDER BY name`,
+		models.PolicySubjectToken,
+		tokenID,
+	)
+}
+
+// Delete removes a policy and its attachments.
+func (r *PolicyRepository) Delete(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM policies WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM policy_attachments WHERE policy_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PolicyRepository) Attach(id int64, subjectType string, subjectID int64) error {
+	_, err := r.db.Exec(
+		"INSERT OR IGNORE INTO policy_attachments (policy_id, subject_type, subject_id, created_at) VALUES (?, ?, ?, ?)",
+		id,
+		subjectType,
+		subjectID,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (r *PolicyRepository) Detach(id int64, subjectType string, subjectID int64) (bool, error) {
+	res, err := r.db.Exec(
+		"DELETE FROM policy_attachments WHERE policy_id = ? AND subject_type = ? AND subject_id = ?",
+		id,
+		subjectType,
+		subjectID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SubjectExists reports whether the user, team or token a policy would be
+// attached to exists.
+func (r *PolicyRepository) SubjectExists(subjectType string, subjectID int64) (bool, error) {
+	table, ok := subjectTables[subjectType]
+	if !ok {
+		return false, errors.New("unknown subject type")
+	}
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = ?)", subjectID).Scan(&exists)
+	return exists, err
+}
+
+func (r *PolicyRepository) list(query string, args ...any) ([]models.Policy, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []models.Policy{}
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, rows.Err()
+}
+
+// attachments loads attachments matching where, grouped by policy id.
+func (r *PolicyRepository) attachments(where string, args ...any) (map[int64][]models.PolicyAttachment, error) {
+	rows, err := r.db.Query(
+		"SELECT policy_id, subject_type, subject_id, created_at FROM policy_attachments "+where+" ORDER BY subject_type, subject_id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byPolicy := map[int64][]models.PolicyAttachment{}
+	for rows.Next() {
+		var attachment models.PolicyAttachment
+		var createdAt string
+		if err := rows.Scan(&attachment.PolicyID, &attachment.SubjectType, &attachment.SubjectID, &createdAt); err != nil {
+			return nil, err
+		}
+		attachment.CreatedAt = parseTime(createdAt)
+		byPolicy[attachment.PolicyID] = append(byPolicy[attachment.PolicyID], attachment)
+	}
+	return byPolicy, rows.Err()
+}
+
+func scanPolicy(row scanner) (*models.Policy, error) {
+	var policy models.Policy
+	var document, createdAt, updatedAt string
+
+	if err := row.Scan(&policy.ID, &policy.Name, &document, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	var doc policyDocument
+	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+		return nil, err
+	}
+	policy.Rules = doc.Rules
+	policy.CreatedAt = parseTime(createdAt)
+	policy.UpdatedAt = parseTime(updatedAt)
+	return &policy, nil
+}
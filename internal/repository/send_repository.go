@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+const sendColumns = "id, user_id, entry_id, ciphertext, has_passphrase, key_check, passphrase_salt, max_views, views, expires_at, created_at"
+
+type SendRepository struct {
+	db *sql.DB
+}
+
+func NewSendRepository(db *sql.DB) *SendRepository {
+	return &SendRepository{db: db}
+}
+
+func (r *SendRepository) Create(send models.Send) error {
+	_, err := r.db.Exec(
+		`INSERT INTO sends (id, user_id, entry_id, ciphertext, has_passphrase, key_check, passphrase_salt, max_views, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		send.ID,
+		send.UserID,
+		send.EntryID,
+		send.Ciphertext,
+		send.HasPassphrase,
+		nullableString(send.KeyCheck),
+		nullableString(send.PassphraseSalt),
+		send.MaxViews,
+		send.ExpiresAt.UTC().Format(time.RFC3339),
+		send.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetActive returns a send that has neither expired nor run out of views.
+func (r *SendRepository) GetActive(id string, now time.Time) (*models.Send, error) {
+	row := r.db.QueryRow(
+		"SELECT "+sendColumns+" FROM sends WHERE id = ? AND views < max_views AND expires_at > ?",
+		id,
+		now.UTC().Format(time.RFC3339),
+	)
+	return scanSend(row)
+}
+
+func (r *SendRepository) ListByUser(userID int64) ([]models.Send, error) {
+	rows, err := r.db.Query(
+		"SELECT "+sendColumns+" FROM sends WHERE user_id = ? ORDER BY created_at DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sends := []models.Send{}
+	for rows.Next() {
+		send, err := scanSend(rows)
+		if err != nil {
+			return nil, err
+		}
+		sends = append(sends, *send)
+	}
+	return sends, rows.Err()
+}
+
+// ConsumeView counts one view unless the send expired or ran out in the
+// meantime, deleting it once the last view is used. It reports whether the
+// view was granted.
+func (r *SendRepository) ConsumeView(id string, now time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"UPDATE sends SET views = views + 1 WHERE id = ? AND views < max_views AND expires_at > ?",
+		id,
+		now.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM sends WHERE id = ? AND views >= max_views", id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RecordFailure counts a wrong passphrase for send id and deletes the send
+// once limit wrong passphrases have been given. It reports whether the send
+// was deleted.
+func (r *SendRepository) RecordFailure(id string, limit int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE sends SET failed_attempts = failed_attempts + 1 WHERE id = ?", id); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec("DELETE FROM sends WHERE id = ? AND failed_attempts >= ?", id, limit)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+func (r *SendRepository) Delete(userID int64, id string) (bool, error) {
+	res, err := r.db.Exec("DELETE FROM sends WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *SendRepository) DeleteExpired(now time.Time) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM sends WHERE expires_at <= ?", now.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanSend(row scanner) (*models.Send, error) {
+	var send models.Send
+	var entryID sql.NullInt64
+	var keyCheck, salt sql.NullString
+	var expiresAt string
+	var createdAt string
+
+	err := row.Scan(
+		&send.ID,
+		&send.UserID,
+		&entryID,
+		&send.Ciphertext,
+		&send.HasPassphrase,
+		&keyCheck,
+		&salt,
+		&send.MaxViews,
+		&send.Views,
+		&expiresAt,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if entryID.Valid {
+		send.EntryID = &entryID.Int64
+	}
+	send.KeyCheck = keyCheck.String
+	send.PassphraseSalt = salt.String
+	send.ExpiresAt = parseTime(expiresAt)
+	send.CreatedAt = parseTime(createdAt)
+	return &send, nil
+}
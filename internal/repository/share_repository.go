@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+const shareColumns = "s.id, s.entry_id, s.owner_id, s.recipient_id, u.email, s.permission, s.key_sealed, s.created_at"
+
+type ShareRepository struct {
+	db *sql.DB
+}
+
+func NewShareRepository(db *sql.DB) *ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+// Upsert creates a share or, if the recipient already has one for the
+// entry, replaces its permission and sealed key.
+func (r *ShareRepository) Upsert(share models.EntryShare) error {
+	_, err := r.db.Exec(
+		`INSERT INTO entry_shares (entry_id, owner_id, recipient_id, permission, key_sealed, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (entry_id, recipient_id) DO UPDATE SET permission = excluded.permission, key_sealed = excluded.key_sealed`,
+		share.EntryID,
+		share.OwnerID,
+		share.RecipientID,
+		share.Permission,
+		share.KeySealed,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (r *ShareRepository) ListByEntry(entryID int64) ([]models.EntryShare, error) {
+	rows, err := r.db.Query(
+		`SELECT `+shareColumns+`
+		FROM entry_shares s JOIN users u ON u.id = s.recipient_id
+		WHERE s.entry_id = ?
+		ORDER BY s.id`,
+		entryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.EntryShare{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, *share)
+	}
+	return shares, rows.Err()
+}
+
+// GetForRecipient returns the share giving recipientID access to entryID.
+func (r *ShareRepository) GetForRecipient(entryID, recipientID int64) (*models.EntryShare, error) {
+	row := r.db.QueryRow(
+		`SELECT `+shareColumns+`
+		FROM entry_shares s JOIN users u ON u.id = s.recipient_id
+		WHERE s.entry_id = ? AND s.recipient_id = ?`,
+		entryID,
+		recipientID,
+	)
+	return scanShare(row)
+}
+
+func (r *ShareRepository) GetByID(entryID, shareID int64) (*models.EntryShare, error) {
+	row := r.db.QueryRow(
+		`SELECT `+shareColumns+`
+		FROM entry_shares s JOIN users u ON u.id = s.recipient_id
+		WHERE s.entry_id = ? AND s.id = ?`,
+		entryID,
+		shareID,
+	)
+	return scanShare(row)
+}
+
+func (r *ShareRepository) Delete(entryID, shareID int64) error {
+	_, err := r.db.Exec("DELETE FROM entry_shares WHERE entry_id = ? AND id = ?", entryID, shareID)
+	return err
+}
+
+func (r *ShareRepository) DeleteByEntry(entryID int64) error {
+	_, err := r.db.Exec("DELETE FROM entry_shares WHERE entry_id = ?", entryID)
+	return err
+}
+
+func scanShare(row scanner) (*models.EntryShare, error) {
+	var share models.EntryShare
+	var createdAt string
+	err := row.Scan(
+		&share.ID,
+		&share.EntryID,
+		&share.OwnerID,
+		&share.RecipientID,
+		&share.RecipientEmail,
+		&share.Permission,
+		&share.KeySealed,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	share.CreatedAt = parseTime(createdAt)
+	return &share, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"vault/internal/models"
+)
+
+type TokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) Create(token models.APIToken) (int64, error) {
+	res, err := r.db.Exec(
+		`INSERT INTO api_tokens (user_id, name, prefix, token_hash, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		token.UserID,
+		token.Name,
+		token.Prefix,
+		token.TokenHash,
+		strings.Join(token.Scopes, " "),
+		formatNullableTime(token.ExpiresAt),
+		token.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *TokenRepository) ListByUser(userID int64) ([]models.APIToken, error) {
+	rows, err := r.db.Query(
+		"SELECT id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE user_id = ? ORDER BY id DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *TokenRepository) GetByHash(hash string) (*models.APIToken, error) {
+	row := r.db.QueryRow(
+		"SELECT id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE token_hash = ?",
+		hash,
+	)
+	return scanAPIToken(row)
+}
+
+// Delete removes a token and the policies attached to it, and reports
+// whether the user owned one with that id.
+func (r *TokenRepository) Delete(userID, id int64) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM api_tokens WHERE user_id = ? AND id = ?", userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM policy_attachments WHERE subject_type = 'token' AND subject_id = ?", id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *TokenRepository) TouchLastUsed(id int64, usedAt time.Time) error {
+	_, err := r.db.Exec(
+		"UPDATE api_tokens SET last_used_at = ? WHERE id = ?",
+		usedAt.UTC().Format(time.RFC3339),
+		id,
+	)
+	return err
+}
+
+func scanAPIToken(row scanner) (*models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	var expiresAt sql.NullString
+	var lastUsed sql.NullString
+	var createdAt string
+
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		&scopes,
+		&expiresAt,
+		&lastUsed,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	token.CreatedAt = parseTime(createdAt)
+	token.ExpiresAt = parseNullableTime(expiresAt)
+	token.LastUsedAt = parseNullableTime(lastUsed)
+	return &token, nil
+}
+
+func formatNullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseNullableTime(value sql.NullString) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := parseTime(value.String)
+	return &t
+}
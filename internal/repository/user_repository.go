@@ -7,6 +7,8 @@ import (
 	"vault/internal/models"
 )
 
+const userColumns = "u.id, u.email, u.password_hash, u.auth_backend, u.webauthn_handle, u.webauthn_mfa, u.role, u.disabled_at, u.sessions_valid_after, u.created_at"
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -30,30 +32,233 @@ func (r *UserRepository) Create(email, passwordHash string) (int64, error) {
 
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	row := r.db.QueryRow(
-		"SELECT id, email, password_hash, created_at FROM users WHERE email = ?",
+		"SELECT "+userColumns+" FROM users u WHERE u.email = ?",
 		email,
 	)
-
-	var user models.User
-	var createdAt string
-	if err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &createdAt); err != nil {
-		return nil, err
-	}
-	user.CreatedAt = parseTime(createdAt)
-	return &user, nil
+	return scanUser(row)
 }
 
 func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 	row := r.db.QueryRow(
-		"SELECT id, email, password_hash, created_at FROM users WHERE id = ?",
+		"SELECT "+userColumns+" FROM users u WHERE u.id = ?",
 		id,
 	)
+	return scanUser(row)
+}
+
+// GetByIdentity finds the user linked to an external provider subject.
+func (r *UserRepository) GetByIdentity(provider, subject string) (*models.User, error) {
+	row := r.db.QueryRow(
+		`SELECT `+userColumns+`
+		FROM users u JOIN user_identities i ON i.user_id = u.id
+		WHERE i.provider = ? AND i.subject = ?`,
+		provider,
+		subject,
+	)
+	return scanUser(row)
+}
+
+func (r *UserRepository) LinkIdentity(userID int64, provider, subject string) error {
+	_, err := r.db.Exec(
+		"INSERT INTO user_identities (user_id, provider, subject, created_at) VALUES (?, ?, ?, ?)",
+		userID,
+		provider,
+		subject,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// SetAuthBackend pins a user to a credential backend; "" follows the global default.
+func (r *UserRepository) SetAuthBackend(userID int64, backend string) error {
+	var value any
+	if backend != "" {
+		value = backend
+	}
+	_, err := r.db.Exec("UPDATE users SET auth_backend = ? WHERE id = ?", value, userID)
+	return err
+}
+
+func (r *UserRepository) GetByWebAuthnHandle(handle string) (*models.User, error) {
+	row := r.db.QueryRow(
+		"SELECT "+userColumns+" FROM users u WHERE u.webauthn_handle = ?",
+		handle,
+	)
+	return scanUser(row)
+}
+
+func (r *UserRepository) SetWebAuthnHandle(userID int64, handle string) error {
+	_, err := r.db.Exec("UPDATE users SET webauthn_handle = ? WHERE id = ?", handle, userID)
+	return err
+}
+
+func (r *UserRepository) SetWebAuthnMFA(userID int64, enabled bool) error {
+	_, err := r.db.Exec("UPDATE users SET webauthn_mfa = ? WHERE id = ?", enabled, userID)
+	return err
+}
+
+func (r *UserRepository) List() ([]models.User, error) {
+	rows, err := r.db.Query("SELECT " + userColumns + " FROM users u ORDER BY u.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (r *UserRepository) SetRole(userID int64, role string) error {
+	_, err := r.db.Exec("UPDATE users SET role = ? WHERE id = ?", role, userID)
+	return err
+}
 
+// SetDisabled disables the user at the given time, or re-enables them when nil.
+func (r *UserRepository) SetDisabled(userID int64, disabledAt *time.Time) error {
+	_, err := r.db.Exec("UPDATE users SET disabled_at = ? WHERE id = ?", formatNullableTime(disabledAt), userID)
+	return err
+}
+
+func (r *UserRepository) SetSessionsValidAfter(userID int64, validAfter time.Time) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET sessions_valid_after = ? WHERE id = ?",
+		validAfter.UTC().Format(time.RFC3339),
+		userID,
+	)
+	return err
+}
+
+// userOwnedTables lists tables holding rows that belong to a user. They are
+// cleared explicitly because connections do not enable foreign_keys, so the
+// schema's ON DELETE CASCADE cannot be relied on. Entries the user created
+// in organization collections belong to the organization and are kept.
+var userOwnedTables = []string{
+	"api_tokens",
+	"webauthn_credentials",
+	"user_identities",
+	"org_members",
+	"team_members",
+	"user_keys",
+	"sends",
+	"access_requests",
+	"entry_checkouts",
+	"folders",
+	"entry_favorites",
+	"domain_group_members",
+	"domain_groups",
+}
+
+// Delete removes a user together with everything they own.
+func (r *UserRepository) Delete(userID int64) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM entry_fields WHERE entry_id IN (SELECT id FROM vault_entries WHERE user_id = ? AND collection_id IS NULL)", userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_revisions WHERE entry_id IN (SELECT id FROM vault_entries WHERE user_id = ? AND collection_id IS NULL)", userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_tags WHERE entry_id IN (SELECT id FROM vault_entries WHERE user_id = ? AND collection_id IS NULL)", userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_favorites WHERE entry_id IN (SELECT id FROM vault_entries WHERE user_id = ? AND collection_id IS NULL)", userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_uris WHERE entry_id IN (SELECT id FROM vault_entries WHERE user_id = ? AND collection_id IS NULL)", userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM vault_entries WHERE user_id = ? AND collection_id IS NULL", userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_shares WHERE owner_id = ? OR recipient_id = ?", userID, userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM emergency_contacts WHERE grantor_id = ? OR grantee_id = ?", userID, userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(
+		`DELETE FROM policy_attachments WHERE (subject_type = 'user' AND subject_id = ?)
+		OR (subject_type = 'token' AND subject_id IN (SELECT id FROM api_tokens WHERE user_id = ?))`,
+		userID,
+		userID,
+	); err != nil {
+		return false, err
+	}
+	for _, table := range userOwnedTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", userID); err != nil {
+			return false, err
+		}
+	}
+
+	res, err := tx.Exec("DELETE FROM users WHERE id = ?", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+func (r *UserRepository) Stats(userID int64) (*models.UserStats, error) {
+	stats := models.UserStats{UserID: userID}
+	var lastAccessed sql.NullString
+
+	err := r.db.QueryRow(
+		`SELECT
+			(SELECT COUNT(*) FROM vault_entries WHERE user_id = ? AND collection_id IS NULL AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM vault_entries WHERE user_id = ? AND collection_id IS NULL AND deleted_at IS NULL AND sensitive = 1),
+			(SELECT COUNT(*) FROM api_tokens WHERE user_id = ?),
+			(SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = ?),
+			(SELECT MAX(last_accessed_at) FROM vault_entries WHERE user_id = ? AND collection_id IS NULL)`,
+		userID, userID, userID, userID, userID,
+	).Scan(&stats.Entries, &stats.Sensitive, &stats.APITokens, &stats.Passkeys, &lastAccessed)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.LastAccessedAt = parseNullableTime(lastAccessed)
+	return &stats, nil
+}
+
+func scanUser(row scanner) (*models.User, error) {
 	var user models.User
+	var backend sql.NullString
+	var handle sql.NullString
+	var disabledAt sql.NullString
+	var validAfter sql.NullString
 	var createdAt string
-	if err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &createdAt); err != nil {
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&backend,
+		&handle,
+		&user.WebAuthnMFA,
+		&user.Role,
+		&disabledAt,
+		&validAfter,
+		&createdAt,
+	)
+	if err != nil {
 		return nil, err
 	}
+	user.AuthBackend = backend.String
+	user.WebAuthnHandle = handle.String
+	user.DisabledAt = parseNullableTime(disabledAt)
+	user.SessionsValidAfter = parseNullableTime(validAfter)
 	user.CreatedAt = parseTime(createdAt)
 	return &user, nil
 }
@@ -2,11 +2,55 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
 	"time"
 
 	"vault/internal/models"
 )
 
+const entryColumns = "id, user_id, type, title, username, password_enc, key_enc, data_enc, url, category, notes, sensitive, requires_approval, checkout_required, password_version, collection_id, folder_id, created_at, updated_at, last_accessed_at, deleted_at, access_count, url_match"
+
+// collectionsFor selects the ids of collections a user (bound once) may use
+// when holding one of roles. Owners and admins reach every collection in
+// their organization; other roles reach open collections and those shared
+// with one of their teams.
+func collectionsFor(roles string) string {
+	return `SELECT c.id FROM collections c
+		JOIN org_members m ON m.org_id = c.org_id AND m.user_id = ?
+		WHERE m.role IN ('owner', 'admin')
+			OR (m.role IN (` + roles + `) AND (
+				NOT EXISTS (SELECT 1 FROM collection_teams ct WHERE ct.collection_id = c.id)
+				OR EXISTS (SELECT 1 FROM collection_teams ct JOIN team_members tm ON tm.team_id = ct.team_id
+					WHERE ct.collection_id = c.id AND tm.user_id = m.user_id)))`
+}
+
+var (
+	readableCollections = collectionsFor("'member', 'readonly'")
+	writableCollections = collectionsFor("'member'")
+
+	// entryControlled covers the user's personal entries and entries in
+	// collections they can write, trashed or not. It binds the user id twice.
+	entryControlled = "((user_id = ? AND collection_id IS NULL) OR collection_id IN (" + writableCollections + "))"
+
+	// entryManageable is entryControlled minus the trash: these entries can
+	// be deleted, moved and shared. It binds the user id twice.
+	entryManageable = "(deleted_at IS NULL AND " + entryControlled + ")"
+
+	// entryReadable and entryWritable add entries shared with the user; each
+	// binds the user id three times. Trashed entries are in neither.
+	entryReadable = "(deleted_at IS NULL AND ((user_id = ? AND collection_id IS NULL) OR collection_id IN (" + readableCollections + ")" +
+		" OR id IN (SELECT entry_id FROM entry_shares WHERE recipient_id = ?)))"
+	entryWritable = "(deleted_at IS NULL AND (" + entryControlled +
+		" OR id IN (SELECT entry_id FROM entry_shares WHERE recipient_id = ? AND permission = 'edit')))"
+
+	// entryTrashed selects the trashed entries the user controls; it binds
+	// the user id twice.
+	entryTrashed = "(deleted_at IS NOT NULL AND " + entryControlled + ")"
+)
+
 type VaultRepository struct {
 	db *sql.DB
 }
@@ -15,11 +59,220 @@ func NewVaultRepository(db *sql.DB) *VaultRepository {
 	return &VaultRepository{db: db}
 }
 
-func (r *VaultRepository) ListByUser(userID int64) ([]models.VaultEntry, error) {
-	rows, err := r.db.Query(
-		"SELECT id, user_id, title, username, password_enc, url, category, notes, created_at, updated_at, last_accessed_at FROM vault_entries WHERE user_id = ? ORDER BY id DESC",
+// entryFilter returns the conditions and arguments selecting entries that
+// match filter as seen by userID, each condition preceded by AND.
+func entryFilter(userID int64, filter models.EntryFilter) (string, []any) {
+	where := ""
+	args := []any{}
+	if filter.Type != "" {
+		where += " AND type = ?"
+		args = append(args, filter.Type)
+	}
+	if filter.FolderID != 0 {
+		where += ` AND folder_id IN (
+			WITH RECURSIVE subtree(id) AS (
+				SELECT ? UNION ALL SELECT f.id FROM folders f JOIN subtree ON f.parent_id = subtree.id)
+			SELECT id FROM subtree)`
+		args = append(args, filter.FolderID)
+	}
+	for _, tag := range filter.Tags {
+		where += " AND id IN (SELECT entry_id FROM entry_tags WHERE tag = ?)"
+		args = append(args, tag)
+	}
+	if filter.Favorite {
+		where += " AND id IN (SELECT entry_id FROM entry_favorites WHERE user_id = ?)"
+		args = append(args, userID)
+	}
+	if filter.Category != "" {
+		where += " AND category = ? COLLATE NOCASE"
+		args = append(args, filter.Category)
+	}
+	for _, bound := range []struct {
+		cond string
+		at   time.Time
+	}{
+		{" AND created_at >= ?", filter.CreatedAfter},
+		{" AND created_at < ?", filter.CreatedBefore},
+		{" AND updated_at >= ?", filter.UpdatedAfter},
+		{" AND updated_at < ?", filter.UpdatedBefore},
+	} {
+		if !bound.at.IsZero() {
+			where += bound.cond
+			args = append(args, bound.at.UTC().Format(time.RFC3339))
+		}
+	}
+	return where, args
+}
+
+// entrySortKeys maps the sort keys of models.PageRequest to the column
+// expressions entries are ordered by. Relevance only exists in searches.
+var entrySortKeys = map[string]string{
+	models.SortCreated:   "created_at",
+	models.SortUpdated:   "updated_at",
+	models.SortTitle:     "title COLLATE NOCASE",
+	models.SortAccessed:  "COALESCE(last_accessed_at, '')",
+	models.SortRelevance: "hit_score",
+}
+
+// Snippets mark matched terms with private-use characters, which survive
+// HTML escaping, and highlightSnippet turns them into <mark> tags.
+const (
+	snippetOpen  = "\uE000"
+	snippetClose = "\uE001"
+)
+
+// entrySearchHits ranks the entries matching an FTS5 query by BM25, weighing
+// titles most and notes least, with a highlighted snippet of the best
+// matching column.
+const entrySearchHits = `WITH hits AS (
+	SELECT rowid AS hit_id,
+		-bm25(entry_search, 10.0, 4.0, 4.0, 2.0, 1.0) AS hit_score,
+		snippet(entry_search, -1, '` + snippetOpen + `', '` + snippetClose + `', '…', 12) AS hit_snippet
+	FROM entry_search WHERE entry_search MATCH ?)
+`
+
+// highlightSnippet HTML-escapes the entry text of snippet and wraps the
+// matched terms in <mark> tags, so clients can render it as markup.
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer(snippetOpen, "<mark>", snippetClose, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// entryMatch returns the condition and arguments selecting the entries
+// userID can read that match filter.
+func entryMatch(userID int64, filter models.EntryFilter) (string, []any) {
+	cond, args := entryFilter(userID, filter)
+	return entryReadable + cond, append([]any{userID, userID, userID}, args...)
+}
+
+// SearchTexts returns the values of columns, which must be names of
+// entry columns and not empty, across the entries selected as by
+// entryMatch. Typo-tolerant search takes its candidate words from them, so
+// only the caller's own entries are compared.
+func (r *VaultRepository) SearchTexts(userID int64, filter models.EntryFilter, columns []string) ([]string, error) {
+	selected := make([]string, len(columns))
+	for i, col := range columns {
+		selected[i] = "COALESCE(" + col + ", '')"
+	}
+	where, args := entryMatch(userID, filter)
+	rows, err := r.db.Query("SELECT "+strings.Join(selected, ", ")+" FROM vault_entries WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	texts := []string{}
+	values := make([]string, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		texts = append(texts, values...)
+	}
+	return texts, rows.Err()
+}
+
+// ListPage returns up to limit entries selected as by entryMatch, ordered
+// by sort (a key of entrySortKeys) with ties broken by id, starting after
+// the cursor position when after is set. A non-empty search is an FTS5
+// query the entries must match; their Snippet is then set. It also returns
+// each entry's sort column value for building the next cursor. A limit of 0
+// returns them all.
+func (r *VaultRepository) ListPage(userID int64, search string, filter models.EntryFilter, sort string, desc bool, after *models.EntryCursor, limit int) ([]models.VaultEntry, []string, error) {
+	key, ok := entrySortKeys[sort]
+	if !ok || (sort == models.SortRelevance && search == "") {
+		return nil, nil, fmt.Errorf("unknown sort %q", sort)
+	}
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	query := "SELECT " + entryColumns + ", " + key
+	args := []any{}
+	if search != "" {
+		query = entrySearchHits + query + ", hit_snippet FROM vault_entries JOIN hits ON hit_id = id"
+		args = append(args, search)
+	} else {
+		query += " FROM vault_entries"
+	}
+	where, whereArgs := entryMatch(userID, filter)
+	args = append(args, whereArgs...)
+	if after != nil {
+		var afterKey any = after.Key
+		if sort == models.SortRelevance {
+			score, err := strconv.ParseFloat(after.Key, 64)
+			if err != nil {
+				return nil, nil, err
+			}
+			afterKey = score
+		}
+		where += " AND (" + key + " " + cmp + " ? OR (" + key + " = ? AND id " + cmp + " ?))"
+		args = append(args, afterKey, afterKey, after.ID)
+	}
+	query += " WHERE " + where + " ORDER BY " + key + " " + dir + ", id " + dir
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.VaultEntry{}
+	keys := []string{}
+	for rows.Next() {
+		var sortKey, snippet string
+		extra := []any{&sortKey}
+		if search != "" {
+			extra = append(extra, &snippet)
+		}
+		entry, err := scanVaultEntry(extraColumns{rows, extra})
+		if err != nil {
+			return nil, nil, err
+		}
+		entry.Snippet = highlightSnippet(snippet)
+		entries = append(entries, *entry)
+		keys = append(keys, sortKey)
+	}
+	return entries, keys, rows.Err()
+}
+
+// Count returns how many entries ListPage selects across all pages.
+func (r *VaultRepository) Count(userID int64, search string, filter models.EntryFilter) (int, error) {
+	where, args := entryMatch(userID, filter)
+	if search != "" {
+		where += " AND id IN (SELECT rowid FROM entry_search WHERE entry_search MATCH ?)"
+		args = append(args, search)
+	}
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM vault_entries WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+func (r *VaultRepository) GetByID(userID, id int64) (*models.VaultEntry, error) {
+	row := r.db.QueryRow(
+		"SELECT "+entryColumns+" FROM vault_entries WHERE id = ? AND "+entryReadable,
+		id,
+		userID,
+		userID,
 		userID,
 	)
+	return scanVaultEntry(row)
+}
+
+// ListPersonal returns entries in ownerID's personal vault only, excluding
+// collections, entries shared with them and the trash.
+func (r *VaultRepository) ListPersonal(ownerID int64) ([]models.VaultEntry, error) {
+	rows, err := r.db.Query(
+		"SELECT "+entryColumns+" FROM vault_entries WHERE user_id = ? AND collection_id IS NULL AND deleted_at IS NULL ORDER BY id DESC",
+		ownerID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -36,68 +289,672 @@ func (r *VaultRepository) ListByUser(userID int64) ([]models.VaultEntry, error)
 	return entries, rows.Err()
 }
 
-func (r *VaultRepository) GetByID(userID, id int64) (*models.VaultEntry, error) {
+func (r *VaultRepository) GetPersonal(ownerID, id int64) (*models.VaultEntry, error) {
 	row := r.db.QueryRow(
-		"SELECT id, user_id, title, username, password_enc, url, category, notes, created_at, updated_at, last_accessed_at FROM vault_entries WHERE user_id = ? AND id = ?",
-		userID,
+		"SELECT "+entryColumns+" FROM vault_entries WHERE id = ? AND user_id = ? AND collection_id IS NULL AND deleted_at IS NULL",
 		id,
+		ownerID,
 	)
 	return scanVaultEntry(row)
 }
 
+// Create stores an entry with its custom fields and URIs.
 func (r *VaultRepository) Create(entry models.VaultEntry) (int64, error) {
-	res, err := r.db.Exec(
-		`INSERT INTO vault_entries (user_id, title, username, password_enc, url, category, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`INSERT INTO vault_entries (user_id, type, title, username, password_enc, key_enc, data_enc, url, url_match, category, notes, sensitive, requires_approval, checkout_required, collection_id, folder_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		entry.UserID,
+		entry.Type,
 		entry.Title,
 		entry.Username,
 		entry.PasswordEnc,
+		nullableString(entry.KeyEnc),
+		nullableString(entry.DataEnc),
 		entry.URL,
+		nullableString(entry.URLMatch),
 		entry.Category,
 		entry.Notes,
+		entry.Sensitive,
+		entry.RequiresApproval,
+		entry.CheckoutRequired,
+		entry.CollectionID,
+		entry.FolderID,
 		entry.CreatedAt.UTC().Format(time.RFC3339),
 		entry.UpdatedAt.UTC().Format(time.RFC3339),
 	)
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := replaceFields(tx, id, entry.Fields); err != nil {
+		return 0, err
+	}
+	if err := replaceURIs(tx, id, entry.URIs); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-func (r *VaultRepository) Update(entry models.VaultEntry) error {
-	_, err := r.db.Exec(
+// Update saves entry on behalf of userID, who must be able to write it.
+// entry.UserID and entry.CollectionID are stored as given so the service
+// can move entries between a personal vault and collections. Custom fields
+// and URIs are replaced unless nil.
+func (r *VaultRepository) Update(userID int64, entry models.VaultEntry) error {
+	return r.UpdateWithRevision(userID, entry, nil, 0)
+}
+
+// UpdateWithRevision is Update that also records revision, the entry as it
+// was before, and drops all but the newest keep revisions of the entry.
+func (r *VaultRepository) UpdateWithRevision(userID int64, entry models.VaultEntry, revision *models.EntryRevision, keep int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
 		`UPDATE vault_entries
-		SET title = ?, username = ?, password_enc = ?, url = ?, category = ?, notes = ?, updated_at = ?
-		WHERE user_id = ? AND id = ?`,
+		SET user_id = ?, title = ?, username = ?, password_enc = ?, key_enc = ?, data_enc = ?, url = ?, url_match = ?, category = ?, notes = ?, sensitive = ?, requires_approval = ?, checkout_required = ?, password_version = ?, collection_id = ?, folder_id = ?, updated_at = ?
+		WHERE id = ? AND `+entryWritable,
+		entry.UserID,
 		entry.Title,
 		entry.Username,
 		entry.PasswordEnc,
+		nullableString(entry.KeyEnc),
+		nullableString(entry.DataEnc),
 		entry.URL,
+		nullableString(entry.URLMatch),
 		entry.Category,
 		entry.Notes,
+		entry.Sensitive,
+		entry.RequiresApproval,
+		entry.CheckoutRequired,
+		entry.PasswordVersion,
+		entry.CollectionID,
+		entry.FolderID,
 		entry.UpdatedAt.UTC().Format(time.RFC3339),
-		entry.UserID,
 		entry.ID,
+		userID,
+		userID,
+		userID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	if entry.Fields != nil {
+		if err := replaceFields(tx, entry.ID, entry.Fields); err != nil {
+			return err
+		}
+	}
+	if entry.URIs != nil {
+		if err := replaceURIs(tx, entry.ID, entry.URIs); err != nil {
+			return err
+		}
+	}
+	if revision != nil {
+		if err := addRevision(tx, revision, keep); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func replaceFields(tx *sql.Tx, entryID int64, fields []models.CustomField) error {
+	if _, err := tx.Exec("DELETE FROM entry_fields WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	for i, field := range fields {
+		if _, err := tx.Exec(
+			"INSERT INTO entry_fields (entry_id, position, name, type, value, linked_entry_id, linked_field) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			entryID,
+			i,
+			field.Name,
+			field.Type,
+			field.Value,
+			field.LinkedEntryID,
+			nullableString(field.LinkedField),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListFields returns the custom fields of the given entries, in order and
+// keyed by entry id.
+func (r *VaultRepository) ListFields(entryIDs []int64) (map[int64][]models.CustomField, error) {
+	fields := map[int64][]models.CustomField{}
+	if len(entryIDs) == 0 {
+		return fields, nil
+	}
+	placeholders, args := int64Placeholders(entryIDs)
+	rows, err := r.db.Query(
+		"SELECT entry_id, name, type, value, linked_entry_id, linked_field FROM entry_fields WHERE entry_id IN ("+placeholders+") ORDER BY entry_id, position",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int64
+		var field models.CustomField
+		var linkedEntryID sql.NullInt64
+		var linkedField sql.NullString
+		if err := rows.Scan(&entryID, &field.Name, &field.Type, &field.Value, &linkedEntryID, &linkedField); err != nil {
+			return nil, err
+		}
+		if linkedEntryID.Valid {
+			field.LinkedEntryID = &linkedEntryID.Int64
+		}
+		field.LinkedField = linkedField.String
+		fields[entryID] = append(fields[entryID], field)
+	}
+	return fields, rows.Err()
+}
+
+func replaceURIs(tx *sql.Tx, entryID int64, uris []models.EntryURI) error {
+	if _, err := tx.Exec("DELETE FROM entry_uris WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	for i, uri := range uris {
+		if _, err := tx.Exec(
+			"INSERT INTO entry_uris (entry_id, position, uri, match_mode) VALUES (?, ?, ?, ?)",
+			entryID,
+			i,
+			uri.URI,
+			nullableString(uri.Match),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListURIs returns the further URIs of the given entries, in order and
+// keyed by entry.
+func (r *VaultRepository) ListURIs(entryIDs []int64) (map[int64][]models.EntryURI, error) {
+	uris := map[int64][]models.EntryURI{}
+	if len(entryIDs) == 0 {
+		return uris, nil
+	}
+	placeholders, args := int64Placeholders(entryIDs)
+	rows, err := r.db.Query(
+		"SELECT entry_id, uri, match_mode FROM entry_uris WHERE entry_id IN ("+placeholders+") ORDER BY entry_id, position",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int64
+		var uri models.EntryURI
+		var match sql.NullString
+		if err := rows.Scan(&entryID, &uri.URI, &match); err != nil {
+			return nil, err
+		}
+		uri.Match = match.String
+		uris[entryID] = append(uris[entryID], uri)
+	}
+	return uris, rows.Err()
+}
+
+// Trash moves an entry the user manages to the trash.
+func (r *VaultRepository) Trash(userID, id int64, deletedAt time.Time) error {
+	res, err := r.db.Exec(
+		"UPDATE vault_entries SET deleted_at = ? WHERE id = ? AND "+entryManageable,
+		deletedAt.UTC().Format(time.RFC3339),
+		id,
+		userID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// ListTrash returns the trashed entries userID controls, most recently
+// deleted first.
+func (r *VaultRepository) ListTrash(userID int64) ([]models.VaultEntry, error) {
+	rows, err := r.db.Query(
+		"SELECT "+entryColumns+" FROM vault_entries WHERE "+entryTrashed+" ORDER BY deleted_at DESC, id DESC",
+		userID,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.VaultEntry{}
+	for rows.Next() {
+		entry, err := scanVaultEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *VaultRepository) GetTrashed(userID, id int64) (*models.VaultEntry, error) {
+	return scanVaultEntry(r.db.QueryRow(
+		"SELECT "+entryColumns+" FROM vault_entries WHERE id = ? AND "+entryTrashed,
+		id,
+		userID,
+		userID,
+	))
 }
 
-func (r *VaultRepository) Delete(userID, id int64) error {
-	_, err := r.db.Exec("DELETE FROM vault_entries WHERE user_id = ? AND id = ?", userID, id)
+// Untrash takes an entry userID controls back out of the trash.
+func (r *VaultRepository) Untrash(userID, id int64) error {
+	res, err := r.db.Exec(
+		"UPDATE vault_entries SET deleted_at = NULL WHERE id = ? AND "+entryTrashed,
+		id,
+		userID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// Purge permanently removes a trashed entry userID controls.
+func (r *VaultRepository) Purge(userID, id int64) error {
+	return r.purge("id = ? AND "+entryTrashed, id, userID, userID)
+}
+
+// PurgeTrashedBefore permanently removes every entry trashed before cutoff
+// and returns how many there were.
+func (r *VaultRepository) PurgeTrashedBefore(cutoff time.Time) (int64, error) {
+	var n int64
+	cond := "deleted_at IS NOT NULL AND deleted_at < ?"
+	at := cutoff.UTC().Format(time.RFC3339)
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM vault_entries WHERE "+cond, at).Scan(&n); err != nil || n == 0 {
+		return 0, err
+	}
+	return n, r.purge(cond, at)
+}
+
+// purge deletes the entries matching cond together with their shares, access
+// requests, checkouts, custom fields, revisions, tags, favorites and URIs. It
+// fails with sql.ErrNoRows when nothing matches.
+func (r *VaultRepository) purge(cond string, args ...any) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"entry_shares", "access_requests", "entry_checkouts", "entry_fields", "entry_revisions", "entry_tags", "entry_favorites", "entry_uris"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE entry_id IN (SELECT id FROM vault_entries WHERE "+cond+")", args...); err != nil {
+			return err
+		}
+	}
+	res, err := tx.Exec("DELETE FROM vault_entries WHERE "+cond, args...)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CanWrite reports whether userID may modify entry id.
+func (r *VaultRepository) CanWrite(userID, id int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM vault_entries WHERE id = ? AND "+entryWritable+")",
+		id,
+		userID,
+		userID,
+		userID,
+	).Scan(&ok)
+	return ok, err
+}
+
+// CanManage reports whether userID may delete, move or share entry id.
+func (r *VaultRepository) CanManage(userID, id int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM vault_entries WHERE id = ? AND "+entryManageable+")",
+		id,
+		userID,
+		userID,
+	).Scan(&ok)
+	return ok, err
+}
+
+// SetTags replaces the tags of entry entryID.
+func (r *VaultRepository) SetTags(entryID int64, tags []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM entry_tags WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO entry_tags (entry_id, tag) VALUES (?, ?)", entryID, tag); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListTags returns the tags of the given entries, sorted and keyed by entry
+// id.
+func (r *VaultRepository) ListTags(entryIDs []int64) (map[int64][]string, error) {
+	tags := map[int64][]string{}
+	if len(entryIDs) == 0 {
+		return tags, nil
+	}
+	placeholders, args := int64Placeholders(entryIDs)
+	rows, err := r.db.Query("SELECT entry_id, tag FROM entry_tags WHERE entry_id IN ("+placeholders+") ORDER BY entry_id, tag", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int64
+		var tag string
+		if err := rows.Scan(&entryID, &tag); err != nil {
+			return nil, err
+		}
+		tags[entryID] = append(tags[entryID], tag)
+	}
+	return tags, rows.Err()
+}
+
+// FolderPaths returns the paths of userID's folders from the top of the
+// tree, such as "Work/Servers", keyed by folder id.
+func (r *VaultRepository) FolderPaths(userID int64) (map[int64]string, error) {
+	rows, err := r.db.Query(
+		`WITH RECURSIVE paths (id, path) AS (
+			SELECT id, name FROM folders WHERE user_id = ? AND parent_id IS NULL
+			UNION ALL
+			SELECT f.id, p.path || '/' || f.name FROM folders f JOIN paths p ON f.parent_id = p.id
+		)
+		SELECT id, path FROM paths`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, err
+		}
+		paths[id] = path
+	}
+	return paths, rows.Err()
+}
+
+// SuggestTags returns the tags starting with prefix on entries userID can
+// read, most used first.
+func (r *VaultRepository) SuggestTags(userID int64, prefix string, limit int) ([]models.TagCount, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	rows, err := r.db.Query(
+		`SELECT tag, COUNT(*) FROM entry_tags
+		WHERE tag LIKE ? ESCAPE '\' AND entry_id IN (SELECT id FROM vault_entries WHERE `+entryReadable+`)
+		GROUP BY tag ORDER BY COUNT(*) DESC, tag LIMIT ?`,
+		pattern,
+		userID,
+		userID,
+		userID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// SetFavorite stars or unstars entry entryID for userID.
+func (r *VaultRepository) SetFavorite(userID, entryID int64, favorite bool, now time.Time) error {
+	if !favorite {
+		_, err := r.db.Exec("DELETE FROM entry_favorites WHERE user_id = ? AND entry_id = ?", userID, entryID)
+		return err
+	}
+	_, err := r.db.Exec(
+		"INSERT OR IGNORE INTO entry_favorites (user_id, entry_id, created_at) VALUES (?, ?, ?)",
+		userID,
+		entryID,
+		now.UTC().Format(time.RFC3339),
+	)
 	return err
 }
 
-func (r *VaultRepository) SearchByWebsite(userID int64, query string) ([]models.VaultEntry, error) {
+// Favorites reports which of the given entries userID has starred.
+func (r *VaultRepository) Favorites(userID int64, entryIDs []int64) (map[int64]bool, error) {
+	favorites := map[int64]bool{}
+	if len(entryIDs) == 0 {
+		return favorites, nil
+	}
+	placeholders, args := int64Placeholders(entryIDs)
 	rows, err := r.db.Query(
-		`SELECT id, user_id, title, username, password_enc, url, category, notes, created_at, updated_at, last_accessed_at 
-		FROM vault_entries 
-		WHERE user_id = ? AND (title LIKE ? OR url LIKE ? OR username LIKE ?)
-		ORDER BY id DESC`,
+		"SELECT entry_id FROM entry_favorites WHERE user_id = ? AND entry_id IN ("+placeholders+")",
+		append([]any{userID}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int64
+		if err := rows.Scan(&entryID); err != nil {
+			return nil, err
+		}
+		favorites[entryID] = true
+	}
+	return favorites, rows.Err()
+}
+
+// HasFolder reports whether folderID is one of userID's folders.
+func (r *VaultRepository) HasFolder(userID, folderID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM folders WHERE id = ? AND user_id = ?)", folderID, userID).Scan(&ok)
+	return ok, err
+}
+
+// CanWriteCollection reports whether userID may add or change entries in collectionID.
+func (r *VaultRepository) CanWriteCollection(userID, collectionID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM collections WHERE id = ? AND id IN ("+writableCollections+"))",
+		collectionID,
 		userID,
-		"%"+query+"%",
-		"%"+query+"%",
-		"%"+query+"%",
+	).Scan(&ok)
+	return ok, err
+}
+
+// SameOrganization reports whether collections a and b belong to the same
+// organization.
+func (r *VaultRepository) SameOrganization(a, b int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM collections ca JOIN collections cb ON cb.org_id = ca.org_id WHERE ca.id = ? AND cb.id = ?)",
+		a,
+		b,
+	).Scan(&ok)
+	return ok, err
+}
+
+// RotatePassword closes checkout checkoutID with status and, in the same
+// transaction, stores a new password for its entry and bumps its version,
+// recording revision like UpdateWithRevision. A lease is therefore never
+// closed without its rotation. It reports false, changing nothing, if the
+// checkout had already ended.
+func (r *VaultRepository) RotatePassword(checkoutID int64, status string, passwordEnc string, updatedAt time.Time, revision *models.EntryRevision, keep int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"UPDATE entry_checkouts SET status = ?, checked_in_at = ? WHERE id = ? AND entry_id = ? AND status = ?",
+		status,
+		updatedAt.UTC().Format(time.RFC3339),
+		checkoutID,
+		revision.EntryID,
+		models.CheckoutActive,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	res, err = tx.Exec(
+		"UPDATE vault_entries SET password_enc = ?, password_version = password_version + 1, updated_at = ? WHERE id = ?",
+		passwordEnc,
+		updatedAt.UTC().Format(time.RFC3339),
+		revision.EntryID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if err := requireAffected(res); err != nil {
+		return false, err
+	}
+	if err := addRevision(tx, revision, keep); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// addRevision numbers and stores revision, then prunes the entry's history
+// down to the newest keep revisions.
+func addRevision(tx *sql.Tx, revision *models.EntryRevision, keep int) error {
+	if _, err := tx.Exec(
+		`INSERT INTO entry_revisions (entry_id, revision, snapshot_enc, key_enc, changed_by, created_at)
+		VALUES (?, (SELECT COALESCE(MAX(revision), 0) + 1 FROM entry_revisions WHERE entry_id = ?), ?, ?, ?, ?)`,
+		revision.EntryID,
+		revision.EntryID,
+		revision.SnapshotEnc,
+		nullableString(revision.KeyEnc),
+		revision.ChangedBy,
+		revision.CreatedAt.UTC().Format(time.RFC3339),
+	); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		`DELETE FROM entry_revisions WHERE entry_id = ? AND id NOT IN (
+			SELECT id FROM entry_revisions WHERE entry_id = ? ORDER BY revision DESC LIMIT ?)`,
+		revision.EntryID,
+		revision.EntryID,
+		keep,
+	)
+	return err
+}
+
+const revisionColumns = "r.id, r.entry_id, r.revision, r.snapshot_enc, r.key_enc, r.changed_by, COALESCE(u.email, ''), r.created_at"
+
+// ListRevisions returns the stored revisions of an entry, newest first.
+func (r *VaultRepository) ListRevisions(entryID int64) ([]models.EntryRevision, error) {
+	rows, err := r.db.Query(
+		"SELECT "+revisionColumns+" FROM entry_revisions r LEFT JOIN users u ON u.id = r.changed_by WHERE r.entry_id = ? ORDER BY r.revision DESC",
+		entryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.EntryRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *VaultRepository) GetRevision(entryID int64, revision int) (*models.EntryRevision, error) {
+	return scanRevision(r.db.QueryRow(
+		"SELECT "+revisionColumns+" FROM entry_revisions r LEFT JOIN users u ON u.id = r.changed_by WHERE r.entry_id = ? AND r.revision = ?",
+		entryID,
+		revision,
+	))
+}
+
+func scanRevision(row scanner) (*models.EntryRevision, error) {
+	var revision models.EntryRevision
+	var keyEnc sql.NullString
+	var changedBy sql.NullInt64
+	var createdAt string
+	if err := row.Scan(
+		&revision.ID,
+		&revision.EntryID,
+		&revision.Revision,
+		&revision.SnapshotEnc,
+		&keyEnc,
+		&changedBy,
+		&revision.ChangedByEmail,
+		&createdAt,
+	); err != nil {
+		return nil, err
+	}
+	revision.KeyEnc = keyEnc.String
+	if changedBy.Valid {
+		revision.ChangedBy = &changedBy.Int64
+	}
+	revision.CreatedAt = parseTime(createdAt)
+	return &revision, nil
+}
+
+// GetForSystem loads an entry without an access check, for background jobs.
+func (r *VaultRepository) GetForSystem(id int64) (*models.VaultEntry, error) {
+	return scanVaultEntry(r.db.QueryRow("SELECT "+entryColumns+" FROM vault_entries WHERE id = ?", id))
+}
+
+// ListUnsealedNotes returns the note entries, trashed ones included, whose
+// body is still in the plain notes column.
+func (r *VaultRepository) ListUnsealedNotes() ([]models.VaultEntry, error) {
+	rows, err := r.db.Query(
+		"SELECT "+entryColumns+" FROM vault_entries WHERE type = ? AND notes != ''",
+		models.EntryTypeNote,
 	)
 	if err != nil {
 		return nil, err
@@ -115,12 +972,26 @@ func (r *VaultRepository) SearchByWebsite(userID int64, query string) ([]models.
 	return entries, rows.Err()
 }
 
+// SealNote replaces a note's plain body with dataEnc. Clearing the notes
+// column also drops the body from entry_search.
+func (r *VaultRepository) SealNote(id int64, dataEnc string) error {
+	res, err := r.db.Exec("UPDATE vault_entries SET data_enc = ?, notes = '' WHERE id = ?", dataEnc, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// TouchLastAccessed records that entry id was opened at accessedAt and
+// counts the access.
 func (r *VaultRepository) TouchLastAccessed(userID, id int64, accessedAt time.Time) error {
 	_, err := r.db.Exec(
-		"UPDATE vault_entries SET last_accessed_at = ? WHERE user_id = ? AND id = ?",
+		"UPDATE vault_entries SET last_accessed_at = ?, access_count = access_count + 1 WHERE id = ? AND "+entryReadable,
 		accessedAt.UTC().Format(time.RFC3339),
-		userID,
 		id,
+		userID,
+		userID,
+		userID,
 	)
 	return err
 }
@@ -129,24 +1000,53 @@ type scanner interface {
 	Scan(dest ...any) error
 }
 
+// extraColumns scans rows carrying columns after the ones a scan function
+// expects into extra.
+type extraColumns struct {
+	scanner
+	extra []any
+}
+
+func (s extraColumns) Scan(dest ...any) error {
+	return s.scanner.Scan(append(dest, s.extra...)...)
+}
+
 func scanVaultEntry(row scanner) (*models.VaultEntry, error) {
 	var entry models.VaultEntry
 	var createdAt string
 	var updatedAt string
+	var keyEnc sql.NullString
+	var dataEnc sql.NullString
+	var collectionID sql.NullInt64
+	var folderID sql.NullInt64
 	var lastAccessed sql.NullString
+	var deletedAt sql.NullString
+	var urlMatch sql.NullString
 
 	err := row.Scan(
 		&entry.ID,
 		&entry.UserID,
+		&entry.Type,
 		&entry.Title,
 		&entry.Username,
 		&entry.PasswordEnc,
+		&keyEnc,
+		&dataEnc,
 		&entry.URL,
 		&entry.Category,
 		&entry.Notes,
+		&entry.Sensitive,
+		&entry.RequiresApproval,
+		&entry.CheckoutRequired,
+		&entry.PasswordVersion,
+		&collectionID,
+		&folderID,
 		&createdAt,
 		&updatedAt,
 		&lastAccessed,
+		&deletedAt,
+		&entry.AccessCount,
+		&urlMatch,
 	)
 	if err != nil {
 		return nil, err
@@ -154,10 +1054,53 @@ func scanVaultEntry(row scanner) (*models.VaultEntry, error) {
 
 	entry.CreatedAt = parseTime(createdAt)
 	entry.UpdatedAt = parseTime(updatedAt)
+	entry.KeyEnc = keyEnc.String
+	entry.DataEnc = dataEnc.String
+	entry.URLMatch = urlMatch.String
+	if collectionID.Valid {
+		entry.CollectionID = &collectionID.Int64
+	}
+	if folderID.Valid {
+		entry.FolderID = &folderID.Int64
+	}
 	if lastAccessed.Valid {
 		t := parseTime(lastAccessed.String)
 		entry.LastAccessedAt = &t
 	}
+	if deletedAt.Valid {
+		t := parseTime(deletedAt.String)
+		entry.DeletedAt = &t
+	}
 
 	return &entry, nil
 }
+
+// nullableString stores "" as NULL.
+func nullableString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// int64Placeholders returns the placeholders and arguments for an IN list
+// of ids, which must not be empty.
+func int64Placeholders(ids []int64) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.Repeat("?, ", len(ids)-1) + "?", args
+}
+
+// requireAffected turns an update that matched no rows into sql.ErrNoRows.
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
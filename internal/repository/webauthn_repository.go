@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"vault/internal/models"
+)
+
+type WebAuthnRepository struct {
+	db *sql.DB
+}
+
+func NewWebAuthnRepository(db *sql.DB) *WebAuthnRepository {
+	return &WebAuthnRepository{db: db}
+}
+
+func (r *WebAuthnRepository) Create(cred models.WebAuthnCredential) (int64, error) {
+	res, err := r.db.Exec(
+		`INSERT INTO webauthn_credentials (user_id, name, credential_id, credential_json, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		cred.UserID,
+		cred.Name,
+		cred.CredentialID,
+		cred.Data,
+		cred.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *WebAuthnRepository) ListByUser(userID int64) ([]models.WebAuthnCredential, error) {
+	rows, err := r.db.Query(
+		"SELECT id, user_id, name, credential_id, credential_json, created_at, last_used_at FROM webauthn_credentials WHERE user_id = ? ORDER BY id",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []models.WebAuthnCredential{}
+	for rows.Next() {
+		cred, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, *cred)
+	}
+	return creds, rows.Err()
+}
+
+// UpdateAfterLogin stores the authenticator state (sign count) from an assertion.
+func (r *WebAuthnRepository) UpdateAfterLogin(credentialID, data string, usedAt time.Time) error {
+	_, err := r.db.Exec(
+		"UPDATE webauthn_credentials SET credential_json = ?, last_used_at = ? WHERE credential_id = ?",
+		data,
+		usedAt.UTC().Format(time.RFC3339),
+		credentialID,
+	)
+	return err
+}
+
+// Delete removes a credential and reports whether the user owned one with that id.
+func (r *WebAuthnRepository) Delete(userID, id int64) (bool, error) {
+	res, err := r.db.Exec("DELETE FROM webauthn_credentials WHERE user_id = ? AND id = ?", userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *WebAuthnRepository) DeleteAllByUser(userID int64) error {
+	_, err := r.db.Exec("DELETE FROM webauthn_credentials WHERE user_id = ?", userID)
+	return err
+}
+
+func (r *WebAuthnRepository) CountByUser(userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = ?", userID).Scan(&count)
+	return count, err
+}
+
+func scanWebAuthnCredential(row scanner) (*models.WebAuthnCredential, error) {
+	var cred models.WebAuthnCredential
+	var createdAt string
+	var lastUsed sql.NullString
+
+	err := row.Scan(
+		&cred.ID,
+		&cred.UserID,
+		&cred.Name,
+		&cred.CredentialID,
+		&cred.Data,
+		&createdAt,
+		&lastUsed,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cred.CreatedAt = parseTime(createdAt)
+	cred.LastUsedAt = parseNullableTime(lastUsed)
+	return &cred, nil
+}
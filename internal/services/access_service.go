@@ -0,0 +1,238 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const (
+	defaultAccessDuration  = time.Hour
+	maxAccessDuration      = 24 * time.Hour
+	maxJustificationLength = 1000
+	maxDecisionNoteLength  = 1000
+)
+
+// AccessService runs the request-to-view workflow for collection entries
+// that require approval. Members submit a justification, an owner or admin
+// of the organization approves or denies it, and VaultService.Get lets the
+// requester read the entry until the approved window ends. ExpireDue is run
+// by the scheduler to close finished windows.
+type AccessService struct {
+	repo     *repository.AccessRepository
+	vault    *VaultService
+	audit    *AuditService
+	notifier Notifier
+}
+
+func NewAccessService(repo *repository.AccessRepository, vault *VaultService, audit *AuditService, notifier Notifier) *AccessService {
+	return &AccessService{repo: repo, vault: vault, audit: audit, notifier: notifier}
+}
+
+// ForToken returns an AccessService enforcing API token tokenID's policies.
+func (s *AccessService) ForToken(tokenID int64) *AccessService {
+	scoped := *s
+	scoped.vault = s.vault.ForToken(tokenID)
+	return &scoped
+}
+
+func errAccessRequestNotFound() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "access request not found")
+}
+
+// Request asks for read access to entryID for duration, zero meaning one hour.
+func (s *AccessService) Request(userID, entryID int64, justification string, duration time.Duration) (*models.AccessRequest, error) {
+	justification = strings.TrimSpace(justification)
+	if justification == "" {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "justification required")
+	}
+	if len(justification) > maxJustificationLength {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "justification is too long")
+	}
+	if duration == 0 {
+		duration = defaultAccessDuration
+	}
+	if duration < time.Minute || duration > maxAccessDuration {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "duration must be between 1 minute and 24 hours")
+	}
+
+	entry, err := s.vault.requireReadable(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.RequiresApproval || entry.CollectionID == nil {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "entry does not require approval")
+	}
+	approver, err := s.repo.IsApprover(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if approver {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "organization owners and admins do not need approval")
+	}
+	open, err := s.repo.HasOpen(entryID, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "a request for this entry is already pending or approved")
+	}
+
+	id, err := s.repo.Create(entryID, userID, justification, int(duration/time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogEntryEvent(userID, entryID, "access_requested", fmt.Sprintf("request=%d duration=%dm", id, req.DurationMinutes))
+
+	approvers, err := s.repo.Approvers(entryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range approvers {
+		s.notify("access.requested", member.UserID, member.Email, req)
+	}
+	return req, nil
+}
+
+// ListMine returns the caller's own requests, newest first.
+func (s *AccessService) ListMine(userID int64) ([]models.AccessRequest, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// ListPending returns the requests waiting for approverID's decision.
+func (s *AccessService) ListPending(approverID int64) ([]models.AccessRequest, error) {
+	return s.repo.ListPending(approverID)
+}
+
+// Approve grants a pending request for the duration it asked for.
+func (s *AccessService) Approve(approverID, id int64, note string) (*models.AccessRequest, error) {
+	req, err := s.approverRequest(approverID, id, note)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserID == approverID {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrForbidden, "cannot approve your own request")
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	ok, err := s.repo.Approve(id, approverID, strings.TrimSpace(note), now, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "request is not pending")
+	}
+
+	req, err = s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogEntryEvent(approverID, req.EntryID, "access_approved",
+		fmt.Sprintf("request=%d requester=%d expires=%s", id, req.UserID, expiresAt.Format(time.RFC3339)))
+	s.notify("access.approved", req.UserID, req.UserEmail, req)
+	return req, nil
+}
+
+// Deny turns down a pending request or ends an approved one early.
+func (s *AccessService) Deny(approverID, id int64, note string) (*models.AccessRequest, error) {
+	req, err := s.approverRequest(approverID, id, note)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.Deny(id, approverID, strings.TrimSpace(note), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "request is not pending or approved")
+	}
+
+	event := "access_denied"
+	if req.Status == models.AccessApproved {
+		event = "access_revoked"
+	}
+	req, err = s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogEntryEvent(approverID, req.EntryID, event, fmt.Sprintf("request=%d requester=%d", id, req.UserID))
+	s.notify("access."+strings.TrimPrefix(event, "access_"), req.UserID, req.UserEmail, req)
+	return req, nil
+}
+
+// ExpireDue marks approved requests whose window has closed as expired.
+// VaultService.Get already stops honoring them at expires_at; this records
+// the end of access in the audit log.
+func (s *AccessService) ExpireDue() error {
+	now := time.Now()
+	due, err := s.repo.ListExpired(now)
+	if err != nil {
+		return err
+	}
+	for _, req := range due {
+		ok, err := s.repo.Expire(req.ID, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		req.Status = models.AccessExpired
+		s.audit.LogEntryEvent(req.UserID, req.EntryID, "access_expired", fmt.Sprintf("request=%d", req.ID))
+		s.notify("access.expired", req.UserID, req.UserEmail, &req)
+	}
+	return nil
+}
+
+// approverRequest loads a request approverID may decide. Requests outside
+// their organizations are reported as not found.
+func (s *AccessService) approverRequest(approverID, id int64, note string) (*models.AccessRequest, error) {
+	if len(note) > maxDecisionNoteLength {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "note is too long")
+	}
+	req, err := s.repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errAccessRequestNotFound()
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.IsApprover(approverID, req.EntryID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errAccessRequestNotFound()
+	}
+	return req, nil
+}
+
+func (s *AccessService) notify(event string, userID int64, email string, req *models.AccessRequest) {
+	s.notifier.Notify(Notification{
+		Event:  event,
+		UserID: userID,
+		Email:  email,
+		Data: map[string]any{
+			"requestId":     req.ID,
+			"entryId":       req.EntryID,
+			"entryTitle":    req.EntryTitle,
+			"requester":     req.UserEmail,
+			"justification": req.Justification,
+			"status":        req.Status,
+			"expiresAt":     req.ExpiresAt,
+		},
+		SentAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	errSelfAction   = errors.New("administrators cannot do this to their own account")
+)
+
+// AdminService implements user management for administrators. Every
+// action is written to the audit log with the acting admin.
+type AdminService struct {
+	users *repository.UserRepository
+	creds *repository.WebAuthnRepository
+	audit *AuditService
+}
+
+func NewAdminService(users *repository.UserRepository, creds *repository.WebAuthnRepository, audit *AuditService) *AdminService {
+	return &AdminService{users: users, creds: creds, audit: audit}
+}
+
+func (s *AdminService) ListUsers(adminID int64) ([]models.User, error) {
+	users, err := s.users.List()
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogAdminEvent(adminID, 0, "admin.list_users")
+	return users, nil
+}
+
+func (s *AdminService) DisableUser(adminID, userID int64) error {
+	if adminID == userID {
+		return errSelfAction
+	}
+	if err := s.requireUser(userID); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if err := s.users.SetDisabled(userID, &now); err != nil {
+		return err
+	}
+	s.audit.LogAdminEvent(adminID, userID, "admin.disable_user")
+	return nil
+}
+
+func (s *AdminService) EnableUser(adminID, userID int64) error {
+	if err := s.requireUser(userID); err != nil {
+		return err
+	}
+	if err := s.users.SetDisabled(userID, nil); err != nil {
+		return err
+	}
+	s.audit.LogAdminEvent(adminID, userID, "admin.enable_user")
+	return nil
+}
+
+func (s *AdminService) DeleteUser(adminID, userID int64) error {
+	if adminID == userID {
+		return errSelfAction
+	}
+	found, err := s.users.Delete(userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+	s.audit.LogAdminEvent(adminID, userID, "admin.delete_user")
+	return nil
+}
+
+// ForceLogout invalidates every JWT issued to the user so far, and every
+// API token created so far.
+func (s *AdminService) ForceLogout(adminID, userID int64) error {
+	if err := s.requireUser(userID); err != nil {
+		return err
+	}
+	if err := s.users.SetSessionsValidAfter(userID, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.audit.LogAdminEvent(adminID, userID, "admin.force_logout")
+	return nil
+}
+
+// ResetMFA removes the user's passkeys and the second-factor requirement,
+// for users who lost their authenticator.
+func (s *AdminService) ResetMFA(adminID, userID int64) error {
+	if err := s.requireUser(userID); err != nil {
+		return err
+	}
+	if err := s.creds.DeleteAllByUser(userID); err != nil {
+		return err
+	}
+	if err := s.users.SetWebAuthnMFA(userID, false); err != nil {
+		return err
+	}
+	s.audit.LogAdminEvent(adminID, userID, "admin.reset_mfa")
+	return nil
+}
+
+// SetAuthBackend pins a user to credential backend "local" or "ldap", or
+// with "" lets them follow the configured default again.
+func (s *AdminService) SetAuthBackend(adminID, userID int64, backend string) error {
+	switch backend {
+	case "", AuthBackendLocal, AuthBackendLDAP:
+	default:
+		return errors.New("backend must be local, ldap or empty")
+	}
+	if err := s.requireUser(userID); err != nil {
+		return err
+	}
+	if err := s.users.SetAuthBackend(userID, backend); err != nil {
+		return err
+	}
+	s.audit.LogAdminEvent(adminID, userID, "admin.set_auth_backend")
+	return nil
+}
+
+func (s *AdminService) UserStats(adminID, userID int64) (*models.UserStats, error) {
+	if err := s.requireUser(userID); err != nil {
+		return nil, err
+	}
+	stats, err := s.users.Stats(userID)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogAdminEvent(adminID, userID, "admin.view_stats")
+	return stats, nil
+}
+
+// Bootstrap creates an administrator with a local password, or promotes an
+// existing user. It backs the create-admin command and is not exposed over HTTP.
+func (s *AdminService) Bootstrap(email, password string) (int64, error) {
+	if email == "" {
+		return 0, errors.New("email required")
+	}
+
+	user, err := s.users.GetByEmail(email)
+	switch {
+	case err == nil:
+		if err := s.users.SetRole(user.ID, models.RoleAdmin); err != nil {
+			return 0, err
+		}
+		s.audit.LogAdminEvent(0, user.ID, "admin.bootstrap_promote")
+		return user.ID, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	if password == "" {
+		return 0, errors.New("password required to create a new admin")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+	id, err := s.users.Create(email, string(hash))
+	if err != nil {
+		return 0, err
+	}
+	// Pin to local so the admin can log in even when a directory is the default
+	if err := s.users.SetAuthBackend(id, AuthBackendLocal); err != nil {
+		return 0, err
+	}
+	if err := s.users.SetRole(id, models.RoleAdmin); err != nil {
+		return 0, err
+	}
+	s.audit.LogAdminEvent(0, id, "admin.bootstrap_create")
+	return id, nil
+}
+
+func (s *AdminService) requireUser(userID int64) error {
+	if _, err := s.users.GetByID(userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
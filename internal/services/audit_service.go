@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,9 +12,13 @@ import (
 
 // AuditEvent represents an audit log event
 type AuditEvent struct {
-	UserID  int64
-	EntryID int64
-	Action  string
+	UserID       int64
+	EntryID      int64
+	TokenID      int64
+	TargetUserID int64
+	OrgID        int64
+	Action       string
+	Detail       string
 }
 
 // AuditService handles background audit logging using goroutines and channels
@@ -38,8 +43,37 @@ func NewAuditService(repo *repository.VaultRepository) *AuditService {
 
 // LogEvent sends an audit event to the channel (non-blocking)
 func (s *AuditService) LogEvent(userID, entryID int64, action string) {
+	s.enqueue(AuditEvent{UserID: userID, EntryID: entryID, Action: action})
+}
+
+// LogEntryEvent records an action on an entry with extra detail, such as the other party of a share
+func (s *AuditService) LogEntryEvent(userID, entryID int64, action, detail string) {
+	s.enqueue(AuditEvent{UserID: userID, EntryID: entryID, Action: action, Detail: detail})
+}
+
+// LogUserEvent records an account-level action that involves no single entry
+func (s *AuditService) LogUserEvent(userID int64, action, detail string) {
+	s.enqueue(AuditEvent{UserID: userID, Action: action, Detail: detail})
+}
+
+// LogTokenEvent records an action performed with or on an API token
+func (s *AuditService) LogTokenEvent(userID, tokenID int64, action, detail string) {
+	s.enqueue(AuditEvent{UserID: userID, TokenID: tokenID, Action: action, Detail: detail})
+}
+
+// LogAdminEvent records an administrator acting on another user's account
+func (s *AuditService) LogAdminEvent(adminID, targetUserID int64, action string) {
+	s.enqueue(AuditEvent{UserID: adminID, TargetUserID: targetUserID, Action: action})
+}
+
+// LogOrgEvent records a change to an organization, its members, teams or collections
+func (s *AuditService) LogOrgEvent(userID, orgID int64, action, detail string) {
+	s.enqueue(AuditEvent{UserID: userID, OrgID: orgID, Action: action, Detail: detail})
+}
+
+func (s *AuditService) enqueue(event AuditEvent) {
 	select {
-	case s.eventChan <- AuditEvent{UserID: userID, EntryID: entryID, Action: action}:
+	case s.eventChan <- event:
 	case <-s.done:
 		// Service is shutting down
 	default:
@@ -54,6 +88,31 @@ func (s *AuditService) auditWorker() {
 		select {
 		case event := <-s.eventChan:
 			// Process audit event (would save to DB in production)
+			if event.OrgID != 0 {
+				fmt.Printf("[AUDIT] User %d in org %d: %s %s at %s\n",
+					event.UserID, event.OrgID, event.Action, event.Detail, time.Now().Format(time.RFC3339))
+				continue
+			}
+			if strings.HasPrefix(event.Action, "admin.") {
+				fmt.Printf("[AUDIT] Admin %d acted on user %d: %s at %s\n",
+					event.UserID, event.TargetUserID, event.Action, time.Now().Format(time.RFC3339))
+				continue
+			}
+			if event.TokenID != 0 {
+				fmt.Printf("[AUDIT] User %d via token %d: %s %s at %s\n",
+					event.UserID, event.TokenID, event.Action, event.Detail, time.Now().Format(time.RFC3339))
+				continue
+			}
+			if event.EntryID == 0 {
+				fmt.Printf("[AUDIT] User %d: %s %s at %s\n",
+					event.UserID, event.Action, event.Detail, time.Now().Format(time.RFC3339))
+				continue
+			}
+			if event.Detail != "" {
+				fmt.Printf("[AUDIT] User %d accessed entry %d: %s %s at %s\n",
+					event.UserID, event.EntryID, event.Action, event.Detail, time.Now().Format(time.RFC3339))
+				continue
+			}
 			fmt.Printf("[AUDIT] User %d accessed entry %d: %s at %s\n",
 				event.UserID, event.EntryID, event.Action, time.Now().Format(time.RFC3339))
 		case <-s.done:
@@ -2,6 +2,9 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -11,20 +14,93 @@ import (
 	"vault/internal/repository"
 )
 
+const mfaChallengeTTL = 5 * time.Minute
+
+var errAccountDisabled = errors.New("account disabled")
+
+// MFARequiredError is returned by Login when the password was accepted but
+// the user must still complete a passkey assertion using Token.
+type MFARequiredError struct {
+	Token string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "second factor required"
+}
+
+type mfaChallenge struct {
+	userID    int64
+	scopes    []string
+	expiresAt time.Time
+}
+
 type AuthService struct {
-	users     *repository.UserRepository
-	jwtSecret string
-	tokenTTL  time.Duration
+	users      *repository.UserRepository
+	jwtSecret  string
+	tokenTTL   time.Duration
+	ssoDomains []string
+
+	authenticators map[string]Authenticator
+	defaultBackend string
+
+	mu         sync.Mutex
+	mfaPending map[string]mfaChallenge
 }
 
 func NewAuthService(users *repository.UserRepository, jwtSecret string, tokenTTL time.Duration) *AuthService {
-	return &AuthService{users: users, jwtSecret: jwtSecret, tokenTTL: tokenTTL}
+	return &AuthService{
+		users:     users,
+		jwtSecret: jwtSecret,
+		tokenTTL:  tokenTTL,
+		authenticators: map[string]Authenticator{
+			AuthBackendLocal: &localAuthenticator{users: users},
+		},
+		defaultBackend: AuthBackendLocal,
+		mfaPending:     map[string]mfaChallenge{},
+	}
+}
+
+// AddBackend makes an additional credential backend available to Login.
+func (s *AuthService) AddBackend(name string, authenticator Authenticator) {
+	s.authenticators[name] = authenticator
+}
+
+// HasBackend reports whether credential backend name is configured.
+func (s *AuthService) HasBackend(name string) bool {
+	_, ok := s.authenticators[name]
+	return ok
+}
+
+// SetDefaultBackend selects the backend for users not pinned to one.
+func (s *AuthService) SetDefaultBackend(name string) error {
+	if _, ok := s.authenticators[name]; !ok {
+		return fmt.Errorf("auth backend %q is not configured", name)
+	}
+	s.defaultBackend = name
+	return nil
+}
+
+// ReserveDomainsForSSO blocks password registration for the given email
+// domains so accounts there can only be created through single sign-on.
+func (s *AuthService) ReserveDomainsForSSO(domains []string) {
+	s.ssoDomains = domains
 }
 
 func (s *AuthService) Register(email, password string) (int64, error) {
 	if email == "" || password == "" {
 		return 0, errors.New("email and password required")
 	}
+	if s.defaultBackend != AuthBackendLocal {
+		return 0, errors.New("accounts are managed by the " + s.defaultBackend + " directory")
+	}
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain := strings.ToLower(email[at+1:])
+		for _, reserved := range s.ssoDomains {
+			if strings.EqualFold(domain, reserved) {
+				return 0, errors.New("accounts for this domain must use single sign-on")
+			}
+		}
+	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -34,31 +110,183 @@ func (s *AuthService) Register(email, password string) (int64, error) {
 	return s.users.Create(email, string(hash))
 }
 
-func (s *AuthService) Login(email, password string) (string, *models.User, error) {
+// Login verifies credentials against the user's backend and mints a JWT.
+// scopes narrows the token, for example to entries:read for read-only
+// tooling; empty means AllScopes.
+func (s *AuthService) Login(email, password string, scopes []string) (string, *models.User, error) {
 	if email == "" || password == "" {
 		return "", nil, errors.New("email and password required")
 	}
+	if len(scopes) == 0 {
+		scopes = AllScopes
+	}
+	if err := validateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+
+	backend := s.defaultBackend
+	if existing, err := s.users.GetByEmail(email); err == nil && existing.AuthBackend != "" {
+		backend = existing.AuthBackend
+	}
+	authenticator, ok := s.authenticators[backend]
+	if !ok {
+		return "", nil, errInvalidCredentials
+	}
+
+	user, err := authenticator.Authenticate(email, password)
+	if err != nil {
+		return "", nil, err
+	}
+	if user.DisabledAt != nil {
+		return "", nil, errAccountDisabled
+	}
+
+	if user.WebAuthnMFA {
+		token, err := s.newMFAChallenge(user.ID, scopes)
+		if err != nil {
+			return "", nil, err
+		}
+		return "", nil, &MFARequiredError{Token: token}
+	}
+
+	signed, err := s.IssueToken(user, scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signed, user, nil
+}
+
+func (s *AuthService) newMFAChallenge(userID int64, scopes []string) (string, error) {
+	token, err := randomURLToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, c := range s.mfaPending {
+		if now.After(c.expiresAt) {
+			delete(s.mfaPending, key)
+		}
+	}
+	s.mfaPending[token] = mfaChallenge{userID: userID, scopes: scopes, expiresAt: now.Add(mfaChallengeTTL)}
+	return token, nil
+}
+
+// MFAChallengeUser returns the user a pending second-factor challenge
+// belongs to without consuming it.
+func (s *AuthService) MFAChallengeUser(token string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.mfaPending[token]
+	if !ok || time.Now().After(c.expiresAt) {
+		return 0, errors.New("invalid or expired mfa token")
+	}
+	return c.userID, nil
+}
+
+// CompleteMFA consumes a challenge once the second factor has been verified
+// for userID and mints the JWT the password login asked for.
+func (s *AuthService) CompleteMFA(token string, userID int64) (string, *models.User, error) {
+	s.mu.Lock()
+	c, ok := s.mfaPending[token]
+	delete(s.mfaPending, token)
+	s.mu.Unlock()
+	if !ok || time.Now().After(c.expiresAt) || c.userID != userID {
+		return "", nil, errors.New("invalid or expired mfa token")
+	}
+
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	signed, err := s.IssueToken(user, c.scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, user, nil
+}
 
-	user, err := s.users.GetByEmail(email)
+// Reauthenticate re-checks the password of a signed-in user and mints a
+// token with a fresh auth_time, as required to reveal sensitive entries.
+// Users with a passkey second factor get an MFARequiredError instead.
+func (s *AuthService) Reauthenticate(userID int64, password string, scopes []string) (string, error) {
+	if password == "" {
+		return "", errors.New("password required")
+	}
+
+	user, err := s.users.GetByID(userID)
 	if err != nil {
-		return "", nil, errors.New("invalid credentials")
+		return "", errInvalidCredentials
+	}
+
+	backend := s.defaultBackend
+	if user.AuthBackend != "" {
+		backend = user.AuthBackend
+	}
+	authenticator, ok := s.authenticators[backend]
+	if !ok {
+		return "", errInvalidCredentials
+	}
+
+	verified, err := authenticator.Authenticate(user.Email, password)
+	if err != nil || verified.ID != user.ID {
+		return "", errInvalidCredentials
+	}
+
+	if user.WebAuthnMFA {
+		token, err := s.newMFAChallenge(user.ID, scopes)
+		if err != nil {
+			return "", err
+		}
+		return "", &MFARequiredError{Token: token}
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", nil, errors.New("invalid credentials")
+	return s.IssueToken(user, scopes)
+}
+
+// IssueToken mints a JWT for a user who has just been authenticated,
+// whether by password, passkey or an external identity provider. The
+// auth_time claim records that moment for step-up checks.
+func (s *AuthService) IssueToken(user *models.User, scopes []string) (string, error) {
+	if user.DisabledAt != nil {
+		return "", errAccountDisabled
 	}
 
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(s.tokenTTL).Unix(),
-		"iat": time.Now().Unix(),
+		"sub":       user.ID,
+		"scope":     strings.Join(scopes, " "),
+		"auth_time": now.Unix(),
+		"exp":       now.Add(s.tokenTTL).Unix(),
+		"iat":       now.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(s.jwtSecret))
+	return token.SignedString([]byte(s.jwtSecret))
+}
+
+// ValidateSession rejects requests from disabled users and from tokens
+// issued before an administrator forced a logout. For API tokens issuedAt
+// is when the token was created.
+func (s *AuthService) ValidateSession(userID int64, issuedAt time.Time) error {
+	user, err := s.users.GetByID(userID)
 	if err != nil {
-		return "", nil, err
+		return errors.New("unknown user")
+	}
+	if user.DisabledAt != nil {
+		return errAccountDisabled
+	}
+	if !issuedAt.IsZero() && user.SessionsValidAfter != nil && !issuedAt.After(*user.SessionsValidAfter) {
+		return errors.New("session revoked")
 	}
+	return nil
+}
 
-	return signed, user, nil
+// IsAdmin reports whether the user currently holds the admin role.
+func (s *AuthService) IsAdmin(userID int64) bool {
+	user, err := s.users.GetByID(userID)
+	return err == nil && user.Role == models.RoleAdmin && user.DisabledAt == nil
 }
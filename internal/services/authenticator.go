@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+// Credential backends a user can be pinned to via users.auth_backend.
+const (
+	AuthBackendLocal = "local"
+	AuthBackendLDAP  = "ldap"
+)
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator verifies a login against one credential backend and
+// returns the local user it maps to.
+type Authenticator interface {
+	Authenticate(login, password string) (*models.User, error)
+}
+
+// localAuthenticator checks bcrypt hashes stored in the users table.
+type localAuthenticator struct {
+	users *repository.UserRepository
+}
+
+func (a *localAuthenticator) Authenticate(login, password string) (*models.User, error) {
+	user, err := a.users.GetByEmail(login)
+	if err != nil {
+		return nil, errInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, errInvalidCredentials
+	}
+	return user, nil
+}
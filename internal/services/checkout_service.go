@@ -0,0 +1,197 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const (
+	defaultCheckoutLease = time.Hour
+	maxCheckoutLease     = 24 * time.Hour
+)
+
+// CheckoutResult is the lease together with the entry it unlocked.
+type CheckoutResult struct {
+	Checkout *models.Checkout   `json:"checkout"`
+	Entry    *models.VaultEntry `json:"entry"`
+}
+
+// CheckoutService gives one user at a time a time-boxed lease on an entry
+// marked checkoutRequired, e.g. a shared admin account. When the lease is
+// returned or expires the password is regenerated, so the previous holder's
+// copy stops working. ExpireDue is run by the scheduler.
+type CheckoutService struct {
+	repo     *repository.CheckoutRepository
+	vault    *VaultService
+	audit    *AuditService
+	notifier Notifier
+}
+
+func NewCheckoutService(repo *repository.CheckoutRepository, vault *VaultService, audit *AuditService, notifier Notifier) *CheckoutService {
+	return &CheckoutService{repo: repo, vault: vault, audit: audit, notifier: notifier}
+}
+
+// ForToken returns a CheckoutService enforcing API token tokenID's policies.
+func (s *CheckoutService) ForToken(tokenID int64) *CheckoutService {
+	scoped := *s
+	scoped.vault = s.vault.ForToken(tokenID)
+	return &scoped
+}
+
+// Status returns the entry's active checkout, or nil when it is available.
+func (s *CheckoutService) Status(userID, entryID int64) (*models.Checkout, error) {
+	if _, err := s.checkoutEntry(userID, entryID); err != nil {
+		return nil, err
+	}
+	checkout, err := s.repo.Active(entryID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !checkout.ExpiresAt.After(time.Now())) {
+		return nil, nil
+	}
+	return checkout, err
+}
+
+// CheckOut leases entryID to userID for lease, zero meaning one hour, and
+// returns the entry with its password. The same approval and step-up checks
+// as VaultService.Get apply.
+func (s *CheckoutService) CheckOut(userID, entryID int64, lease time.Duration, authTime time.Time) (*CheckoutResult, error) {
+	if lease == 0 {
+		lease = defaultCheckoutLease
+	}
+	if lease < time.Minute || lease > maxCheckoutLease {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "lease must be between 1 minute and 24 hours")
+	}
+
+	entry, err := s.checkoutEntry(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.vault.requireRevealable(userID, entry, authTime); err != nil {
+		return nil, err
+	}
+
+	// A lapsed lease the scheduler has not swept yet is ended here, so its
+	// rotation cannot happen after the new holder has read the password
+	if active, err := s.repo.Active(entryID); err == nil && !active.ExpiresAt.After(time.Now()) {
+		if err := s.end(active, models.CheckoutExpired); err != nil {
+			return nil, err
+		}
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	id, ok, err := s.repo.Create(entryID, userID, now, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		active, err := s.repo.Active(entryID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrConflict,
+			fmt.Sprintf("entry is checked out by %s until %s", active.UserEmail, active.ExpiresAt.Format(time.RFC3339)))
+	}
+	checkout, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogEntryEvent(userID, entryID, "checked_out", fmt.Sprintf("checkout=%d expires=%s", id, checkout.ExpiresAt.Format(time.RFC3339)))
+
+	// The entry was loaded before the lapsed lease's rotation, so reload it
+	entry, err = s.vault.requireReadable(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err = s.vault.reveal(userID, entry)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckoutResult{Checkout: checkout, Entry: entry}, nil
+}
+
+// CheckIn returns userID's checkout of entryID and rotates the password.
+func (s *CheckoutService) CheckIn(userID, entryID int64) (*models.Checkout, error) {
+	if _, err := s.checkoutEntry(userID, entryID); err != nil {
+		return nil, err
+	}
+	active, err := s.repo.Active(entryID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && active.UserID != userID) {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "entry is not checked out by you")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.end(active, models.CheckoutReturned); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(active.ID)
+}
+
+// ExpireDue ends checkouts whose lease has run out and rotates their
+// passwords. A checkout that fails stays active and is retried on the next
+// run without holding up the others.
+func (s *CheckoutService) ExpireDue() error {
+	due, err := s.repo.ListExpired(time.Now())
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range due {
+		if err := s.end(&due[i], models.CheckoutExpired); err != nil {
+			errs = append(errs, fmt.Errorf("checkout %d: %w", due[i].ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// end closes checkout with status and rotates the entry's password in one
+// step, so a failed rotation leaves the lease active to be retried. A
+// checkout ended concurrently is left to whoever ended it.
+func (s *CheckoutService) end(checkout *models.Checkout, status string) error {
+	version, ok, err := s.vault.rotatePassword(checkout, status)
+	if err != nil || !ok {
+		return err
+	}
+
+	action := "checked_in"
+	if status == models.CheckoutExpired {
+		action = "checkout_expired"
+	}
+	s.audit.LogEntryEvent(checkout.UserID, checkout.EntryID, action, fmt.Sprintf("checkout=%d", checkout.ID))
+	s.audit.LogEntryEvent(checkout.UserID, checkout.EntryID, "password_rotated", fmt.Sprintf("checkout=%d version=%d", checkout.ID, version))
+
+	if status == models.CheckoutExpired {
+		s.notifier.Notify(Notification{
+			Event:  "checkout.expired",
+			UserID: checkout.UserID,
+			Email:  checkout.UserEmail,
+			Data: map[string]any{
+				"checkoutId": checkout.ID,
+				"entryId":    checkout.EntryID,
+				"expiresAt":  checkout.ExpiresAt,
+			},
+			SentAt: time.Now().UTC(),
+		})
+	}
+	return nil
+}
+
+// checkoutEntry loads an entry userID can see that requires checkout.
+func (s *CheckoutService) checkoutEntry(userID, entryID int64) (*models.VaultEntry, error) {
+	entry, err := s.vault.requireReadable(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.CheckoutRequired {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "entry does not require checkout")
+	}
+	return entry, nil
+}
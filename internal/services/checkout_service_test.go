@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+func TestFailedRotationKeepsCheckoutActive(t *testing.T) {
+	v := newVaultTest(t)
+	checkouts := NewCheckoutService(repository.NewCheckoutRepository(v.db), v.vault, newTestAudit(t, v.db), LogNotifier{})
+	owner := v.register(t, "owner@example.com")
+	id, err := v.vault.Create(owner, models.VaultEntry{Title: "Admin", Password: "initial", CheckoutRequired: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := v.vault.repo.GetForSystem(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkouts.CheckOut(owner, id, time.Minute, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// Break the stored password so the rotation cannot read the entry
+	var stored string
+	if err := v.db.QueryRow("SELECT password_enc FROM vault_entries WHERE id = ?", id).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.db.Exec("UPDATE vault_entries SET password_enc = 'garbage' WHERE id = ?", id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkouts.CheckIn(owner, id); err == nil {
+		t.Fatal("check-in succeeded without rotating")
+	}
+	active, err := checkouts.Status(owner, id)
+	if err != nil || active == nil {
+		t.Fatalf("lease closed by a failed rotation: %v, %v", active, err)
+	}
+
+	// Once the entry can be rotated, checking in again does so
+	if _, err := v.db.Exec("UPDATE vault_entries SET password_enc = ? WHERE id = ?", stored, id); err != nil {
+		t.Fatal(err)
+	}
+	checkout, err := checkouts.CheckIn(owner, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkout.Status != models.CheckoutReturned {
+		t.Fatalf("checkout status %q", checkout.Status)
+	}
+	entry, err := v.vault.repo.GetForSystem(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.PasswordVersion != before.PasswordVersion+1 {
+		t.Fatalf("password version %d, want %d", entry.PasswordVersion, before.PasswordVersion+1)
+	}
+	if active, err := checkouts.Status(owner, id); err != nil || active != nil {
+		t.Fatalf("lease still active after check-in: %v, %v", active, err)
+	}
+}
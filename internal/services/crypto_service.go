@@ -4,12 +4,16 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"io"
+
+	"golang.org/x/crypto/hkdf"
 )
 
 type CryptoService struct {
+	key []byte
 	gcm cipher.AEAD
 }
 
@@ -21,7 +25,12 @@ func NewCryptoService(base64Key string) (*CryptoService, error) {
 	if len(key) != 32 {
 		return nil, errors.New("VAULT_ENC_KEY must be 32 bytes")
 	}
+	return newCryptoService(key)
+}
 
+// newCryptoService builds an AES-256-GCM cipher from a raw 32-byte key, such
+// as a per-entry data key.
+func newCryptoService(key []byte) (*CryptoService, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -32,7 +41,26 @@ func NewCryptoService(base64Key string) (*CryptoService, error) {
 		return nil, err
 	}
 
-	return &CryptoService{gcm: gcm}, nil
+	return &CryptoService{key: key, gcm: gcm}, nil
+}
+
+// Derive returns a cipher whose key is derived from this one with HKDF;
+// different info strings give independent keys.
+func (c *CryptoService) Derive(info string) (*CryptoService, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, c.key, nil, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	return newCryptoService(key)
+}
+
+// newDataKey returns a random 32-byte key for encrypting a single entry.
+func newDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
 }
 
 func (c *CryptoService) Encrypt(plain string) (string, error) {
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+)
+
+const (
+	maxCustomFields     = 50
+	maxFieldNameLength  = 100
+	maxFieldValueLength = 5000
+)
+
+// Built-in fields a linked field can name; they take precedence over custom
+// fields of the same name.
+const (
+	linkedFieldUsername = "username"
+	linkedFieldPassword = "password"
+	linkedFieldURL      = "url"
+	linkedFieldTitle    = "title"
+	linkedFieldNotes    = "notes"
+)
+
+// validateFields checks custom fields and normalizes boolean values. Linked
+// targets are checked separately by checkLinks.
+func validateFields(fields []models.CustomField) error {
+	if len(fields) > maxCustomFields {
+		return errInvalidEntry(fmt.Sprintf("at most %d custom fields", maxCustomFields))
+	}
+	seen := map[string]bool{}
+	for i := range fields {
+		field := &fields[i]
+		field.Name = strings.TrimSpace(field.Name)
+		if field.Name == "" || len(field.Name) > maxFieldNameLength {
+			return errInvalidEntry("field names must be 1-100 characters")
+		}
+		if seen[strings.ToLower(field.Name)] {
+			return errInvalidEntry("duplicate field " + strconv.Quote(field.Name))
+		}
+		seen[strings.ToLower(field.Name)] = true
+		if len(field.Value) > maxFieldValueLength {
+			return errInvalidEntry("field " + strconv.Quote(field.Name) + " is too long")
+		}
+		if field.Type != models.FieldLinked && (field.LinkedEntryID != nil || field.LinkedField != "") {
+			return errInvalidEntry("only linked fields reference another entry")
+		}
+
+		switch field.Type {
+		case models.FieldText, models.FieldHidden:
+		case models.FieldBoolean:
+			value, err := strconv.ParseBool(field.Value)
+			if err != nil {
+				return errInvalidEntry("field " + strconv.Quote(field.Name) + " must be true or false")
+			}
+			field.Value = strconv.FormatBool(value)
+		case models.FieldURL:
+			if u, err := url.Parse(field.Value); err != nil || u.Scheme == "" {
+				return errInvalidEntry("field " + strconv.Quote(field.Name) + " must be an absolute URL")
+			}
+		case models.FieldLinked:
+			if field.LinkedEntryID == nil || field.LinkedField == "" || len(field.LinkedField) > maxFieldNameLength {
+				return errInvalidEntry("linked field " + strconv.Quote(field.Name) + " needs linkedEntryId and linkedField")
+			}
+			if field.Value != "" {
+				return errInvalidEntry("linked field " + strconv.Quote(field.Name) + " cannot have a value")
+			}
+		default:
+			return errInvalidEntry("unknown field type " + strconv.Quote(field.Type))
+		}
+	}
+	return nil
+}
+
+// checkLinks requires every linked field to point at another entry userID
+// can read.
+func (s *VaultService) checkLinks(userID, entryID int64, fields []models.CustomField) error {
+	for _, field := range fields {
+		if field.Type != models.FieldLinked {
+			continue
+		}
+		if *field.LinkedEntryID == entryID {
+			return errInvalidEntry("a field cannot link to its own entry")
+		}
+		if _, err := s.requireReadable(userID, *field.LinkedEntryID); err != nil {
+			return errInvalidEntry("linked entry for " + strconv.Quote(field.Name) + " not found")
+		}
+	}
+	return nil
+}
+
+// sealFields returns a copy of fields with hidden values encrypted.
+func sealFields(cipher *CryptoService, fields []models.CustomField) ([]models.CustomField, error) {
+	sealed := make([]models.CustomField, len(fields))
+	for i, field := range fields {
+		if field.Type == models.FieldHidden && field.Value != "" {
+			enc, err := cipher.Encrypt(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			field.Value = enc
+		}
+		sealed[i] = field
+	}
+	return sealed, nil
+}
+
+// maskFields loads the custom fields of entries without hidden values, for
+// lists and search results.
+func (s *VaultService) maskFields(entries []models.VaultEntry) error {
+	ids := make([]int64, len(entries))
+	for i := range entries {
+		ids[i] = entries[i].ID
+	}
+	fields, err := s.repo.ListFields(ids)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Fields = fields[entries[i].ID]
+		for j := range entries[i].Fields {
+			if entries[i].Fields[j].Type == models.FieldHidden {
+				entries[i].Fields[j].Value = ""
+			}
+		}
+	}
+	return nil
+}
+
+// openFields loads entry's custom fields and decrypts hidden values.
+func (s *VaultService) openFields(cipher *CryptoService, entry *models.VaultEntry) error {
+	fields, err := s.repo.ListFields([]int64{entry.ID})
+	if err != nil {
+		return err
+	}
+	entry.Fields = fields[entry.ID]
+	for i := range entry.Fields {
+		if entry.Fields[i].Type == models.FieldHidden && entry.Fields[i].Value != "" {
+			if entry.Fields[i].Value, err = cipher.Decrypt(entry.Fields[i].Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveLinks fills in linked fields from their target entries. A target
+// the caller cannot read right now, e.g. one needing approval or a recent
+// re-authentication, leaves the field without a value.
+func (s *VaultService) resolveLinks(userID int64, entry *models.VaultEntry, authTime time.Time) error {
+	for i := range entry.Fields {
+		field := &entry.Fields[i]
+		if field.Type != models.FieldLinked {
+			continue
+		}
+		value, err := s.linkedValue(userID, entry.ID, *field.LinkedEntryID, field.LinkedField, authTime)
+		var vaultErr *vaulterrors.VaultError
+		if errors.As(err, &vaultErr) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		field.Value = value
+	}
+	return nil
+}
+
+// linkedValue reads field name of entry targetID for userID. Secrets go
+// through the same checks as VaultService.Get.
+func (s *VaultService) linkedValue(userID, sourceID, targetID int64, name string, authTime time.Time) (string, error) {
+	target, err := s.requireReadable(userID, targetID)
+	if err != nil {
+		return "", err
+	}
+	switch name {
+	case linkedFieldUsername:
+		return target.Username, nil
+	case linkedFieldURL:
+		return target.URL, nil
+	case linkedFieldTitle:
+		return target.Title, nil
+	case linkedFieldNotes:
+		// A secure note's body is sealed and revealed like a password
+		if target.Type != models.EntryTypeNote {
+			return target.Notes, nil
+		}
+	}
+
+	secret := target.PasswordEnc
+	if name != linkedFieldPassword && name != linkedFieldNotes {
+		field, err := s.targetField(targetID, name)
+		if err != nil {
+			return "", err
+		}
+		if field.Type != models.FieldHidden || field.Value == "" {
+			return field.Value, nil
+		}
+		secret = field.Value
+	}
+
+	if err := s.requireRevealable(userID, target, authTime); err != nil {
+		return "", err
+	}
+	if err := s.requireCheckout(userID, target); err != nil {
+		return "", err
+	}
+	cipher, err := s.entryCipher(userID, target)
+	if err != nil {
+		return "", err
+	}
+	var value string
+	if name == linkedFieldNotes {
+		if err := openEntryData(cipher, target); err != nil {
+			return "", err
+		}
+		if target.Note != nil {
+			value = target.Note.Text
+		}
+	} else if value, err = cipher.Decrypt(secret); err != nil {
+		return "", err
+	}
+	s.audit.LogEntryEvent(userID, targetID, "accessed_via_link", fmt.Sprintf("entry=%d field=%s", sourceID, name))
+	return value, nil
+}
+
+// targetField returns custom field name of entry targetID as stored.
+func (s *VaultService) targetField(targetID int64, name string) (*models.CustomField, error) {
+	fields, err := s.repo.ListFields([]int64{targetID})
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields[targetID] {
+		if field.Name != name {
+			continue
+		}
+		if field.Type == models.FieldLinked {
+			return nil, errInvalidEntry("linked fields cannot be chained")
+		}
+		return &field, nil
+	}
+	return nil, errInvalidEntry("linked field not found")
+}
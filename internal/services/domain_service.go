@@ -0,0 +1,111 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+// DomainService finds the entries to autofill on a web page and manages
+// the groups of domains each user treats as one site.
+type DomainService struct {
+	repo  *repository.DomainRepository
+	vault *VaultService
+	audit *AuditService
+}
+
+func NewDomainService(repo *repository.DomainRepository, vault *VaultService, audit *AuditService) *DomainService {
+	return &DomainService{repo: repo, vault: vault, audit: audit}
+}
+
+// ForToken returns a DomainService enforcing API token tokenID's policies
+// on the entries it matches.
+func (s *DomainService) ForToken(tokenID int64) *DomainService {
+	scoped := *s
+	scoped.vault = s.vault.ForToken(tokenID)
+	return &scoped
+}
+
+// Match returns the entries userID can list whose URL or URIs match
+// pageURL, strongest match first and, among equal matches, most recently
+// used first.
+func (s *DomainService) Match(userID int64, pageURL string) (*models.EntryPage, error) {
+	groups, err := s.repo.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newPageMatcher(pageURL, groups)
+	if err != nil {
+		return nil, err
+	}
+	set, err := s.vault.loadPolicies(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, _, err := s.vault.repo.ListPage(userID, "", models.EntryFilter{}, models.SortAccessed, true, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.vault.loadURIs(entries); err != nil {
+		return nil, err
+	}
+	items := []models.VaultEntry{}
+	strength := map[int64]int{}
+	for i := range entries {
+		if !set.Allows(models.CapabilityList, &entries[i]).Allowed {
+			continue
+		}
+		if rating := matcher.rateEntry(&entries[i]); rating != matchNone {
+			items = append(items, entries[i])
+			strength[entries[i].ID] = rating
+		}
+	}
+	sort.SliceStable(items, func(a, b int) bool {
+		return strength[items[a].ID] > strength[items[b].ID]
+	})
+
+	if err := s.vault.prepareListed(userID, items); err != nil {
+		return nil, err
+	}
+	return &models.EntryPage{Items: items, Total: len(items)}, nil
+}
+
+// Groups returns userID's equivalent domain groups.
+func (s *DomainService) Groups(userID int64) ([]models.DomainGroup, error) {
+	return s.repo.List(userID)
+}
+
+// CreateGroup makes domains equivalent for userID. Domains are reduced to
+// their base domains, and each may only be in one group.
+func (s *DomainService) CreateGroup(userID int64, domains []string) (*models.DomainGroup, error) {
+	domains, err := normalizeDomainGroup(domains)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(domains)
+	group := models.DomainGroup{UserID: userID, Domains: domains, CreatedAt: time.Now().UTC()}
+	if group.ID, err = s.repo.Create(group); err != nil {
+		return nil, err
+	}
+	s.audit.LogUserEvent(userID, "domains.grouped", fmt.Sprintf("group=%d", group.ID))
+	return &group, nil
+}
+
+func (s *DomainService) DeleteGroup(userID, id int64) error {
+	err := s.repo.Delete(userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "domain group not found")
+	}
+	if err != nil {
+		return err
+	}
+	s.audit.LogUserEvent(userID, "domains.ungrouped", fmt.Sprintf("group=%d", id))
+	return nil
+}
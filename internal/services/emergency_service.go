@@ -0,0 +1,272 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const maxEmergencyWaitDays = 90
+
+// EmergencyService lets users name trusted contacts who can request read
+// access to their personal vault. Access is granted once the waiting period
+// passes unless the grantor rejects the request first; GrantDue is run by
+// the scheduler to do that.
+type EmergencyService struct {
+	repo     *repository.EmergencyRepository
+	users    *repository.UserRepository
+	vault    *VaultService
+	audit    *AuditService
+	notifier Notifier
+}
+
+func NewEmergencyService(repo *repository.EmergencyRepository, users *repository.UserRepository, vault *VaultService, audit *AuditService, notifier Notifier) *EmergencyService {
+	return &EmergencyService{repo: repo, users: users, vault: vault, audit: audit, notifier: notifier}
+}
+
+func errContactNotFound() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "emergency contact not found")
+}
+
+func validateWaitDays(days int) error {
+	if days < 0 || days > maxEmergencyWaitDays {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "waitDays must be between 0 and 90")
+	}
+	return nil
+}
+
+func (s *EmergencyService) AddContact(grantorID int64, email string, waitDays int) (*models.EmergencyContact, error) {
+	if err := validateWaitDays(waitDays); err != nil {
+		return nil, err
+	}
+
+	grantee, err := s.users.GetByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if grantee.ID == grantorID {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "cannot name yourself as an emergency contact")
+	}
+
+	id, err := s.repo.Create(grantorID, grantee.ID, waitDays)
+	if err != nil {
+		return nil, vaulterrors.NewVaultErrorWithErr(vaulterrors.ErrInvalidInput, "contact already exists", err)
+	}
+	contact, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.LogUserEvent(grantorID, "emergency.contact_added", fmt.Sprintf("contact=%d grantee=%d wait_days=%d", id, grantee.ID, waitDays))
+	s.notify("emergency.contact_added", contact.GranteeID, contact.GranteeEmail, contact)
+	return contact, nil
+}
+
+// ListContacts returns the trusted contacts grantorID has named.
+func (s *EmergencyService) ListContacts(grantorID int64) ([]models.EmergencyContact, error) {
+	return s.repo.ListByGrantor(grantorID)
+}
+
+// ListGrants returns the vaults granteeID is a trusted contact for.
+func (s *EmergencyService) ListGrants(granteeID int64) ([]models.EmergencyContact, error) {
+	return s.repo.ListByGrantee(granteeID)
+}
+
+func (s *EmergencyService) SetWaitDays(grantorID, id int64, waitDays int) error {
+	if err := validateWaitDays(waitDays); err != nil {
+		return err
+	}
+	if _, err := s.grantorContact(grantorID, id); err != nil {
+		return err
+	}
+	if err := s.repo.SetWaitDays(id, waitDays); err != nil {
+		return err
+	}
+	s.audit.LogUserEvent(grantorID, "emergency.wait_changed", fmt.Sprintf("contact=%d wait_days=%d", id, waitDays))
+	return nil
+}
+
+func (s *EmergencyService) RemoveContact(grantorID, id int64) error {
+	contact, err := s.grantorContact(grantorID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.audit.LogUserEvent(grantorID, "emergency.contact_removed", fmt.Sprintf("contact=%d grantee=%d", id, contact.GranteeID))
+	s.notify("emergency.contact_removed", contact.GranteeID, contact.GranteeEmail, contact)
+	return nil
+}
+
+// Request starts the waiting period. With a zero wait, access is granted at once.
+func (s *EmergencyService) Request(granteeID, id int64) (*models.EmergencyContact, error) {
+	contact, err := s.granteeContact(granteeID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ok, err := s.repo.Request(id, now, now.Add(time.Duration(contact.WaitDays)*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "access already "+contact.Status)
+	}
+	s.audit.LogUserEvent(granteeID, "emergency.requested", fmt.Sprintf("contact=%d grantor=%d", id, contact.GrantorID))
+
+	if contact.WaitDays == 0 {
+		if _, err := s.grant(id); err != nil {
+			return nil, err
+		}
+	}
+
+	contact, err = s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if contact.Status == models.EmergencyRequested {
+		s.notify("emergency.requested", contact.GrantorID, contact.GrantorEmail, contact)
+	}
+	return contact, nil
+}
+
+// Approve grants a pending request before the waiting period ends.
+func (s *EmergencyService) Approve(grantorID, id int64) error {
+	if _, err := s.grantorContact(grantorID, id); err != nil {
+		return err
+	}
+	ok, err := s.grant(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "no pending request")
+	}
+	return nil
+}
+
+// Reject turns down a pending request or revokes access already granted.
+func (s *EmergencyService) Reject(grantorID, id int64) error {
+	contact, err := s.grantorContact(grantorID, id)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.Reset(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "no pending request or granted access")
+	}
+
+	event := "emergency.rejected"
+	if contact.Status == models.EmergencyGranted {
+		event = "emergency.revoked"
+	}
+	s.audit.LogUserEvent(grantorID, event, fmt.Sprintf("contact=%d grantee=%d", id, contact.GranteeID))
+	s.notify(event, contact.GranteeID, contact.GranteeEmail, contact)
+	return nil
+}
+
+// GrantDue grants every request whose waiting period has passed.
+func (s *EmergencyService) GrantDue() error {
+	due, err := s.repo.ListDue(time.Now())
+	if err != nil {
+		return err
+	}
+	for _, contact := range due {
+		// A request rejected since ListDue is simply skipped
+		if _, err := s.grant(contact.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grant moves a pending request to granted and reports whether one was pending.
+func (s *EmergencyService) grant(id int64) (bool, error) {
+	ok, err := s.repo.Grant(id, time.Now().UTC())
+	if err != nil || !ok {
+		return false, err
+	}
+
+	contact, err := s.repo.GetByID(id)
+	if err != nil {
+		return false, err
+	}
+	s.audit.LogUserEvent(contact.GrantorID, "emergency.granted", fmt.Sprintf("contact=%d grantee=%d", id, contact.GranteeID))
+	s.notify("emergency.granted", contact.GranteeID, contact.GranteeEmail, contact)
+	s.notify("emergency.granted", contact.GrantorID, contact.GrantorEmail, contact)
+	return true, nil
+}
+
+// Entries lists the grantor's personal entries, without secrets, to a
+// grantee whose access has been granted.
+func (s *EmergencyService) Entries(granteeID, id int64) ([]models.VaultEntry, error) {
+	contact, err := s.grantedContact(granteeID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.vault.ListPersonal(contact.GrantorID)
+}
+
+func (s *EmergencyService) Entry(granteeID, id, entryID int64, authTime time.Time) (*models.VaultEntry, error) {
+	contact, err := s.grantedContact(granteeID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.vault.GetPersonal(contact.GrantorID, granteeID, entryID, authTime)
+}
+
+func (s *EmergencyService) grantorContact(grantorID, id int64) (*models.EmergencyContact, error) {
+	contact, err := s.repo.GetByID(id)
+	if err != nil || contact.GrantorID != grantorID {
+		return nil, errContactNotFound()
+	}
+	return contact, nil
+}
+
+func (s *EmergencyService) granteeContact(granteeID, id int64) (*models.EmergencyContact, error) {
+	contact, err := s.repo.GetByID(id)
+	if err != nil || contact.GranteeID != granteeID {
+		return nil, errContactNotFound()
+	}
+	return contact, nil
+}
+
+func (s *EmergencyService) grantedContact(granteeID, id int64) (*models.EmergencyContact, error) {
+	contact, err := s.granteeContact(granteeID, id)
+	if err != nil {
+		return nil, err
+	}
+	if contact.Status != models.EmergencyGranted {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrForbidden, "emergency access has not been granted")
+	}
+	return contact, nil
+}
+
+func (s *EmergencyService) notify(event string, userID int64, email string, contact *models.EmergencyContact) {
+	s.notifier.Notify(Notification{
+		Event:  event,
+		UserID: userID,
+		Email:  email,
+		Data: map[string]any{
+			"contactId": contact.ID,
+			"grantor":   contact.GrantorEmail,
+			"grantee":   contact.GranteeEmail,
+			"status":    contact.Status,
+			"grantAt":   contact.GrantAt,
+		},
+		SentAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+)
+
+// snapshot captures entry as stored, secrets included, as a revision to be
+// written alongside the change that replaces it. changedBy is nil for
+// password rotations.
+func (s *VaultService) snapshot(userID int64, entry *models.VaultEntry, changedBy *int64) (*models.EntryRevision, error) {
+	cipher, err := s.entryCipher(userID, entry)
+	if err != nil {
+		return nil, err
+	}
+	snap := *entry
+	if snap.Password, err = cipher.Decrypt(entry.PasswordEnc); err != nil {
+		return nil, err
+	}
+	if err := openEntryData(cipher, &snap); err != nil {
+		return nil, err
+	}
+	if err := s.openFields(cipher, &snap); err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(snap)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := cipher.Encrypt(string(plain))
+	if err != nil {
+		return nil, err
+	}
+	return &models.EntryRevision{
+		EntryID:     entry.ID,
+		ChangedBy:   changedBy,
+		CreatedAt:   time.Now().UTC(),
+		SnapshotEnc: enc,
+		KeyEnc:      entry.KeyEnc,
+	}, nil
+}
+
+// openRevision decrypts revision's snapshot into revision.Entry. Revisions
+// written before the entry got its own key are under the server key.
+func (s *VaultService) openRevision(userID int64, entry *models.VaultEntry, revision *models.EntryRevision) error {
+	cipher := s.crypto
+	if revision.KeyEnc != "" {
+		var err error
+		if cipher, err = s.entryCipher(userID, entry); err != nil {
+			return err
+		}
+	}
+	plain, err := cipher.Decrypt(revision.SnapshotEnc)
+	if err != nil {
+		return err
+	}
+	revision.Entry = &models.VaultEntry{}
+	return json.Unmarshal([]byte(plain), revision.Entry)
+}
+
+// History returns the earlier versions of entry id, newest first, with
+// their secrets. It is guarded like Get.
+func (s *VaultService) History(userID, id int64, authTime time.Time) ([]models.EntryRevision, error) {
+	entry, err := s.requireReadable(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireRevealable(userID, entry, authTime); err != nil {
+		return nil, err
+	}
+	if err := s.requireCheckout(userID, entry); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.ListRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if err := s.openRevision(userID, entry, &revisions[i]); err != nil {
+			return nil, err
+		}
+	}
+	s.audit.LogEvent(userID, id, "history_viewed")
+	return revisions, nil
+}
+
+// Restore brings back the contents of an earlier version of entry id:
+// title, username, password, URL, category, notes, type-specific data and
+// custom fields. The protection flags and collection stay as they are now.
+// The version being replaced is itself kept as a new revision.
+func (s *VaultService) Restore(userID, id int64, revision int, authTime time.Time) error {
+	current, err := s.requireWritable(userID, id)
+	if err != nil {
+		return err
+	}
+	stored, err := s.repo.GetRevision(id, revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "revision not found")
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.openRevision(userID, current, stored); err != nil {
+		return err
+	}
+
+	entry := *stored.Entry
+	if entry.Fields == nil {
+		entry.Fields = []models.CustomField{}
+	}
+	if err := s.Update(userID, id, entry, models.EntryFlags{}, authTime); err != nil {
+		return err
+	}
+	s.audit.LogEntryEvent(userID, id, "restored", fmt.Sprintf("revision=%d", revision))
+	return nil
+}
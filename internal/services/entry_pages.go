@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"vault/internal/models"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// listPage returns one page of the entries userID can list that match
+// filter and, for searches, terms. Entries the caller's policies hide are
+// skipped without shortening the page.
+func (s *VaultService) listPage(userID int64, terms []searchTerm, filter models.EntryFilter, page models.PageRequest) (*models.EntryPage, error) {
+	if err := s.validateEntryFilter(userID, &filter); err != nil {
+		return nil, err
+	}
+	sort, desc, limit, after, err := resolvePage(page, terms != nil)
+	if err != nil {
+		return nil, err
+	}
+	set, err := s.loadPolicies(userID)
+	if err != nil {
+		return nil, err
+	}
+	if terms != nil {
+		return s.searchPage(userID, terms, filter, sort, desc, limit, after, set)
+	}
+
+	// Fetch one entry more than the page holds to learn whether another
+	// page follows, topping up batches thinned out by policies
+	items := []models.VaultEntry{}
+	keys := []string{}
+	for len(items) <= limit {
+		batch, batchKeys, err := s.repo.ListPage(userID, "", filter, sort, desc, after, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			if set.Allows(models.CapabilityList, &batch[i]).Allowed {
+				items = append(items, batch[i])
+				keys = append(keys, batchKeys[i])
+			}
+		}
+		if len(batch) <= limit {
+			break
+		}
+		last := len(batch) - 1
+		after = &models.EntryCursor{Key: batchKeys[last], ID: batch[last].ID}
+	}
+
+	result := &models.EntryPage{Items: items}
+	if len(items) > limit {
+		result.Items = items[:limit]
+		result.NextCursor = encodeCursor(models.EntryCursor{
+			Sort: sort,
+			Desc: desc,
+			Key:  keys[limit-1],
+			ID:   items[limit-1].ID,
+		})
+	}
+	if result.Total, err = s.countListable(userID, filter, set); err != nil {
+		return nil, err
+	}
+	if err := s.prepareListed(userID, result.Items); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// countListable counts the entries listPage would list across all pages.
+func (s *VaultService) countListable(userID int64, filter models.EntryFilter, set *PolicySet) (int, error) {
+	if set.Empty() {
+		return s.repo.Count(userID, "", filter)
+	}
+	entries, _, err := s.repo.ListPage(userID, "", filter, models.SortCreated, true, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := range entries {
+		if set.Allows(models.CapabilityList, &entries[i]).Allowed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// resolvePage validates page and fills in its defaults. Searches sort by
+// relevance and lists by creation unless told otherwise. Titles sort A to
+// Z by default, everything else newest or best first.
+func resolvePage(page models.PageRequest, searching bool) (sort string, desc bool, limit int, after *models.EntryCursor, err error) {
+	sort = strings.ToLower(page.Sort)
+	switch sort {
+	case "":
+		sort = models.SortCreated
+		if searching {
+			sort = models.SortRelevance
+		}
+	case models.SortCreated, models.SortUpdated, models.SortTitle, models.SortAccessed:
+	case models.SortRelevance:
+		if !searching {
+			return "", false, 0, nil, errInvalidEntry("only searches sort by relevance")
+		}
+	default:
+		return "", false, 0, nil, errInvalidEntry("sort must be created, updated, title, accessed or relevance")
+	}
+	switch strings.ToLower(page.Order) {
+	case "":
+		desc = sort != models.SortTitle
+	case "asc":
+	case "desc":
+		desc = true
+	default:
+		return "", false, 0, nil, errInvalidEntry("order must be asc or desc")
+	}
+
+	limit = page.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 1 || limit > maxPageSize {
+		return "", false, 0, nil, errInvalidEntry("limit must be between 1 and 200")
+	}
+
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return "", false, 0, nil, err
+		}
+		if cursor.Sort != sort || cursor.Desc != desc {
+			return "", false, 0, nil, errInvalidEntry("cursor belongs to a different sort order")
+		}
+		if searching && cursor.Offset <= 0 || !searching && cursor.ID == 0 {
+			return "", false, 0, nil, errInvalidEntry("invalid cursor")
+		}
+		after = cursor
+	}
+	return sort, desc, limit, after, nil
+}
+
+func encodeCursor(cursor models.EntryCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*models.EntryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidEntry("invalid cursor")
+	}
+	var cursor models.EntryCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, errInvalidEntry("invalid cursor")
+	}
+	return &cursor, nil
+}
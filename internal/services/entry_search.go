@@ -0,0 +1,309 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vault/internal/models"
+)
+
+// Relevance is how well an entry matches, between 0 and 1, raised by up to
+// recencyWeight for recent use and frequencyWeight for frequent use. Index
+// hits score from exactMatchFloor up; entries found only by the fuzzy
+// matcher score below it.
+const (
+	exactMatchFloor = 0.6
+	recencyWeight   = 0.5
+	recencyHalfLife = 14 * 24 * time.Hour
+	frequencyWeight = 0.5
+)
+
+// maxFuzzyTerms is how many of the closest indexed words a query word is
+// widened to when searching with typos.
+const maxFuzzyTerms = 32
+
+// fuzzyColumns are the index columns a query word not limited to a field is
+// compared with allowing typos; notes only are with notes:.
+var fuzzyColumns = []string{"title", "username", "url", "category"}
+
+// searchPage returns one page of the entries userID can list that match
+// terms, exactly through the search index or approximately through
+// fuzzyScore, ordered by sortKey or by relevance. Only the entries the index
+// finds for candidateQuery are scored. Since relevance moves as entries are
+// used, search pages are addressed by offset.
+func (s *VaultService) searchPage(userID int64, terms []searchTerm, filter models.EntryFilter, sortKey string, desc bool, limit int, after *models.EntryCursor, set *PolicySet) (*models.EntryPage, error) {
+	hits, hitKeys, err := s.repo.ListPage(userID, ftsQuery(terms), filter, models.SortRelevance, true, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	indexed := map[int64]int{}
+	best := 0.0
+	scores := make([]float64, len(hits))
+	for i := range hits {
+		indexed[hits[i].ID] = i
+		scores[i], _ = strconv.ParseFloat(hitKeys[i], 64)
+		best = math.Max(best, scores[i])
+	}
+
+	// Walk the candidates in the requested order, or newest first when
+	// ranking, keeping those that match either way
+	order, orderDesc := sortKey, desc
+	if sortKey == models.SortRelevance {
+		order, orderDesc = models.SortCreated, true
+	}
+	query, err := s.candidateQuery(userID, filter, terms)
+	if err != nil {
+		return nil, err
+	}
+	candidates, _, err := s.repo.ListPage(userID, query, filter, order, orderDesc, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	matched := []models.VaultEntry{}
+	match := []float64{}
+	maxCount := 0
+	for i := range candidates {
+		entry := &candidates[i]
+		if !set.Allows(models.CapabilityList, entry).Allowed {
+			continue
+		}
+		var score float64
+		if hit, ok := indexed[entry.ID]; ok {
+			score = 1
+			if best > 0 {
+				score = exactMatchFloor + (1-exactMatchFloor)*scores[hit]/best
+			}
+			entry.Snippet = hits[hit].Snippet
+		} else if fuzzy := fuzzyScore(terms, entry); fuzzy > 0 {
+			score = exactMatchFloor * fuzzy
+		} else {
+			continue
+		}
+		matched = append(matched, *entry)
+		match = append(match, score)
+		if entry.AccessCount > maxCount {
+			maxCount = entry.AccessCount
+		}
+	}
+
+	if sortKey == models.SortRelevance {
+		now := time.Now().UTC()
+		ranked := make([]int, len(matched))
+		relevance := make([]float64, len(matched))
+		for i := range matched {
+			ranked[i] = i
+			relevance[i] = match[i] * usageBoost(&matched[i], maxCount, now)
+		}
+		sort.SliceStable(ranked, func(a, b int) bool {
+			if desc {
+				return relevance[ranked[a]] > relevance[ranked[b]]
+			}
+			return relevance[ranked[a]] < relevance[ranked[b]]
+		})
+		sorted := make([]models.VaultEntry, len(matched))
+		for i, idx := range ranked {
+			sorted[i] = matched[idx]
+		}
+		matched = sorted
+	}
+
+	offset := 0
+	if after != nil {
+		offset = min(after.Offset, len(matched))
+	}
+	end := min(offset+limit, len(matched))
+	result := &models.EntryPage{Items: matched[offset:end], Total: len(matched)}
+	if end < len(matched) {
+		result.NextCursor = encodeCursor(models.EntryCursor{Sort: sortKey, Desc: desc, Offset: end})
+	}
+	if err := s.prepareListed(userID, result.Items); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// candidateQuery is an FTS5 query for the entries that may match terms
+// either exactly or within fuzzyScore's typo allowance: each query word is
+// widened to the words it is close to among the entries userID can read
+// that match filter, so other users' words never crowd out the caller's.
+func (s *VaultService) candidateQuery(userID int64, filter models.EntryFilter, terms []searchTerm) (string, error) {
+	vocabulary := map[string][]string{}
+	var words []string
+	for _, term := range terms {
+		columns, colset := fuzzyColumns, "{"+strings.Join(fuzzyColumns, " ")+"}"
+		if term.column != "" {
+			columns, colset = []string{term.column}, term.column
+		}
+		for _, word := range searchWords(term.text) {
+			alternatives := []string{ftsString(word) + "*"}
+			if typoAllowance(word) > 0 {
+				known, ok := vocabulary[colset]
+				if !ok {
+					var err error
+					if known, err = s.entryWords(userID, filter, columns); err != nil {
+						return "", err
+					}
+					vocabulary[colset] = known
+				}
+				for _, near := range closestWords(word, known, maxFuzzyTerms) {
+					alternatives = append(alternatives, ftsString(near))
+				}
+			}
+			words = append(words, colset+" : ("+strings.Join(alternatives, " OR ")+")")
+		}
+	}
+	return "(" + ftsQuery(terms) + ") OR (" + strings.Join(words, " AND ") + ")", nil
+}
+
+// entryWords returns the distinct words in columns of the entries userID
+// can read that match filter.
+func (s *VaultService) entryWords(userID int64, filter models.EntryFilter, columns []string) ([]string, error) {
+	texts, err := s.repo.SearchTexts(userID, filter, columns)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	words := []string{}
+	for _, text := range texts {
+		for _, word := range searchWords(text) {
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+	}
+	return words, nil
+}
+
+// closestWords returns up to limit of candidates that query is within its
+// typo allowance of, closest first.
+func closestWords(query string, candidates []string, limit int) []string {
+	var near []string
+	similarity := map[string]float64{}
+	for _, candidate := range candidates {
+		if sim := wordSimilarity(query, candidate); sim > 0 {
+			near = append(near, candidate)
+			similarity[candidate] = sim
+		}
+	}
+	sort.SliceStable(near, func(a, b int) bool {
+		return similarity[near[a]] > similarity[near[b]]
+	})
+	return near[:min(limit, len(near))]
+}
+
+// usageBoost is the factor by which entry's relevance grows with use: up
+// to recencyWeight when it was opened just now, halving every
+// recencyHalfLife, plus up to frequencyWeight for the most opened entry
+// among the results.
+func usageBoost(entry *models.VaultEntry, maxCount int, now time.Time) float64 {
+	boost := 1.0
+	if entry.LastAccessedAt != nil {
+		age := now.Sub(*entry.LastAccessedAt)
+		boost += recencyWeight * math.Pow(0.5, max(age, 0).Hours()/recencyHalfLife.Hours())
+	}
+	if maxCount > 0 {
+		boost += frequencyWeight * math.Log1p(float64(entry.AccessCount)) / math.Log1p(float64(maxCount))
+	}
+	return boost
+}
+
+// fuzzyScore rates from 0 to 1 how closely entry matches terms, 0 meaning
+// some word of the query matches nothing. Query words are compared with
+// the words of the entry's title, username, URL and category, or of the
+// term's field, allowing one typo in words of four or five letters, two in
+// longer ones and none in shorter ones. A query word may also be the start
+// of a word.
+func fuzzyScore(terms []searchTerm, entry *models.VaultEntry) float64 {
+	fields := map[string][]string{
+		"title":    searchWords(entry.Title),
+		"username": searchWords(entry.Username),
+		"url":      searchWords(entry.URL),
+		"category": searchWords(entry.Category),
+	}
+	var all []string
+	for _, words := range fields {
+		all = append(all, words...)
+	}
+	fields["notes"] = searchWords(entry.Notes)
+
+	total, count := 0.0, 0
+	for _, term := range terms {
+		candidates := all
+		if term.column != "" {
+			candidates = fields[term.column]
+		}
+		for _, word := range searchWords(term.text) {
+			best := 0.0
+			for _, candidate := range candidates {
+				best = math.Max(best, wordSimilarity(word, candidate))
+			}
+			if best == 0 {
+				return 0
+			}
+			total += best
+			count++
+		}
+	}
+	return total / float64(count)
+}
+
+// wordSimilarity rates from 0 to 1 how closely query word matches word,
+// 0 meaning not within the typo allowance. Matching only the start of word
+// rates a little lower than matching all of it.
+func wordSimilarity(query, word string) float64 {
+	q, w := []rune(query), []rune(word)
+	allowed := typoAllowance(query)
+
+	best := 0.0
+	if d := editDistance(q, w); d <= allowed {
+		best = 1 - float64(d)/float64(max(len(q), len(w)))
+	}
+	if len(w) > len(q) {
+		if d := editDistance(q, w[:len(q)]); d <= allowed {
+			best = math.Max(best, 0.9*(1-float64(d)/float64(len(q))))
+		}
+	}
+	return best
+}
+
+// typoAllowance is how many typos a query word may contain: none up to
+// three letters, one in four or five and two in longer words.
+func typoAllowance(word string) int {
+	switch n := len([]rune(word)); {
+	case n > 5:
+		return 2
+	case n > 3:
+		return 1
+	}
+	return 0
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent letters that turn a into b (optimal string alignment distance).
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
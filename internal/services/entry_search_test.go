@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"vault/internal/models"
+)
+
+func TestTypoSearchIgnoresOtherUsersWords(t *testing.T) {
+	v := newVaultTest(t)
+	owner := v.register(t, "owner@example.com")
+	other := v.register(t, "other@example.com")
+	mine := v.createLegacy(t, owner, "GitHub", "pw")
+
+	// Another user's titles that are all closer to the typo than GitHub is,
+	// more of them than a query word is widened to
+	for _, c := range "abcdefghijklmnopqrstuvwxyz" {
+		v.createLegacy(t, other, "gihtub"+string(c), "pw")
+		v.createLegacy(t, other, string(c)+"gihtub", "pw")
+	}
+
+	page, err := v.vault.Search(context.Background(), owner, "gihtub", models.EntryFilter{}, models.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != mine {
+		t.Fatalf("search found %d entries, want only the owner's GitHub", len(page.Items))
+	}
+}
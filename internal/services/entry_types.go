@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+)
+
+// entryTypes maps each entry type to whether it carries type-specific data.
+var entryTypes = map[string]bool{
+	models.EntryTypeLogin:    false,
+	models.EntryTypeNote:     true,
+	models.EntryTypeCard:     true,
+	models.EntryTypeIdentity: true,
+	models.EntryTypeSSHKey:   true,
+	models.EntryTypeAPIKey:   true,
+}
+
+// ValidEntryType reports whether t is a known entry type.
+func ValidEntryType(t string) bool {
+	_, ok := entryTypes[t]
+	return ok
+}
+
+func errInvalidEntry(message string) error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, message)
+}
+
+// validateEntryData checks that data holds exactly the fields of entryType,
+// normalizes them and fills in the derived ones.
+func validateEntryData(entryType string, data *models.EntryData) error {
+	set := map[string]bool{
+		models.EntryTypeNote:     data.Note != nil,
+		models.EntryTypeCard:     data.Card != nil,
+		models.EntryTypeIdentity: data.Identity != nil,
+		models.EntryTypeSSHKey:   data.SSHKey != nil,
+		models.EntryTypeAPIKey:   data.APIKey != nil,
+	}
+	for t, present := range set {
+		if present && t != entryType {
+			return errInvalidEntry(t + " fields do not belong on a " + entryType + " entry")
+		}
+	}
+	if entryTypes[entryType] && !set[entryType] {
+		return errInvalidEntry(entryType + " fields required")
+	}
+
+	switch entryType {
+	case models.EntryTypeNote:
+		if strings.TrimSpace(data.Note.Text) == "" {
+			return errInvalidEntry("note text required")
+		}
+	case models.EntryTypeCard:
+		return validateCard(data.Card)
+	case models.EntryTypeIdentity:
+		if *data.Identity == (models.IdentityData{}) {
+			return errInvalidEntry("identity needs at least one field")
+		}
+	case models.EntryTypeSSHKey:
+		return validateSSHKey(data.SSHKey)
+	case models.EntryTypeAPIKey:
+		if strings.TrimSpace(data.APIKey.Key) == "" {
+			return errInvalidEntry("api key required")
+		}
+	}
+	return nil
+}
+
+func validateCard(card *models.CardData) error {
+	number := strings.NewReplacer(" ", "", "-", "").Replace(card.Number)
+	if len(number) < 12 || len(number) > 19 || !isDigits(number) || !luhnValid(number) {
+		return errInvalidEntry("invalid card number")
+	}
+	card.Number = number
+
+	if card.ExpMonth < 1 || card.ExpMonth > 12 {
+		return errInvalidEntry("expMonth must be between 1 and 12")
+	}
+	if card.ExpYear >= 0 && card.ExpYear < 100 {
+		card.ExpYear += 2000
+	}
+	if card.ExpYear < 2000 || card.ExpYear > 2100 {
+		return errInvalidEntry("invalid expYear")
+	}
+	if card.CVV != "" && (len(card.CVV) < 3 || len(card.CVV) > 4 || !isDigits(card.CVV)) {
+		return errInvalidEntry("cvv must be 3 or 4 digits")
+	}
+	if card.Brand == "" {
+		card.Brand = cardBrand(number)
+	}
+	return nil
+}
+
+// cardBrand guesses the card network from the number's prefix.
+func cardBrand(number string) string {
+	switch {
+	case strings.HasPrefix(number, "4"):
+		return "visa"
+	case strings.HasPrefix(number, "34"), strings.HasPrefix(number, "37"):
+		return "amex"
+	case number[:2] >= "51" && number[:2] <= "55", number[:4] >= "2221" && number[:4] <= "2720":
+		return "mastercard"
+	case strings.HasPrefix(number, "6011"), strings.HasPrefix(number, "65"):
+		return "discover"
+	case number[:4] >= "3528" && number[:4] <= "3589":
+		return "jcb"
+	default:
+		return ""
+	}
+}
+
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validateSSHKey parses the private key and derives its public key and
+// SHA256 fingerprint.
+func validateSSHKey(key *models.SSHKeyData) error {
+	pem := []byte(strings.TrimSpace(key.PrivateKey) + "\n")
+	var signer ssh.Signer
+	var err error
+	if key.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(key.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(pem)
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return errInvalidEntry("passphrase required for encrypted ssh key")
+	}
+	if err != nil {
+		return errInvalidEntry("invalid ssh private key")
+	}
+
+	key.PrivateKey = string(pem)
+	key.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	key.Fingerprint = ssh.FingerprintSHA256(signer.PublicKey())
+	return nil
+}
+
+// sealEntryData encrypts the entry's type-specific fields into DataEnc.
+func sealEntryData(cipher *CryptoService, entry *models.VaultEntry) error {
+	if entry.EntryData == (models.EntryData{}) {
+		entry.DataEnc = ""
+		return nil
+	}
+	plain, err := json.Marshal(entry.EntryData)
+	if err != nil {
+		return err
+	}
+	entry.DataEnc, err = cipher.Encrypt(string(plain))
+	return err
+}
+
+// openEntryData decrypts DataEnc into the entry's type-specific fields.
+func openEntryData(cipher *CryptoService, entry *models.VaultEntry) error {
+	if entry.DataEnc == "" {
+		return nil
+	}
+	plain, err := cipher.Decrypt(entry.DataEnc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(plain), &entry.EntryData)
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const (
+	maxFolderNameLength = 100
+	maxFolderDepth      = 10
+)
+
+// FolderService manages the nested folders of a user's personal vault.
+// Filing entries is done through VaultService.SetFolder.
+type FolderService struct {
+	repo  *repository.FolderRepository
+	vault *VaultService
+	audit *AuditService
+}
+
+func NewFolderService(repo *repository.FolderRepository, vault *VaultService, audit *AuditService) *FolderService {
+	return &FolderService{repo: repo, vault: vault, audit: audit}
+}
+
+// ForToken returns a FolderService enforcing API token tokenID's policies
+// on the entries a folder deletion trashes.
+func (s *FolderService) ForToken(tokenID int64) *FolderService {
+	scoped := *s
+	scoped.vault = s.vault.ForToken(tokenID)
+	return &scoped
+}
+
+// Tree returns userID's folders as a tree, each with its entry counts.
+func (s *FolderService) Tree(userID int64) ([]models.FolderNode, error) {
+	folders, err := s.repo.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.EntryCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[int64][]models.Folder{}
+	var roots []models.Folder
+	for _, folder := range folders {
+		if folder.ParentID == nil {
+			roots = append(roots, folder)
+		} else {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder)
+		}
+	}
+
+	var build func(folders []models.Folder) []models.FolderNode
+	build = func(folders []models.Folder) []models.FolderNode {
+		nodes := []models.FolderNode{}
+		for _, folder := range folders {
+			node := models.FolderNode{Folder: folder, Count: counts[folder.ID], Children: build(children[folder.ID])}
+			node.Total = node.Count
+			for _, child := range node.Children {
+				node.Total += child.Total
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(roots), nil
+}
+
+func (s *FolderService) Create(userID int64, name string, parentID *int64) (*models.Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		folders, err := s.repo.List(userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkFolderParent(folders, 0, *parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	folder := models.Folder{UserID: userID, ParentID: parentID, Name: name, CreatedAt: now, UpdatedAt: now}
+	if folder.ID, err = s.repo.Create(folder); err != nil {
+		return nil, err
+	}
+	s.audit.LogUserEvent(userID, "folder.created", fmt.Sprintf("folder=%d", folder.ID))
+	return &folder, nil
+}
+
+func (s *FolderService) Rename(userID, id int64, name string) (*models.Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := s.requireFolder(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	folder.Name = name
+	folder.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(*folder); err != nil {
+		return nil, err
+	}
+	s.audit.LogUserEvent(userID, "folder.renamed", fmt.Sprintf("folder=%d", id))
+	return folder, nil
+}
+
+// Move puts folder id, with its subfolders and entries, under parentID, or
+// at the top level when parentID is nil.
+func (s *FolderService) Move(userID, id int64, parentID *int64) (*models.Folder, error) {
+	folder, err := s.requireFolder(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		folders, err := s.repo.List(userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkFolderParent(folders, id, *parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	folder.ParentID = parentID
+	folder.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(*folder); err != nil {
+		return nil, err
+	}
+	s.audit.LogUserEvent(userID, "folder.moved", fmt.Sprintf("folder=%d", id))
+	return folder, nil
+}
+
+// Delete removes folder id and its subfolders and moves the entries filed
+// in them to the trash. It returns how many entries were trashed. Nothing
+// is deleted when the caller's policies forbid deleting any of the entries.
+func (s *FolderService) Delete(userID, id int64) (int, error) {
+	if _, err := s.requireFolder(userID, id); err != nil {
+		return 0, err
+	}
+	folders, err := s.repo.List(userID)
+	if err != nil {
+		return 0, err
+	}
+	folderIDs := folderSubtree(folders, id)
+
+	entries, err := s.repo.ListEntries(userID, folderIDs)
+	if err != nil {
+		return 0, err
+	}
+	entryIDs := make([]int64, len(entries))
+	for i := range entries {
+		if err := s.vault.authorize(userID, models.CapabilityDelete, &entries[i]); err != nil {
+			return 0, err
+		}
+		entryIDs[i] = entries[i].ID
+	}
+
+	if err := s.repo.Delete(userID, folderIDs, entryIDs, time.Now().UTC()); err != nil {
+		return 0, err
+	}
+	for _, entryID := range entryIDs {
+		s.audit.LogEvent(userID, entryID, "trashed")
+	}
+	s.audit.LogUserEvent(userID, "folder.deleted", fmt.Sprintf("folder=%d entries=%d", id, len(entryIDs)))
+	return len(entryIDs), nil
+}
+
+func (s *FolderService) requireFolder(userID, id int64) (*models.Folder, error) {
+	folder, err := s.repo.GetByID(userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "folder not found")
+	}
+	return folder, err
+}
+
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxFolderNameLength {
+		return "", errInvalidEntry("folder names must be 1-100 characters")
+	}
+	if strings.Contains(name, "/") {
+		return "", errInvalidEntry("folder names cannot contain /")
+	}
+	return name, nil
+}
+
+// checkFolderParent checks that parentID is one of folders and that placing
+// folder id (0 for a new folder) under it neither creates a cycle nor nests
+// deeper than maxFolderDepth.
+func checkFolderParent(folders []models.Folder, id, parentID int64) error {
+	byID := map[int64]models.Folder{}
+	for _, folder := range folders {
+		byID[folder.ID] = folder
+	}
+	if _, ok := byID[parentID]; !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "parent folder not found")
+	}
+
+	// Levels from the parent up to the top, plus the moved folder's own
+	// subtree below it
+	depth := 1
+	for current := parentID; ; depth++ {
+		if current == id {
+			return errInvalidEntry("a folder cannot be moved into itself")
+		}
+		parent := byID[current].ParentID
+		if parent == nil {
+			break
+		}
+		current = *parent
+	}
+	if id != 0 {
+		depth += subtreeHeight(folders, id)
+	} else {
+		depth++
+	}
+	if depth > maxFolderDepth {
+		return errInvalidEntry(fmt.Sprintf("folders nest at most %d levels deep", maxFolderDepth))
+	}
+	return nil
+}
+
+// folderSubtree returns id and the ids of all folders below it.
+func folderSubtree(folders []models.Folder, id int64) []int64 {
+	ids := []int64{id}
+	for i := 0; i < len(ids); i++ {
+		for _, folder := range folders {
+			if folder.ParentID != nil && *folder.ParentID == ids[i] {
+				ids = append(ids, folder.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// subtreeHeight counts the levels from folder id down to its deepest
+// descendant, id itself being one.
+func subtreeHeight(folders []models.Folder, id int64) int {
+	height := 0
+	for _, folder := range folders {
+		if folder.ParentID != nil && *folder.ParentID == id {
+			if h := subtreeHeight(folders, folder.ID); h > height {
+				height = h
+			}
+		}
+	}
+	return height + 1
+}
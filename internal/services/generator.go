@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"unicode"
+
+	"vault/internal/models"
+)
+
+const rotatedPasswordLength = 24
+
+const (
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	digitChars  = "0123456789"
+	symbolChars = "!@#$%^&*-_=+"
+
+	// ambiguousChars are easily mistaken for one another when read
+	ambiguousChars = "0Oo1lI|"
+
+	consonants = "bcdfghjklmnprstvwz"
+	vowels     = "aeiou"
+)
+
+const passwordAlphabet = upperChars + lowerChars + digitChars + symbolChars
+
+const (
+	defaultGeneratedLength     = 20
+	defaultPronounceableLength = 16
+	minGeneratedLength         = 8
+	maxGeneratedLength         = 128
+	defaultPassphraseWords     = 6
+	minPassphraseWords         = 3
+	maxPassphraseWords         = 20
+	maxSeparatorLength         = 5
+	// minWordlistSize is the size of the EFF short wordlists; the large
+	// one has 7776 words
+	minWordlistSize = 1296
+)
+
+// effLargeWordlist is the EFF large wordlist (CC BY 3.0), used for
+// passphrases unless another list is configured.
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+var charSets = map[string]string{
+	models.CharsUpper:   upperChars,
+	models.CharsLower:   lowerChars,
+	models.CharsDigits:  digitChars,
+	models.CharsSymbols: symbolChars,
+}
+
+// generatePassword returns length characters drawn uniformly from alphabet
+// with crypto/rand.
+func generatePassword(length int, alphabet string) (string, error) {
+	out := make([]byte, length)
+	for i := range out {
+		n, err := randomIndex(len(alphabet))
+		if err != nil {
+			return "", err
+		}
+		out[i] = alphabet[n]
+	}
+	return string(out), nil
+}
+
+// randomIndex returns a uniformly random integer in [0, n) from crypto/rand.
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+// PasswordGenerator makes random passwords, diceware passphrases and
+// pronounceable passwords. Passphrases need a wordlist.
+type PasswordGenerator struct {
+	words []string
+}
+
+func NewPasswordGenerator(words []string) *PasswordGenerator {
+	return &PasswordGenerator{words: words}
+}
+
+// EFFWordlist returns the words of the EFF large wordlist built into the
+// binary.
+func EFFWordlist() []string {
+	words, err := parseWordlist(strings.NewReader(effLargeWordlist), "eff_large_wordlist.txt")
+	if err != nil {
+		panic(err)
+	}
+	return words
+}
+
+// LoadWordlist reads a diceware wordlist such as the EFF large wordlist,
+// either in its "11111<tab>abacus" form or one word per line.
+func LoadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseWordlist(f, path)
+}
+
+// parseWordlist reads the distinct words of the wordlist named name from r.
+func parseWordlist(r io.Reader, name string) ([]string, error) {
+	seen := map[string]bool{}
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		word := fields[len(fields)-1]
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) < minWordlistSize {
+		return nil, fmt.Errorf("%s has %d distinct words, need at least %d", name, len(words), minWordlistSize)
+	}
+	return words, nil
+}
+
+// Generate makes a password as described by opts.
+func (g *PasswordGenerator) Generate(opts models.GeneratorOptions) (*models.GeneratedPassword, error) {
+	switch opts.Type {
+	case "", models.GenerateRandom:
+		return generateRandom(opts)
+	case models.GeneratePassphrase:
+		return g.generatePassphrase(opts)
+	case models.GeneratePronounceable:
+		return generatePronounceable(opts)
+	}
+	return nil, errInvalidEntry("type must be random, passphrase or pronounceable")
+}
+
+func generatedLength(length, fallback int) (int, error) {
+	if length == 0 {
+		return fallback, nil
+	}
+	if length < minGeneratedLength || length > maxGeneratedLength {
+		return 0, errInvalidEntry(fmt.Sprintf("length must be between %d and %d", minGeneratedLength, maxGeneratedLength))
+	}
+	return length, nil
+}
+
+// generateRandom draws the minimum number of characters from each set,
+// fills up from all chosen sets together and shuffles the result.
+func generateRandom(opts models.GeneratorOptions) (*models.GeneratedPassword, error) {
+	length, err := generatedLength(opts.Length, defaultGeneratedLength)
+	if err != nil {
+		return nil, err
+	}
+	sets := opts.Sets
+	if len(sets) == 0 {
+		sets = []string{models.CharsUpper, models.CharsLower, models.CharsDigits, models.CharsSymbols}
+	}
+	chosen := map[string]string{}
+	alphabet := ""
+	for _, name := range sets {
+		chars, ok := charSets[name]
+		if !ok {
+			return nil, errInvalidEntry("unknown character set " + name)
+		}
+		if _, dup := chosen[name]; dup {
+			continue
+		}
+		if opts.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguousChars, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		chosen[name] = chars
+		alphabet += chars
+	}
+
+	var out []byte
+	for name, count := range opts.Minimums {
+		chars, ok := chosen[name]
+		if !ok {
+			return nil, errInvalidEntry("minimum given for unused character set " + name)
+		}
+		if count < 0 || len(out)+count > length {
+			return nil, errInvalidEntry("minimums must add up to at most the length")
+		}
+		part, err := generatePassword(count, chars)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, part...)
+	}
+	rest, err := generatePassword(length-len(out), alphabet)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, rest...)
+	// Fisher-Yates, so the required characters are not all at the front
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return &models.GeneratedPassword{
+		Password:    string(out),
+		EntropyBits: float64(length) * math.Log2(float64(len(alphabet))),
+	}, nil
+}
+
+// generatePassphrase joins random words from the wordlist. With
+// IncludeNumber one of the words gets a digit appended.
+func (g *PasswordGenerator) generatePassphrase(opts models.GeneratorOptions) (*models.GeneratedPassword, error) {
+	if len(g.words) == 0 {
+		return nil, errInvalidEntry("passphrases are not available: no wordlist is configured")
+	}
+	count := opts.Words
+	if count == 0 {
+		count = defaultPassphraseWords
+	}
+	if count < minPassphraseWords || count > maxPassphraseWords {
+		return nil, errInvalidEntry(fmt.Sprintf("words must be between %d and %d", minPassphraseWords, maxPassphraseWords))
+	}
+	separator := "-"
+	if opts.Separator != nil {
+		separator = *opts.Separator
+	}
+	if len(separator) > maxSeparatorLength {
+		return nil, errInvalidEntry(fmt.Sprintf("separator must be at most %d characters", maxSeparatorLength))
+	}
+
+	words := make([]string, count)
+	for i := range words {
+		n, err := randomIndex(len(g.words))
+		if err != nil {
+			return nil, err
+		}
+		words[i] = g.words[n]
+		if opts.Capitalize {
+			words[i] = capitalize(words[i])
+		}
+	}
+	entropy := float64(count) * math.Log2(float64(len(g.words)))
+	if opts.IncludeNumber {
+		at, err := randomIndex(count)
+		if err != nil {
+			return nil, err
+		}
+		digit, err := randomIndex(10)
+		if err != nil {
+			return nil, err
+		}
+		words[at] += fmt.Sprint(digit)
+		entropy += math.Log2(float64(count) * 10)
+	}
+	return &models.GeneratedPassword{Password: strings.Join(words, separator), EntropyBits: entropy}, nil
+}
+
+// generatePronounceable alternates consonants and vowels. With
+// IncludeNumber the last letter is replaced by a digit.
+func generatePronounceable(opts models.GeneratorOptions) (*models.GeneratedPassword, error) {
+	length, err := generatedLength(opts.Length, defaultPronounceableLength)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, length)
+	entropy := 0.0
+	for i := range out {
+		letters := consonants
+		if i%2 == 1 {
+			letters = vowels
+		}
+		if opts.IncludeNumber && i == length-1 {
+			letters = digitChars
+		}
+		n, err := randomIndex(len(letters))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = letters[n]
+		entropy += math.Log2(float64(len(letters)))
+	}
+	password := string(out)
+	if opts.Capitalize {
+		password = capitalize(password)
+	}
+	return &models.GeneratedPassword{Password: password, EntropyBits: entropy}, nil
+}
+
+func capitalize(word string) string {
+	for i, r := range word {
+		return string(unicode.ToUpper(r)) + word[i+len(string(r)):]
+	}
+	return word
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+// Argon2id parameters for key passphrases, the same as for send passphrases
+const (
+	keyKDFTime    = 2
+	keyKDFMemory  = 19 * 1024
+	keyKDFThreads = 1
+	keySaltSize   = 16
+
+	minKeyPassphraseLength = 8
+)
+
+// unlockedKey is a private key opened with its passphrase, kept in memory only.
+type unlockedKey struct {
+	priv      *ecdh.PrivateKey
+	expiresAt time.Time
+}
+
+// KeyService manages each user's X25519 key pair. Private keys are wrapped
+// with a key stretched from a key passphrase that only the user knows, so
+// the server cannot open shares at rest. A user unlocks their pair once per
+// session and the opened key is held in memory until unlockTTL passes.
+type KeyService struct {
+	keys   *repository.KeyRepository
+	crypto *CryptoService
+	// unlockTTL is how long an unlocked private key stays in memory
+	unlockTTL time.Duration
+	// reauthWindow is how recent auth_time must be to set a passphrase
+	reauthWindow time.Duration
+
+	mu       sync.Mutex
+	unlocked map[int64]unlockedKey
+}
+
+func NewKeyService(keys *repository.KeyRepository, crypto *CryptoService, unlockTTL, reauthWindow time.Duration) *KeyService {
+	return &KeyService{
+		keys:         keys,
+		crypto:       crypto,
+		unlockTTL:    unlockTTL,
+		reauthWindow: reauthWindow,
+		unlocked:     map[int64]unlockedKey{},
+	}
+}
+
+// Status reports whether the user has a protected key pair and whether it
+// is unlocked.
+func (s *KeyService) Status(userID int64) (*models.UserKeyStatus, error) {
+	key, err := s.keys.Get(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.UserKeyStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, unlocked := s.cached(userID)
+	return &models.UserKeyStatus{
+		PublicKey:     key.PublicKey,
+		PassphraseSet: key.KDFSalt != "",
+		Unlocked:      unlocked,
+	}, nil
+}
+
+// SetPassphrase protects the user's private key with passphrase, creating
+// the key pair if needed. Changing an existing passphrase needs the current
+// one; pairs from before key passphrases are rewrapped without it. It needs
+// a recent login (authTime) and leaves the key unlocked.
+func (s *KeyService) SetPassphrase(userID int64, current, passphrase string, authTime time.Time) error {
+	if err := requireAuthWithin(authTime, s.reauthWindow); err != nil {
+		return err
+	}
+	if len(passphrase) < minKeyPassphraseLength {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "key passphrase must be at least "+strconv.Itoa(minKeyPassphraseLength)+" characters")
+	}
+
+	key, err := s.keys.Get(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.create(userID, passphrase)
+	}
+	if err != nil {
+		return err
+	}
+
+	var priv *ecdh.PrivateKey
+	if key.KDFSalt == "" {
+		priv, err = s.openLegacy(key)
+	} else {
+		priv, err = openPrivateKey(key, current)
+	}
+	if err != nil {
+		return err
+	}
+
+	wrapped, salt, err := wrapPrivateKey(priv, passphrase)
+	if err != nil {
+		return err
+	}
+	ok, err := s.keys.Rewrap(userID, key.PrivateKeyEnc, wrapped, salt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrConflict, "key passphrase changed concurrently")
+	}
+	s.remember(userID, priv)
+	return nil
+}
+
+// Unlock opens the user's private key with passphrase and keeps it in
+// memory so shares can be read until it expires or Lock is called.
+func (s *KeyService) Unlock(userID int64, passphrase string) error {
+	key, err := s.keys.Get(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "no key pair; set a key passphrase first")
+	}
+	if err != nil {
+		return err
+	}
+	if key.KDFSalt == "" {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "set a key passphrase first")
+	}
+
+	priv, err := openPrivateKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	s.remember(userID, priv)
+	return nil
+}
+
+// Lock forgets the user's unlocked private key.
+func (s *KeyService) Lock(userID int64) {
+	s.mu.Lock()
+	delete(s.unlocked, userID)
+	s.mu.Unlock()
+}
+
+// PublicKey returns the user's public key. Pairs are only created by the
+// user themselves, with SetPassphrase.
+func (s *KeyService) PublicKey(userID int64) (*ecdh.PublicKey, error) {
+	key, err := s.keys.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.X25519().NewPublicKey(raw)
+}
+
+// SealFor encrypts data to the user's public key. Users who have never set
+// a key passphrase have no pair and cannot receive sealed data.
+func (s *KeyService) SealFor(userID int64, data []byte) (string, error) {
+	pub, err := s.PublicKey(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "recipient has not set up a key pair yet")
+	}
+	if err != nil {
+		return "", err
+	}
+	return sealX25519(pub, data)
+}
+
+// OpenFor decrypts data sealed to the user with SealFor. It fails with
+// ErrKeyLocked unless the user unlocked their private key.
+func (s *KeyService) OpenFor(userID int64, sealed string) ([]byte, error) {
+	priv, ok := s.cached(userID)
+	if !ok {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrKeyLocked, "unlock your key pair to open shared entries")
+	}
+	return openX25519(priv, sealed)
+}
+
+func (s *KeyService) create(userID int64, passphrase string) error {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	wrapped, salt, err := wrapPrivateKey(priv, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := s.keys.CreateIfMissing(models.UserKey{
+		UserID:        userID,
+		PublicKey:     base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()),
+		PrivateKeyEnc: wrapped,
+		KDFSalt:       salt,
+	}); err != nil {
+		return err
+	}
+
+	// A concurrent request may have stored its pair first
+	stored, err := s.keys.Get(userID)
+	if err != nil {
+		return err
+	}
+	if stored.PrivateKeyEnc != wrapped {
+		return vaulterrors.NewVaultError(vaulterrors.ErrConflict, "key pair created concurrently")
+	}
+	s.remember(userID, priv)
+	return nil
+}
+
+// openLegacy unwraps a pair created before key passphrases, which used a
+// key derived from the server key. It is only used to rewrap such pairs.
+func (s *KeyService) openLegacy(key *models.UserKey) (*ecdh.PrivateKey, error) {
+	wrap, err := s.crypto.Derive("user-key:" + strconv.FormatInt(key.UserID, 10))
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := wrap.Decrypt(key.PrivateKeyEnc)
+	if err != nil {
+		return nil, err
+	}
+	return decodePrivateKey(encoded)
+}
+
+func (s *KeyService) remember(userID int64, priv *ecdh.PrivateKey) {
+	now := time.Now()
+	s.mu.Lock()
+	for id, k := range s.unlocked {
+		if now.After(k.expiresAt) {
+			delete(s.unlocked, id)
+		}
+	}
+	s.unlocked[userID] = unlockedKey{priv: priv, expiresAt: now.Add(s.unlockTTL)}
+	s.mu.Unlock()
+}
+
+func (s *KeyService) cached(userID int64) (*ecdh.PrivateKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.unlocked[userID]
+	if !ok || time.Now().After(k.expiresAt) {
+		return nil, false
+	}
+	return k.priv, true
+}
+
+// wrapPrivateKey encrypts priv under passphrase stretched with a fresh salt
+// and returns the wrapped key and the salt.
+func wrapPrivateKey(priv *ecdh.PrivateKey, passphrase string) (string, string, error) {
+	salt := make([]byte, keySaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", "", err
+	}
+	wrap, err := passphraseCipher(passphrase, salt)
+	if err != nil {
+		return "", "", err
+	}
+	wrapped, err := wrap.Encrypt(base64.StdEncoding.EncodeToString(priv.Bytes()))
+	if err != nil {
+		return "", "", err
+	}
+	return wrapped, base64.StdEncoding.EncodeToString(salt), nil
+}
+
+// openPrivateKey unwraps a passphrase-protected private key; a wrong
+// passphrase fails with ErrUnauthorized.
+func openPrivateKey(key *models.UserKey, passphrase string) (*ecdh.PrivateKey, error) {
+	salt, err := base64.StdEncoding.DecodeString(key.KDFSalt)
+	if err != nil {
+		return nil, err
+	}
+	wrap, err := passphraseCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := wrap.Decrypt(key.PrivateKeyEnc)
+	if err != nil {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrUnauthorized, "wrong key passphrase")
+	}
+	return decodePrivateKey(encoded)
+}
+
+func passphraseCipher(passphrase string, salt []byte) (*CryptoService, error) {
+	return newCryptoService(argon2.IDKey([]byte(passphrase), salt, keyKDFTime, keyKDFMemory, keyKDFThreads, 32))
+}
+
+func decodePrivateKey(encoded string) (*ecdh.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.X25519().NewPrivateKey(raw)
+}
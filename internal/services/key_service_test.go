@@ -0,0 +1,181 @@
+package services
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+type keyTest struct {
+	keys   *KeyService
+	repo   *repository.KeyRepository
+	crypto *CryptoService
+}
+
+func newKeyTest(t *testing.T) *keyTest {
+	t.Helper()
+	crypto := newTestCrypto(t)
+	repo := repository.NewKeyRepository(newTestDB(t))
+	return &keyTest{keys: NewKeyService(repo, crypto, time.Hour, 5*time.Minute), repo: repo, crypto: crypto}
+}
+
+// restart returns the service a new server process would have: same
+// database and server key, nothing unlocked.
+func (k *keyTest) restart() *KeyService {
+	return NewKeyService(k.repo, k.crypto, time.Hour, 5*time.Minute)
+}
+
+func TestKeyPassphraseGuardsSealedData(t *testing.T) {
+	k := newKeyTest(t)
+	if _, err := k.keys.SealFor(1, []byte("entry key")); vaultErrorCode(err) != vaulterrors.ErrInvalidInput {
+		t.Fatalf("sealed to a user without a key pair: %v", err)
+	}
+
+	if err := k.keys.SetPassphrase(1, "", "correct horse", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.keys.SealFor(1, []byte("entry key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := k.keys.OpenFor(1, sealed); err != nil || string(opened) != "entry key" {
+		t.Fatalf("open after setting the passphrase: %q, %v", opened, err)
+	}
+
+	// A restarted server holds the database and server key but cannot open
+	// the share until the user unlocks again
+	restarted := k.restart()
+	if _, err := restarted.OpenFor(1, sealed); vaultErrorCode(err) != vaulterrors.ErrKeyLocked {
+		t.Fatalf("open while locked: got %v, want key locked", err)
+	}
+	if err := restarted.Unlock(1, "wrong horse"); vaultErrorCode(err) != vaulterrors.ErrUnauthorized {
+		t.Fatalf("unlock with the wrong passphrase: %v", err)
+	}
+	if err := restarted.Unlock(1, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := restarted.OpenFor(1, sealed); err != nil || string(opened) != "entry key" {
+		t.Fatalf("open after unlock: %q, %v", opened, err)
+	}
+
+	restarted.Lock(1)
+	if _, err := restarted.OpenFor(1, sealed); vaultErrorCode(err) != vaulterrors.ErrKeyLocked {
+		t.Fatalf("open after lock: got %v, want key locked", err)
+	}
+}
+
+func TestKeyPairIsNotWrappedWithServerKey(t *testing.T) {
+	k := newKeyTest(t)
+	if err := k.keys.SetPassphrase(1, "", "correct horse", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := k.repo.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.KDFSalt == "" {
+		t.Fatal("no passphrase salt stored")
+	}
+	if _, err := k.keys.openLegacy(stored); err == nil {
+		t.Fatal("private key opens with the server-derived key")
+	}
+}
+
+func TestKeyPassphraseChange(t *testing.T) {
+	k := newKeyTest(t)
+	if err := k.keys.SetPassphrase(1, "", "correct horse", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.keys.SealFor(1, []byte("entry key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := k.keys.SetPassphrase(1, "wrong horse", "battery staple", time.Now()); vaultErrorCode(err) != vaulterrors.ErrUnauthorized {
+		t.Fatalf("change with the wrong current passphrase: %v", err)
+	}
+	if err := k.keys.SetPassphrase(1, "correct horse", "battery staple", time.Now().Add(-time.Hour)); vaultErrorCode(err) != vaulterrors.ErrReauthRequired {
+		t.Fatalf("change with a stale login: %v", err)
+	}
+	if err := k.keys.SetPassphrase(1, "correct horse", "short", time.Now()); vaultErrorCode(err) != vaulterrors.ErrInvalidInput {
+		t.Fatalf("short passphrase: %v", err)
+	}
+	if err := k.keys.SetPassphrase(1, "correct horse", "battery staple", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pair is the same, so earlier shares still open
+	restarted := k.restart()
+	if err := restarted.Unlock(1, "correct horse"); err == nil {
+		t.Fatal("old passphrase still unlocks")
+	}
+	if err := restarted.Unlock(1, "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := restarted.OpenFor(1, sealed); err != nil || string(opened) != "entry key" {
+		t.Fatalf("open after passphrase change: %q, %v", opened, err)
+	}
+}
+
+func TestLegacyKeyPairIsRewrapped(t *testing.T) {
+	k := newKeyTest(t)
+	// A pair stored before key passphrases, wrapped with the server key
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrap, err := k.crypto.Derive("user-key:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyEnc, err := wrap.Encrypt(base64.StdEncoding.EncodeToString(priv.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.repo.CreateIfMissing(models.UserKey{
+		UserID:        1,
+		PublicKey:     base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()),
+		PrivateKeyEnc: legacyEnc,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.keys.SealFor(1, []byte("entry key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Legacy pairs are not opened for reads, only rewrapped
+	if _, err := k.keys.OpenFor(1, sealed); vaultErrorCode(err) != vaulterrors.ErrKeyLocked {
+		t.Fatalf("open with a legacy pair: %v", err)
+	}
+	if err := k.keys.Unlock(1, "anything"); vaultErrorCode(err) != vaulterrors.ErrInvalidInput {
+		t.Fatalf("unlock a legacy pair: %v", err)
+	}
+	if err := k.keys.SetPassphrase(1, "", "correct horse", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := k.repo.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.PublicKey != base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()) {
+		t.Fatal("rewrapping replaced the key pair")
+	}
+	if _, err := k.keys.openLegacy(stored); err == nil {
+		t.Fatal("rewrapped key still opens with the server-derived key")
+	}
+	restarted := k.restart()
+	if err := restarted.Unlock(1, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := restarted.OpenFor(1, sealed); err != nil || string(opened) != "entry key" {
+		t.Fatalf("open a share sealed to the legacy pair: %q, %v", opened, err)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+type LDAPConfig struct {
+	URL      string
+	StartTLS bool
+	// BindDN and BindPassword identify a service account used to look users
+	// up. When empty, UserDNTemplate is used to bind as the user directly.
+	BindDN         string
+	BindPassword   string
+	UserDNTemplate string
+	BaseDN         string
+	UserFilter     string
+	EmailAttribute string
+	// RequiredGroup is the DN of a group the user must belong to, if set.
+	RequiredGroup string
+	// LinkExistingUsers allows users without a pinned backend to be matched
+	// by email; set when the directory is the global default backend.
+	LinkExistingUsers bool
+}
+
+// LDAPAuthenticator authenticates by binding to a directory as the user.
+type LDAPAuthenticator struct {
+	cfg   LDAPConfig
+	users *repository.UserRepository
+	audit *AuditService
+}
+
+func NewLDAPAuthenticator(cfg LDAPConfig, users *repository.UserRepository, audit *AuditService) *LDAPAuthenticator {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	return &LDAPAuthenticator{cfg: cfg, users: users, audit: audit}
+}
+
+func (a *LDAPAuthenticator) Authenticate(login, password string) (*models.User, error) {
+	// An empty password would be an unauthenticated bind, which many servers accept
+	if login == "" || password == "" {
+		return nil, errInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := a.bindAsUser(conn, login, password)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(entry.GetAttributeValue(a.cfg.EmailAttribute)))
+	if email == "" {
+		return nil, fmt.Errorf("directory entry has no %s attribute", a.cfg.EmailAttribute)
+	}
+
+	if a.cfg.RequiredGroup != "" {
+		member, err := a.isMember(conn, entry, login)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, errInvalidCredentials
+		}
+	}
+
+	return a.resolveUser(entry.DN, email)
+}
+
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial: %w", err)
+	}
+	conn.SetTimeout(10 * time.Second)
+
+	if a.cfg.StartTLS {
+		host := a.cfg.URL
+		if u, err := url.Parse(a.cfg.URL); err == nil {
+			host = u.Hostname()
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// bindAsUser proves the password by binding as the user, then returns the
+// user's directory entry.
+func (a *LDAPAuthenticator) bindAsUser(conn *ldap.Conn, login, password string) (*ldap.Entry, error) {
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind: %w", err)
+		}
+		entry, err := a.findUser(conn, login)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.Bind(entry.DN, password); err != nil {
+			return nil, errInvalidCredentials
+		}
+		return entry, nil
+	}
+
+	dn := fmt.Sprintf(a.cfg.UserDNTemplate, ldap.EscapeDN(login))
+	if err := conn.Bind(dn, password); err != nil {
+		return nil, errInvalidCredentials
+	}
+	return a.findUser(conn, login)
+}
+
+func (a *LDAPAuthenticator) findUser(conn *ldap.Conn, login string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		10,
+		false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(login)),
+		[]string{a.cfg.EmailAttribute, "memberOf"},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("ldap search: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, errInvalidCredentials
+	}
+	return res.Entries[0], nil
+}
+
+// isMember checks memberOf first and falls back to searching the group
+// entry for servers that do not maintain memberOf.
+func (a *LDAPAuthenticator) isMember(conn *ldap.Conn, entry *ldap.Entry, login string) (bool, error) {
+	for _, group := range entry.GetAttributeValues("memberOf") {
+		if strings.EqualFold(group, a.cfg.RequiredGroup) {
+			return true, nil
+		}
+	}
+
+	filter := fmt.Sprintf(
+		"(|(member=%s)(uniqueMember=%s)(memberUid=%s))",
+		ldap.EscapeFilter(entry.DN),
+		ldap.EscapeFilter(entry.DN),
+		ldap.EscapeFilter(login),
+	)
+	req := ldap.NewSearchRequest(
+		a.cfg.RequiredGroup,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		1,
+		10,
+		false,
+		filter,
+		[]string{"dn"},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return false, nil
+		}
+		return false, fmt.Errorf("ldap group search: %w", err)
+	}
+	return len(res.Entries) > 0, nil
+}
+
+// resolveUser maps a directory entry to a local user, provisioning one on
+// first login.
+func (a *LDAPAuthenticator) resolveUser(dn, email string) (*models.User, error) {
+	user, err := a.users.GetByIdentity(AuthBackendLDAP, dn)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	user, err = a.users.GetByEmail(email)
+	switch {
+	case err == nil:
+		linkable := user.AuthBackend == AuthBackendLDAP || (user.AuthBackend == "" && a.cfg.LinkExistingUsers)
+		if !linkable {
+			return nil, errors.New("an account with this email already exists")
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		id, err := a.users.Create(email, "")
+		if err != nil {
+			return nil, err
+		}
+		if err := a.users.SetAuthBackend(id, AuthBackendLDAP); err != nil {
+			return nil, err
+		}
+		if user, err = a.users.GetByID(id); err != nil {
+			return nil, err
+		}
+		a.audit.LogEvent(user.ID, 0, "ldap.provisioned")
+	default:
+		return nil, err
+	}
+
+	if err := a.users.LinkIdentity(user.ID, AuthBackendLDAP, dn); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vault/internal/repository"
+)
+
+// Directory layout of testdata/glauth.cfg
+const (
+	glauthBaseDN      = "dc=glauth,dc=com"
+	glauthServiceDN   = "cn=serviceuser,ou=svcaccts," + glauthBaseDN
+	glauthHeroesGroup = "ou=superheros,ou=groups," + glauthBaseDN
+)
+
+// glauthURL returns the URL of a glauth server loaded with
+// testdata/glauth.cfg: GLAUTH_URL when set, otherwise a server started from
+// GLAUTH_BIN or a glauth binary on PATH. Without either the test is skipped.
+func glauthURL(t *testing.T) string {
+	t.Helper()
+	if url := os.Getenv("GLAUTH_URL"); url != "" {
+		return url
+	}
+	bin := os.Getenv("GLAUTH_BIN")
+	if bin == "" {
+		var err error
+		if bin, err = exec.LookPath("glauth"); err != nil {
+			t.Skip("glauth is not available; set GLAUTH_URL or GLAUTH_BIN")
+		}
+	}
+
+	cfg, err := os.ReadFile("testdata/glauth.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	cfgPath := filepath.Join(t.TempDir(), "glauth.cfg")
+	cfg = bytes.Replace(cfg, []byte("127.0.0.1:3893"), []byte(addr), 1)
+	if err := os.WriteFile(cfgPath, cfg, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(bin, "-c", cfgPath)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return "ldap://" + addr
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("glauth did not start: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// newLDAPTest returns an authenticator for the glauth server with cfg and
+// the user repository it provisions into.
+func newLDAPTest(t *testing.T, cfg LDAPConfig) (*LDAPAuthenticator, *repository.UserRepository) {
+	t.Helper()
+	cfg.URL = glauthURL(t)
+	if cfg.BaseDN == "" {
+		cfg.BaseDN = glauthBaseDN
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(cn=%s)"
+	}
+	database := newTestDB(t)
+	users := repository.NewUserRepository(database)
+	return NewLDAPAuthenticator(cfg, users, newTestAudit(t, database)), users
+}
+
+func serviceAccount() LDAPConfig {
+	return LDAPConfig{BindDN: glauthServiceDN, BindPassword: "mysecret"}
+}
+
+func TestLDAPServiceAccountProvisionsUser(t *testing.T) {
+	ldap, _ := newLDAPTest(t, serviceAccount())
+
+	user, err := ldap.Authenticate("hackers", "dogood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "hackers@example.com" || user.AuthBackend != AuthBackendLDAP {
+		t.Fatalf("provisioned %s with backend %q", user.Email, user.AuthBackend)
+	}
+
+	again, err := ldap.Authenticate("hackers", "dogood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("second login mapped to user %d, want %d", again.ID, user.ID)
+	}
+}
+
+func TestLDAPRejectsBadCredentials(t *testing.T) {
+	ldap, _ := newLDAPTest(t, serviceAccount())
+
+	for _, tc := range []struct{ login, password string }{
+		{"hackers", "wrong"},
+		{"hackers", ""},
+		{"nobody", "dogood"},
+	} {
+		if _, err := ldap.Authenticate(tc.login, tc.password); !errors.Is(err, errInvalidCredentials) {
+			t.Errorf("%s/%q: got %v, want invalid credentials", tc.login, tc.password, err)
+		}
+	}
+}
+
+func TestLDAPUserDNTemplate(t *testing.T) {
+	ldap, _ := newLDAPTest(t, LDAPConfig{UserDNTemplate: "cn=%s,ou=superheros," + glauthBaseDN})
+
+	user, err := ldap.Authenticate("hackers", "dogood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "hackers@example.com" {
+		t.Fatalf("got %s", user.Email)
+	}
+	if _, err := ldap.Authenticate("hackers", "wrong"); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("wrong password: got %v", err)
+	}
+}
+
+func TestLDAPRequiredGroup(t *testing.T) {
+	cfg := serviceAccount()
+	cfg.RequiredGroup = glauthHeroesGroup
+	ldap, _ := newLDAPTest(t, cfg)
+
+	if _, err := ldap.Authenticate("hackers", "dogood"); err != nil {
+		t.Fatalf("group member: %v", err)
+	}
+	if _, err := ldap.Authenticate("johndoe", "johnpass"); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("non-member: got %v, want invalid credentials", err)
+	}
+}
+
+func TestLDAPLinksExistingUsersOnlyWhenAllowed(t *testing.T) {
+	ldap, users := newLDAPTest(t, serviceAccount())
+	id, err := users.Create("hackers@example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ldap.Authenticate("hackers", "dogood"); err == nil {
+		t.Fatal("linked a local account without LinkExistingUsers")
+	}
+
+	ldap.cfg.LinkExistingUsers = true
+	user, err := ldap.Authenticate("hackers", "dogood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != id {
+		t.Fatalf("linked to user %d, want %d", user.ID, id)
+	}
+}
+
+func TestLDAPBackendPinnedByAdmin(t *testing.T) {
+	cfg := serviceAccount()
+	cfg.UserFilter = "(mail=%s)"
+	ldap, users := newLDAPTest(t, cfg)
+	auth := NewAuthService(users, "test-secret", time.Hour)
+	auth.AddBackend(AuthBackendLDAP, ldap)
+	admin := NewAdminService(users, nil, ldap.audit)
+
+	id, err := auth.Register("hackers@example.com", "local-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := auth.Login("hackers@example.com", "dogood", nil); err == nil {
+		t.Fatal("directory password accepted before pinning to ldap")
+	}
+
+	if err := admin.SetAuthBackend(1, id, AuthBackendLDAP); err != nil {
+		t.Fatal(err)
+	}
+	_, user, err := auth.Login("hackers@example.com", "dogood", nil)
+	if err != nil {
+		t.Fatalf("ldap login: %v", err)
+	}
+	if user.ID != id {
+		t.Fatalf("logged in as user %d, want %d", user.ID, id)
+	}
+	if _, _, err := auth.Login("hackers@example.com", "local-password", nil); err == nil {
+		t.Fatal("local password accepted while pinned to ldap")
+	}
+
+	if err := admin.SetAuthBackend(1, id, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := auth.Login("hackers@example.com", "local-password", nil); err != nil {
+		t.Fatalf("local login after unpinning: %v", err)
+	}
+	if err := admin.SetAuthBackend(1, id, "kerberos"); err == nil {
+		t.Fatal("accepted an unknown backend")
+	}
+}
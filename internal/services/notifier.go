@@ -0,0 +1,61 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notification tells a user something happened that may need their action.
+type Notification struct {
+	Event  string         `json:"event"`
+	UserID int64          `json:"userId"`
+	Email  string         `json:"email,omitempty"`
+	Data   map[string]any `json:"data,omitempty"`
+	SentAt time.Time      `json:"sentAt"`
+}
+
+// Notifier delivers notifications. Implementations must not block the caller.
+type Notifier interface {
+	Notify(n Notification)
+}
+
+// LogNotifier prints notifications; it is the default when no webhook is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(n Notification) {
+	fmt.Printf("[NOTIFY] %s for user %d (%s): %v\n", n.Event, n.UserID, n.Email, n.Data)
+}
+
+// WebhookNotifier posts each notification as JSON to a URL, e.g. a mail or
+// chat relay.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *WebhookNotifier) Notify(n Notification) {
+	go func() {
+		body, err := json.Marshal(n)
+		if err != nil {
+			log.Printf("notify: %v", err)
+			return
+		}
+		resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("notify %s: %v", n.Event, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("notify %s: webhook returned %s", n.Event, resp.Status)
+		}
+	}()
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// AllowedDomains restricts which email domains may sign in; empty allows any.
+	AllowedDomains []string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcPending struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// OIDCService implements the OpenID Connect authorization code flow with
+// PKCE against a single external identity provider.
+type OIDCService struct {
+	cfg    OIDCConfig
+	users  *repository.UserRepository
+	auth   *AuthService
+	audit  *AuditService
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]any
+	pending   map[string]oidcPending
+}
+
+func NewOIDCService(cfg OIDCConfig, users *repository.UserRepository, auth *AuthService, audit *AuditService) *OIDCService {
+	for i, domain := range cfg.AllowedDomains {
+		cfg.AllowedDomains[i] = strings.ToLower(domain)
+	}
+	return &OIDCService{
+		cfg:     cfg,
+		users:   users,
+		auth:    auth,
+		audit:   audit,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		keys:    map[string]any{},
+		pending: map[string]oidcPending{},
+	}
+}
+
+// LoginURL starts a login: it remembers a fresh state, nonce and PKCE
+// verifier and returns the provider URL to redirect the browser to along
+// with the state, which the caller must bind to that browser.
+func (s *OIDCService) LoginURL() (string, string, error) {
+	disc, err := s.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomURLToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomURLToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomURLToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	for key, p := range s.pending {
+		if now.After(p.expiresAt) {
+			delete(s.pending, key)
+		}
+	}
+	s.pending[state] = oidcPending{nonce: nonce, verifier: verifier, expiresAt: now.Add(oidcStateTTL)}
+	s.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.cfg.ClientID},
+		"redirect_uri":          {s.cfg.RedirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(disc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return disc.AuthorizationEndpoint + sep + query.Encode(), state, nil
+}
+
+// Callback completes a login started by LoginURL, provisioning a local user
+// on first sight, and returns a vault JWT. boundState is the state the
+// browser kept from LoginURL; a callback carrying someone else's state is
+// refused so a login cannot be planted in another browser.
+func (s *OIDCService) Callback(state, boundState, code string) (string, *models.User, error) {
+	if state == "" || code == "" {
+		return "", nil, errors.New("state and code required")
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(boundState)) != 1 {
+		return "", nil, errors.New("login state does not belong to this browser")
+	}
+
+	s.mu.Lock()
+	pending, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if !ok || time.Now().After(pending.expiresAt) {
+		return "", nil, errors.New("invalid or expired login state")
+	}
+
+	disc, err := s.discover()
+	if err != nil {
+		return "", nil, err
+	}
+
+	rawIDToken, err := s.exchangeCode(disc, code, pending.verifier)
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims, err := s.verifyIDToken(disc, rawIDToken, pending.nonce)
+	if err != nil {
+		return "", nil, err
+	}
+
+	user, err := s.resolveUser(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := s.auth.IssueToken(user, AllScopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.audit.LogEvent(user.ID, 0, "oidc.login")
+	return token, user, nil
+}
+
+func (s *OIDCService) discover() (*oidcDiscovery, error) {
+	s.mu.Lock()
+	cached := s.discovery
+	s.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var disc oidcDiscovery
+	if err := s.getJSON(s.cfg.Issuer+"/.well-known/openid-configuration", &disc); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != s.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q", disc.Issuer)
+	}
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: incomplete provider metadata")
+	}
+
+	s.mu.Lock()
+	s.discovery = &disc
+	s.mu.Unlock()
+	return &disc, nil
+}
+
+func (s *OIDCService) exchangeCode(disc *oidcDiscovery, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.cfg.RedirectURL},
+		"client_id":     {s.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, disc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc token exchange: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc token exchange: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc token exchange: no id_token in response")
+	}
+	return body.IDToken, nil
+}
+
+func (s *OIDCService) verifyIDToken(disc *oidcDiscovery, raw, nonce string) (*oidcClaims, error) {
+	var claims oidcClaims
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384"}))
+	_, err := parser.ParseWithClaims(raw, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.signingKey(disc, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if !claims.VerifyIssuer(disc.Issuer, true) {
+		return nil, errors.New("invalid id_token: wrong issuer")
+	}
+	if !claims.VerifyAudience(s.cfg.ClientID, true) {
+		return nil, errors.New("invalid id_token: wrong audience")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id_token: missing subject")
+	}
+	return &claims, nil
+}
+
+// signingKey returns the provider key for kid, refetching the JWKS once when
+// the key is unknown so provider key rotation is picked up.
+func (s *OIDCService) signingKey(disc *oidcDiscovery, kid string) (any, error) {
+	if key := s.cachedKey(kid); key != nil {
+		return key, nil
+	}
+	if err := s.refreshKeys(disc); err != nil {
+		return nil, err
+	}
+	if key := s.cachedKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *OIDCService) cachedKey(kid string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
+
+func (s *OIDCService) refreshKeys(disc *oidcDiscovery) error {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := s.getJSON(disc.JWKSURI, &set); err != nil {
+		return fmt.Errorf("oidc jwks: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := decodeBigInt(k.N)
+			e, errE := decodeBigInt(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			default:
+				continue
+			}
+			x, errX := decodeBigInt(k.X)
+			y, errY := decodeBigInt(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *OIDCService) resolveUser(claims *oidcClaims) (*models.User, error) {
+	provider := s.cfg.Issuer
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+
+	if len(s.cfg.AllowedDomains) > 0 && !s.domainAllowed(email) {
+		return nil, errors.New("email domain not allowed")
+	}
+
+	user, err := s.users.GetByIdentity(provider, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// First login with this subject: link or provision by verified email
+	if email == "" || !claims.EmailVerified {
+		return nil, errors.New("identity provider did not return a verified email")
+	}
+
+	user, err = s.users.GetByEmail(email)
+	switch {
+	case err == nil:
+		// Linking an existing password account is only safe when the domain
+		// is reserved for SSO, otherwise anyone could pre-register the email.
+		if len(s.cfg.AllowedDomains) == 0 {
+			return nil, errors.New("an account with this email already exists")
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		// Just-in-time provisioning; an empty hash never matches a password
+		id, err := s.users.Create(email, "")
+		if err != nil {
+			return nil, err
+		}
+		if user, err = s.users.GetByID(id); err != nil {
+			return nil, err
+		}
+		s.audit.LogEvent(user.ID, 0, "oidc.provisioned")
+	default:
+		return nil, err
+	}
+
+	if err := s.users.LinkIdentity(user.ID, provider, claims.Subject); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// domainAllowed reports whether email belongs to one of the allowed domains.
+func (s *OIDCService) domainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range s.cfg.AllowedDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *OIDCService) getJSON(target string, dest any) error {
+	resp, err := s.client.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+func randomURLToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"vault/internal/repository"
+)
+
+const (
+	testClientID     = "vault"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "http://localhost:8080/api/auth/oidc/callback"
+)
+
+// mockIdP is an OpenID provider serving discovery, a JWKS and a token
+// endpoint that checks PKCE. Tests sign in through authorize instead of a
+// browser.
+type mockIdP struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	claims    jwt.MapClaims
+	challenge string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	idp := &mockIdP{t: t, codes: map[string]mockGrant{}}
+	idp.rotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		key, kid := idp.key.PublicKey, idp.kid
+		idp.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// rotateKey replaces the signing key under a new kid.
+func (idp *mockIdP) rotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.key = key
+	idp.kid = randomKid(idp.t)
+}
+
+func randomKid(t *testing.T) string {
+	kid, err := randomURLToken(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kid
+}
+
+// authorize plays the provider's login page for loginURL: it checks the
+// request and returns the state and a code for an ID token with claims.
+// Standard claims the test does not set are filled in.
+func (idp *mockIdP) authorize(loginURL string, claims jwt.MapClaims) (string, string) {
+	idp.t.Helper()
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	q := u.Query()
+	if got := u.Scheme + "://" + u.Host + u.Path; got != idp.URL+"/authorize" {
+		idp.t.Fatalf("login URL points at %s", got)
+	}
+	for param, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"code_challenge_method": "S256",
+	} {
+		if q.Get(param) != want {
+			idp.t.Fatalf("%s = %q, want %q", param, q.Get(param), want)
+		}
+	}
+	if !strings.Contains(q.Get("scope"), "openid") {
+		idp.t.Fatalf("scope %q lacks openid", q.Get("scope"))
+	}
+
+	defaults := jwt.MapClaims{
+		"iss":            idp.URL,
+		"aud":            testClientID,
+		"sub":            "subject-1",
+		"email":          "sso@example.com",
+		"email_verified": true,
+		"nonce":          q.Get("nonce"),
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+	for name, value := range claims {
+		defaults[name] = value
+	}
+
+	code := randomKid(idp.t)
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{claims: defaults, challenge: q.Get("code_challenge")}
+	idp.mu.Unlock()
+	return q.Get("state"), code
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if r.Method != http.MethodPost || id != testClientID || secret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != testRedirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	key, kid := idp.key, idp.kid
+	idp.mu.Unlock()
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "unused", "token_type": "Bearer", "id_token": signed})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+type oidcTest struct {
+	idp   *mockIdP
+	oidc  *OIDCService
+	auth  *AuthService
+	users *repository.UserRepository
+}
+
+func newOIDCTest(t *testing.T, allowedDomains ...string) *oidcTest {
+	t.Helper()
+	idp := newMockIdP(t)
+	database := newTestDB(t)
+	users := repository.NewUserRepository(database)
+	auth := NewAuthService(users, "test-secret", time.Hour)
+	svc := NewOIDCService(OIDCConfig{
+		Issuer:         idp.URL,
+		ClientID:       testClientID,
+		ClientSecret:   testClientSecret,
+		RedirectURL:    testRedirectURL,
+		AllowedDomains: allowedDomains,
+	}, users, auth, newTestAudit(t, database))
+	return &oidcTest{idp: idp, oidc: svc, auth: auth, users: users}
+}
+
+// signIn runs a whole login through the mock provider with claims.
+func (o *oidcTest) signIn(t *testing.T, claims jwt.MapClaims) (int64, error) {
+	t.Helper()
+	loginURL, _, err := o.oidc.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := o.idp.authorize(loginURL, claims)
+	token, user, err := o.oidc.Callback(state, state, code)
+	if err != nil {
+		return 0, err
+	}
+	if token == "" {
+		t.Fatal("no token issued")
+	}
+	return user.ID, nil
+}
+
+func TestOIDCProvisionsAndRecognizesUser(t *testing.T) {
+	o := newOIDCTest(t)
+
+	id, err := o.signIn(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := o.users.GetByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "sso@example.com" {
+		t.Fatalf("provisioned %s", user.Email)
+	}
+
+	// The subject, not the email, identifies the user from then on
+	again, err := o.signIn(t, jwt.MapClaims{"email": "renamed@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != id {
+		t.Fatalf("second login mapped to user %d, want %d", again, id)
+	}
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	for name, claims := range map[string]jwt.MapClaims{
+		"nonce":      {"nonce": "not-the-nonce"},
+		"audience":   {"aud": "another-client"},
+		"issuer":     {"iss": "https://evil.example"},
+		"expired":    {"exp": time.Now().Add(-time.Minute).Unix()},
+		"subject":    {"sub": ""},
+		"unverified": {"email_verified": false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			o := newOIDCTest(t)
+			if _, err := o.signIn(t, claims); err == nil {
+				t.Fatal("login accepted")
+			}
+		})
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	o := newOIDCTest(t)
+	loginURL, _, err := o.oidc.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := o.idp.authorize(loginURL, nil)
+	if _, _, err := o.oidc.Callback(state, state, code); err != nil {
+		t.Fatal(err)
+	}
+	_, code = o.idp.authorize(loginURL, nil)
+	if _, _, err := o.oidc.Callback(state, state, code); err == nil {
+		t.Fatal("state accepted twice")
+	}
+	if _, _, err := o.oidc.Callback("unknown", "unknown", code); err == nil {
+		t.Fatal("unknown state accepted")
+	}
+}
+
+func TestOIDCRejectsStateFromAnotherBrowser(t *testing.T) {
+	o := newOIDCTest(t)
+	// The attacker starts a login and hands the victim their code and state
+	attackerURL, attackerState, err := o.oidc.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := o.idp.authorize(attackerURL, jwt.MapClaims{"sub": "attacker", "email": "attacker@example.com"})
+	if state != attackerState {
+		t.Fatalf("provider echoed state %q, want %q", state, attackerState)
+	}
+
+	_, victimState, err := o.oidc.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := o.oidc.Callback(state, victimState, code); err == nil {
+		t.Fatal("callback accepted a state bound to another browser")
+	}
+	if _, _, err := o.oidc.Callback(state, "", code); err == nil {
+		t.Fatal("callback accepted without a bound state")
+	}
+}
+
+func TestOIDCRequiresPKCEVerifier(t *testing.T) {
+	o := newOIDCTest(t)
+	loginURL, _, err := o.oidc.LoginURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := o.idp.authorize(loginURL, nil)
+	// An attacker who intercepted the code cannot know the verifier
+	o.idp.mu.Lock()
+	grant := o.idp.codes[code]
+	grant.challenge = "tampered"
+	o.idp.codes[code] = grant
+	o.idp.mu.Unlock()
+
+	if _, _, err := o.oidc.Callback(state, state, code); err == nil {
+		t.Fatal("code redeemed without the matching verifier")
+	}
+}
+
+func TestOIDCAllowedDomains(t *testing.T) {
+	o := newOIDCTest(t, "Example.com")
+	if _, err := o.signIn(t, jwt.MapClaims{"sub": "a", "email": "a@example.com"}); err != nil {
+		t.Fatalf("allowed domain: %v", err)
+	}
+	if _, err := o.signIn(t, jwt.MapClaims{"sub": "b", "email": "b@other.com"}); err == nil {
+		t.Fatal("login from a domain that is not allowed")
+	}
+}
+
+func TestOIDCLinksExistingAccountOnlyForReservedDomains(t *testing.T) {
+	o := newOIDCTest(t)
+	if _, err := o.auth.Register("sso@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.signIn(t, nil); err == nil {
+		t.Fatal("linked a password account without domain restrictions")
+	}
+
+	o = newOIDCTest(t, "example.com")
+	id, err := o.auth.Register("sso@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := o.signIn(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked != id {
+		t.Fatalf("linked to user %d, want %d", linked, id)
+	}
+}
+
+func TestOIDCFollowsKeyRotation(t *testing.T) {
+	o := newOIDCTest(t)
+	if _, err := o.signIn(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	o.idp.rotateKey()
+	if _, err := o.signIn(t, nil); err != nil {
+		t.Fatalf("after key rotation: %v", err)
+	}
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+// orgRoleRank orders membership roles so checks can ask for "at least" a role.
+var orgRoleRank = map[string]int{
+	models.OrgRoleReadOnly: 1,
+	models.OrgRoleMember:   2,
+	models.OrgRoleAdmin:    3,
+	models.OrgRoleOwner:    4,
+}
+
+// OrgService manages organizations, their members, teams and collections.
+// Access to the entries inside collections is enforced by VaultRepository.
+type OrgService struct {
+	orgs  *repository.OrgRepository
+	users *repository.UserRepository
+	audit *AuditService
+}
+
+func NewOrgService(orgs *repository.OrgRepository, users *repository.UserRepository, audit *AuditService) *OrgService {
+	return &OrgService{orgs: orgs, users: users, audit: audit}
+}
+
+func errOrgNotFound() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "organization not found")
+}
+
+func errOrgForbidden(message string) error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrForbidden, message)
+}
+
+// requireRole returns the caller's role, failing unless it is at least min.
+// Non-members get not found so organization ids cannot be probed.
+func (s *OrgService) requireRole(orgID, userID int64, min string) (string, error) {
+	role, err := s.orgs.MemberRole(orgID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errOrgNotFound()
+	}
+	if err != nil {
+		return "", err
+	}
+	if orgRoleRank[role] < orgRoleRank[min] {
+		return "", errOrgForbidden(min + " role required")
+	}
+	return role, nil
+}
+
+func (s *OrgService) Create(userID int64, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "name required")
+	}
+	orgID, err := s.orgs.Create(name, userID)
+	if err != nil {
+		return 0, err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.create", name)
+	return orgID, nil
+}
+
+func (s *OrgService) List(userID int64) ([]models.Organization, error) {
+	return s.orgs.ListByUser(userID)
+}
+
+func (s *OrgService) Get(userID, orgID int64) (*models.Organization, error) {
+	role, err := s.requireRole(orgID, userID, models.OrgRoleReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgs.GetByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	org.Role = role
+	return org, nil
+}
+
+func (s *OrgService) Rename(userID, orgID int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "name required")
+	}
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	if err := s.orgs.Rename(orgID, name); err != nil {
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.rename", name)
+	return nil
+}
+
+// Delete removes the organization and every entry in its collections.
+func (s *OrgService) Delete(userID, orgID int64) error {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleOwner); err != nil {
+		return err
+	}
+	if err := s.orgs.Delete(orgID); err != nil {
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.delete", "")
+	return nil
+}
+
+func (s *OrgService) Members(userID, orgID int64) ([]models.OrgMember, error) {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleReadOnly); err != nil {
+		return nil, err
+	}
+	return s.orgs.ListMembers(orgID)
+}
+
+// AddMember adds an existing user by email. Admins may add members and
+// read-only users; only owners may hand out the admin or owner roles.
+func (s *OrgService) AddMember(userID, orgID int64, email, role string) (int64, error) {
+	if _, ok := orgRoleRank[role]; !ok {
+		return 0, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "unknown role "+role)
+	}
+	callerRole, err := s.requireRole(orgID, userID, models.OrgRoleAdmin)
+	if err != nil {
+		return 0, err
+	}
+	if err := canAssign(callerRole, role); err != nil {
+		return 0, err
+	}
+
+	user, err := s.users.GetByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "user not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.orgs.MemberRole(orgID, user.ID); err == nil {
+		return 0, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "user is already a member")
+	}
+
+	if err := s.orgs.AddMember(orgID, user.ID, role); err != nil {
+		return 0, err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.member_add", fmt.Sprintf("user=%d role=%s", user.ID, role))
+	return user.ID, nil
+}
+
+func (s *OrgService) SetMemberRole(userID, orgID, memberID int64, role string) error {
+	if _, ok := orgRoleRank[role]; !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "unknown role "+role)
+	}
+	callerRole, err := s.requireRole(orgID, userID, models.OrgRoleAdmin)
+	if err != nil {
+		return err
+	}
+	current, err := s.memberRole(orgID, memberID)
+	if err != nil {
+		return err
+	}
+	// Changing an admin or owner needs the same privilege as granting it
+	if err := canAssign(callerRole, current); err != nil {
+		return err
+	}
+	if err := canAssign(callerRole, role); err != nil {
+		return err
+	}
+	if current == models.OrgRoleOwner && role != models.OrgRoleOwner {
+		if err := s.requireAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgs.SetMemberRole(orgID, memberID, role); err != nil {
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.member_role", fmt.Sprintf("user=%d role=%s", memberID, role))
+	return nil
+}
+
+// RemoveMember removes memberID; any member may remove themselves.
+func (s *OrgService) RemoveMember(userID, orgID, memberID int64) error {
+	minRole := models.OrgRoleAdmin
+	if userID == memberID {
+		minRole = models.OrgRoleReadOnly
+	}
+	callerRole, err := s.requireRole(orgID, userID, minRole)
+	if err != nil {
+		return err
+	}
+	current, err := s.memberRole(orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if userID != memberID {
+		if err := canAssign(callerRole, current); err != nil {
+			return err
+		}
+	}
+	if current == models.OrgRoleOwner {
+		if err := s.requireAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgs.RemoveMember(orgID, memberID); err != nil {
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.member_remove", fmt.Sprintf("user=%d", memberID))
+	return nil
+}
+
+func (s *OrgService) Teams(userID, orgID int64) ([]models.Team, error) {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleReadOnly); err != nil {
+		return nil, err
+	}
+	return s.orgs.ListTeams(orgID)
+}
+
+func (s *OrgService) CreateTeam(userID, orgID int64, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "name required")
+	}
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return 0, err
+	}
+	teamID, err := s.orgs.CreateTeam(orgID, name)
+	if err != nil {
+		return 0, err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.team_create", fmt.Sprintf("team=%d name=%s", teamID, name))
+	return teamID, nil
+}
+
+func (s *OrgService) DeleteTeam(userID, orgID, teamID int64) error {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	if err := s.orgs.DeleteTeam(orgID, teamID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "team not found")
+		}
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.team_delete", fmt.Sprintf("team=%d", teamID))
+	return nil
+}
+
+func (s *OrgService) AddTeamMember(userID, orgID, teamID, memberID int64) error {
+	if err := s.requireTeam(userID, orgID, teamID); err != nil {
+		return err
+	}
+	if _, err := s.memberRole(orgID, memberID); err != nil {
+		return err
+	}
+	if err := s.orgs.AddTeamMember(teamID, memberID); err != nil {
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.team_member_add", fmt.Sprintf("team=%d user=%d", teamID, memberID))
+	return nil
+}
+
+func (s *OrgService) RemoveTeamMember(userID, orgID, teamID, memberID int64) error {
+	if err := s.requireTeam(userID, orgID, teamID); err != nil {
+		return err
+	}
+	if err := s.orgs.RemoveTeamMember(teamID, memberID); err != nil {
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.team_member_remove", fmt.Sprintf("team=%d user=%d", teamID, memberID))
+	return nil
+}
+
+// Collections lists the organization's collections visible to the caller.
+func (s *OrgService) Collections(userID, orgID int64) ([]models.Collection, error) {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleReadOnly); err != nil {
+		return nil, err
+	}
+	return s.orgs.ListCollections(orgID, userID)
+}
+
+func (s *OrgService) CreateCollection(userID, orgID int64, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "name required")
+	}
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return 0, err
+	}
+	collectionID, err := s.orgs.CreateCollection(orgID, name)
+	if err != nil {
+		return 0, err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.collection_create", fmt.Sprintf("collection=%d name=%s", collectionID, name))
+	return collectionID, nil
+}
+
+func (s *OrgService) RenameCollection(userID, orgID, collectionID int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "name required")
+	}
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	if err := s.orgs.RenameCollection(orgID, collectionID, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "collection not found")
+		}
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.collection_rename", fmt.Sprintf("collection=%d name=%s", collectionID, name))
+	return nil
+}
+
+// DeleteCollection removes a collection together with its entries.
+func (s *OrgService) DeleteCollection(userID, orgID, collectionID int64) error {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	if err := s.orgs.DeleteCollection(orgID, collectionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "collection not found")
+		}
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.collection_delete", fmt.Sprintf("collection=%d", collectionID))
+	return nil
+}
+
+// SetCollectionTeams restricts a collection to the given teams; an empty
+// list opens it to every member again.
+func (s *OrgService) SetCollectionTeams(userID, orgID, collectionID int64, teamIDs []int64) error {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	ok, err := s.orgs.CollectionInOrg(orgID, collectionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "collection not found")
+	}
+	for _, teamID := range teamIDs {
+		ok, err := s.orgs.TeamInOrg(orgID, teamID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, fmt.Sprintf("team %d is not in this organization", teamID))
+		}
+	}
+
+	if err := s.orgs.SetCollectionTeams(collectionID, teamIDs); err != nil {
+		return err
+	}
+	s.audit.LogOrgEvent(userID, orgID, "org.collection_teams", fmt.Sprintf("collection=%d teams=%v", collectionID, teamIDs))
+	return nil
+}
+
+func (s *OrgService) requireTeam(userID, orgID, teamID int64) error {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	ok, err := s.orgs.TeamInOrg(orgID, teamID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "team not found")
+	}
+	return nil
+}
+
+func (s *OrgService) memberRole(orgID, memberID int64) (string, error) {
+	role, err := s.orgs.MemberRole(orgID, memberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "member not found")
+	}
+	return role, err
+}
+
+// requireAnotherOwner stops the last owner from leaving or being demoted.
+func (s *OrgService) requireAnotherOwner(orgID int64) error {
+	owners, err := s.orgs.CountOwners(orgID)
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "an organization needs at least one owner")
+	}
+	return nil
+}
+
+// canAssign allows owners to manage any role and admins only roles below admin.
+func canAssign(callerRole, role string) error {
+	if callerRole == models.OrgRoleOwner {
+		return nil
+	}
+	if orgRoleRank[role] >= orgRoleRank[models.OrgRoleAdmin] {
+		return errOrgForbidden("only owners can manage admins and owners")
+	}
+	return nil
+}
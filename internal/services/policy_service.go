@@ -0,0 +1,391 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const (
+	maxPolicyNameLength = 100
+	maxPolicyRules      = 100
+	// collectionPersonal selects entries outside any collection
+	collectionPersonal = "personal"
+)
+
+// policyCapabilities are the capabilities a rule may grant.
+var policyCapabilities = map[string]bool{
+	models.CapabilityList:   true,
+	models.CapabilityRead:   true,
+	models.CapabilityReveal: true,
+	models.CapabilityCreate: true,
+	models.CapabilityUpdate: true,
+	models.CapabilityDelete: true,
+	models.CapabilityDeny:   true,
+	"*":                     true,
+}
+
+// PolicyDecision is the outcome of evaluating a capability on an entry.
+type PolicyDecision struct {
+	Allowed    bool     `json:"allowed"`
+	Capability string   `json:"capability"`
+	Policies   []string `json:"policies"`
+	Reason     string   `json:"reason"`
+}
+
+// PolicySet holds the policies that apply to one caller. Policies narrow
+// what ownership and organization membership already allow: a caller with no
+// policies attached keeps that access unchanged. User policies come from the
+// user and their teams; an API token's own policies must allow the request
+// as well.
+type PolicySet struct {
+	user  []models.Policy
+	token []models.Policy
+	// labels looks up the folder path and tags of an entry for rules that
+	// select on them; without it entries have no folder and their own Tags
+	labels func(entry *models.VaultEntry) (folder string, tags []string, err error)
+}
+
+// Empty reports whether no policies are attached, so that every capability
+// is allowed.
+func (p *PolicySet) Empty() bool {
+	return len(p.user) == 0 && len(p.token) == 0
+}
+
+// Allows evaluates capability on entry against both halves of the set.
+func (p *PolicySet) Allows(capability string, entry *models.VaultEntry) PolicyDecision {
+	decision := PolicyDecision{Capability: capability, Policies: []string{}}
+	if p.Empty() {
+		decision.Allowed = true
+		decision.Reason = "no policies attached"
+		return decision
+	}
+
+	// Folder paths and tags are only looked up once a rule needs them
+	var attrs *entryAttributes
+	var attrsErr error
+	attributes := func() *entryAttributes {
+		if attrs == nil && attrsErr == nil {
+			attrs = &entryAttributes{tags: entry.Tags}
+			if p.labels != nil {
+				attrs.folder, attrs.tags, attrsErr = p.labels(entry)
+			}
+		}
+		return attrs
+	}
+
+	for _, half := range []struct {
+		subject  string
+		policies []models.Policy
+	}{{"user", p.user}, {"token", p.token}} {
+		if len(half.policies) == 0 {
+			continue
+		}
+		granted, denied, matched := evaluatePolicies(half.policies, capability, entry, attributes)
+		if attrsErr != nil {
+			decision.Policies = []string{}
+			decision.Reason = "could not load the entry's folder and tags"
+			return decision
+		}
+		decision.Policies = append(decision.Policies, matched...)
+		if denied {
+			decision.Reason = "denied by " + half.subject + " policy"
+			return decision
+		}
+		if !granted {
+			decision.Reason = "no " + half.subject + " policy grants " + capability
+			return decision
+		}
+	}
+	decision.Allowed = true
+	decision.Reason = "granted by policy"
+	return decision
+}
+
+// entryAttributes are what rules select on besides the entry's columns.
+type entryAttributes struct {
+	folder string
+	tags   []string
+}
+
+// evaluatePolicies unions the capabilities of every rule matching entry.
+// It returns the names of the policies whose rules matched.
+func evaluatePolicies(policies []models.Policy, capability string, entry *models.VaultEntry, attributes func() *entryAttributes) (granted, denied bool, matched []string) {
+	for _, policy := range policies {
+		hit := false
+		for _, rule := range policy.Rules {
+			if !ruleMatches(rule, entry, attributes) {
+				continue
+			}
+			hit = true
+			for _, c := range rule.Capabilities {
+				switch c {
+				case models.CapabilityDeny:
+					denied = true
+				case "*", capability:
+					granted = true
+				}
+			}
+		}
+		if hit {
+			matched = append(matched, policy.Name)
+		}
+	}
+	return granted, denied, matched
+}
+
+func ruleMatches(rule models.PolicyRule, entry *models.VaultEntry, attributes func() *entryAttributes) bool {
+	if rule.Category != "" {
+		if ok, _ := path.Match(rule.Category, entry.Category); !ok {
+			return false
+		}
+	}
+	if rule.Title != "" {
+		if ok, _ := path.Match(rule.Title, entry.Title); !ok {
+			return false
+		}
+	}
+	switch rule.Collection {
+	case "":
+	case collectionPersonal:
+		if entry.CollectionID != nil {
+			return false
+		}
+	default:
+		if entry.CollectionID == nil || strconv.FormatInt(*entry.CollectionID, 10) != rule.Collection {
+			return false
+		}
+	}
+	if rule.Folder != "" && !folderMatches(rule.Folder, attributes().folder) {
+		return false
+	}
+	if rule.Tag != "" && !tagMatches(rule.Tag, attributes().tags) {
+		return false
+	}
+	return true
+}
+
+// folderMatches reports whether pattern matches folder or one of the
+// folders above it, so "Work" covers everything filed under Work.
+func folderMatches(pattern, folder string) bool {
+	for folder != "" {
+		if ok, _ := path.Match(pattern, folder); ok {
+			return true
+		}
+		folder = path.Dir(folder)
+		if folder == "." {
+			folder = ""
+		}
+	}
+	return false
+}
+
+// tagMatches reports whether pattern matches one of tags. Tags are stored in
+// lower case, so the pattern is compared in lower case too.
+func tagMatches(pattern string, tags []string) bool {
+	pattern = strings.ToLower(pattern)
+	for _, tag := range tags {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyService manages access policies and evaluates them for VaultService.
+type PolicyService struct {
+	repo  *repository.PolicyRepository
+	audit *AuditService
+}
+
+func NewPolicyService(repo *repository.PolicyRepository, audit *AuditService) *PolicyService {
+	return &PolicyService{repo: repo, audit: audit}
+}
+
+func errPolicyNotFound() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "policy not found")
+}
+
+// Load returns the policies applying to userID, and to tokenID when the
+// caller authenticated with an API token.
+func (s *PolicyService) Load(userID, tokenID int64) (*PolicySet, error) {
+	user, err := s.repo.ForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	set := &PolicySet{user: user}
+	if tokenID != 0 {
+		if set.token, err = s.repo.ForToken(tokenID); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// Check evaluates capability for userID and tokenID on an entry described
+// by its fields, filed in folder (a path such as "Work/Servers") and tagged
+// with entry.Tags.
+func (s *PolicyService) Check(userID, tokenID int64, capability string, entry *models.VaultEntry, folder string) (*PolicyDecision, error) {
+	if err := validateCheckCapability(capability); err != nil {
+		return nil, err
+	}
+	set, err := s.Load(userID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, len(entry.Tags))
+	for i, tag := range entry.Tags {
+		tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+	set.labels = func(*models.VaultEntry) (string, []string, error) {
+		return strings.Trim(folder, "/"), tags, nil
+	}
+	decision := set.Allows(capability, entry)
+	return &decision, nil
+}
+
+func validateCheckCapability(capability string) error {
+	if capability == models.CapabilityDeny || capability == "*" || !policyCapabilities[capability] {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "unknown capability "+capability)
+	}
+	return nil
+}
+
+func (s *PolicyService) List() ([]models.Policy, error) {
+	return s.repo.List()
+}
+
+func (s *PolicyService) Get(id int64) (*models.Policy, error) {
+	policy, err := s.repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errPolicyNotFound()
+	}
+	return policy, err
+}
+
+func (s *PolicyService) Create(adminID int64, name string, rules []models.PolicyRule) (*models.Policy, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxPolicyNameLength {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "name must be 1-100 characters")
+	}
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+	id, err := s.repo.Create(name, rules)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.LogUserEvent(adminID, "policy.created", fmt.Sprintf("policy=%d name=%s", id, name))
+	return s.Get(id)
+}
+
+func (s *PolicyService) Update(adminID, id int64, rules []models.PolicyRule) (*models.Policy, error) {
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(id, rules); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errPolicyNotFound()
+		}
+		return nil, err
+	}
+	s.audit.LogUserEvent(adminID, "policy.updated", fmt.Sprintf("policy=%d", id))
+	return s.Get(id)
+}
+
+func (s *PolicyService) Delete(adminID, id int64) error {
+	if err := s.repo.Delete(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errPolicyNotFound()
+		}
+		return err
+	}
+	s.audit.LogUserEvent(adminID, "policy.deleted", fmt.Sprintf("policy=%d", id))
+	return nil
+}
+
+// Attach applies policy id to a user, team or API token.
+func (s *PolicyService) Attach(adminID, id int64, subjectType string, subjectID int64) (*models.Policy, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	if err := s.requireSubject(subjectType, subjectID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Attach(id, subjectType, subjectID); err != nil {
+		return nil, err
+	}
+	s.audit.LogUserEvent(adminID, "policy.attached", fmt.Sprintf("policy=%d %s=%d", id, subjectType, subjectID))
+	return s.Get(id)
+}
+
+func (s *PolicyService) Detach(adminID, id int64, subjectType string, subjectID int64) error {
+	ok, err := s.repo.Detach(id, subjectType, subjectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "attachment not found")
+	}
+	s.audit.LogUserEvent(adminID, "policy.detached", fmt.Sprintf("policy=%d %s=%d", id, subjectType, subjectID))
+	return nil
+}
+
+func (s *PolicyService) requireSubject(subjectType string, subjectID int64) error {
+	switch subjectType {
+	case models.PolicySubjectUser, models.PolicySubjectTeam, models.PolicySubjectToken:
+	default:
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "subject type must be user, team or token")
+	}
+	ok, err := s.repo.SubjectExists(subjectType, subjectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, subjectType+" not found")
+	}
+	return nil
+}
+
+func validateRules(rules []models.PolicyRule) error {
+	if len(rules) == 0 || len(rules) > maxPolicyRules {
+		return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "a policy needs 1-100 rules")
+	}
+	for i, rule := range rules {
+		invalid := func(message string) error {
+			return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, fmt.Sprintf("rule %d: %s", i+1, message))
+		}
+		for _, pattern := range []string{rule.Category, rule.Title, rule.Folder, rule.Tag} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return invalid("invalid pattern " + strconv.Quote(pattern))
+			}
+		}
+		if strings.HasPrefix(rule.Folder, "/") || strings.HasSuffix(rule.Folder, "/") {
+			return invalid("folder paths have no leading or trailing slash")
+		}
+		if rule.Folder != "" && rule.Collection != "" && rule.Collection != collectionPersonal {
+			return invalid("collection entries are not filed in folders")
+		}
+		if rule.Collection != "" && rule.Collection != collectionPersonal {
+			if _, err := strconv.ParseInt(rule.Collection, 10, 64); err != nil {
+				return invalid(`collection must be a collection id or "personal"`)
+			}
+		}
+		if len(rule.Capabilities) == 0 {
+			return invalid("capabilities required")
+		}
+		for _, c := range rule.Capabilities {
+			if !policyCapabilities[c] {
+				return invalid("unknown capability " + strconv.Quote(c))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Scheduler runs periodic background jobs, each in its own goroutine, until
+// Shutdown. Jobs must be registered with Every before Start.
+type Scheduler struct {
+	jobs []scheduledJob
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{done: make(chan struct{})}
+}
+
+// Every registers fn to run once per interval. Errors are logged and the
+// job keeps its schedule.
+func (s *Scheduler) Every(name string, interval time.Duration, fn func() error) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, run: fn})
+}
+
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+}
+
+func (s *Scheduler) loop(job scheduledJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := job.run(); err != nil {
+				log.Printf("scheduler: %s: %v", job.name, err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Shutdown stops the scheduler and waits for running jobs to finish
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	close(s.done)
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import "fmt"
+
+// Scopes carried in the "scope" claim of JWTs and granted to API tokens.
+const (
+	ScopeEntriesRead  = "entries:read"
+	ScopeEntriesWrite = "entries:write"
+)
+
+// AllScopes is what an interactive login receives unless it asks for less.
+var AllScopes = []string{ScopeEntriesRead, ScopeEntriesWrite}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		known := false
+		for _, s := range AllScopes {
+			if s == scope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// searchFields maps the field names a search term can be limited to, as in
+// url:github, to columns of the search index.
+var searchFields = map[string]string{
+	"title":    "title",
+	"user":     "username",
+	"username": "username",
+	"url":      "url",
+	"category": "category",
+	"notes":    "notes",
+}
+
+// searchTerm is one word or phrase of a search query.
+type searchTerm struct {
+	column string // index column the term is limited to, or empty
+	text   string
+	prefix bool // whether the last word may be the start of a longer one
+}
+
+// parseSearchQuery splits a search box query into terms. Words match as
+// prefixes, "quoted phrases" match exactly unless followed by *, and
+// field:word or field:"phrase" limits a term to one field. Every term must
+// match.
+func parseSearchQuery(query string) ([]searchTerm, error) {
+	var terms []searchTerm
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		term := searchTerm{prefix: true}
+		if i := strings.IndexByte(rest, ':'); i > 0 && !strings.ContainsAny(rest[:i], " \t\"") {
+			if col, ok := searchFields[strings.ToLower(rest[:i])]; ok {
+				term.column, rest = col, rest[i+1:]
+			}
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				term.text, rest = rest[1:], ""
+			} else {
+				term.text, rest = rest[1:end+1], rest[end+2:]
+			}
+			term.prefix = strings.HasPrefix(rest, "*")
+			rest = strings.TrimPrefix(rest, "*")
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			term.text, rest = strings.TrimRight(rest[:end], "*"), rest[end:]
+		}
+		rest = strings.TrimSpace(rest)
+
+		if len(searchWords(term.text)) > 0 {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, errInvalidEntry("search query needs at least one word")
+	}
+	return terms, nil
+}
+
+// ftsQuery translates terms into an FTS5 MATCH expression. Terms are
+// always quoted, so FTS5 operators typed by the user are searched for
+// literally.
+func ftsQuery(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		part := ftsString(term.text)
+		if term.prefix {
+			part += "*"
+		}
+		if term.column != "" {
+			part = term.column + " : " + part
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// ftsString quotes text as an FTS5 string.
+func ftsString(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// searchWords splits text into lowercase words the way the search index
+// tokenizes it.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const (
+	defaultSendTTL = 24 * time.Hour
+	maxSendTTL     = 30 * 24 * time.Hour
+	maxSendViews   = 100
+	// maxSendFailures wrong passphrases delete a send, so a leaked link
+	// cannot be used to guess its passphrase
+	maxSendFailures = 5
+)
+
+// Argon2id parameters for send passphrases (OWASP's minimum recommendation)
+const (
+	sendKDFTime    = 2
+	sendKDFMemory  = 19 * 1024
+	sendKDFThreads = 1
+	sendSaltSize   = 16
+)
+
+// SendOptions limit how long and how often a send can be opened.
+type SendOptions struct {
+	MaxViews   int
+	ExpiresIn  time.Duration
+	Passphrase string
+}
+
+// SendLink is returned once when a send is created; Key is not stored.
+type SendLink struct {
+	Send *models.Send `json:"send"`
+	URL  string       `json:"url"`
+	Key  string       `json:"key"`
+}
+
+// SendService creates one-time secret links for people outside the vault.
+// Each send is encrypted with a fresh random key that is only returned in
+// the link's URL fragment, so a database copy alone cannot open it.
+type SendService struct {
+	repo    *repository.SendRepository
+	vault   *VaultService
+	audit   *AuditService
+	baseURL string
+}
+
+func NewSendService(repo *repository.SendRepository, vault *VaultService, audit *AuditService, baseURL string) *SendService {
+	return &SendService{repo: repo, vault: vault, audit: audit, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// SendEntry shares an entry's details: its login, type data and custom
+// fields. It goes through VaultService.Get so sensitive entries still
+// require a recent re-authentication.
+func (s *SendService) SendEntry(userID, entryID int64, authTime time.Time, opts SendOptions) (*SendLink, error) {
+	// Get leaves the password out rather than failing without reveal
+	entry, err := s.vault.requireReadable(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.vault.authorize(userID, models.CapabilityReveal, entry); err != nil {
+		return nil, err
+	}
+	entry, err = s.vault.Get(userID, entryID, authTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// The recipient cannot follow links into the vault, only see their values
+	var fields []models.CustomField
+	for _, field := range entry.Fields {
+		fields = append(fields, models.CustomField{Name: field.Name, Type: field.Type, Value: field.Value})
+	}
+	return s.create(userID, &entryID, models.SendPayload{
+		Type:      entry.Type,
+		Title:     entry.Title,
+		Username:  entry.Username,
+		Password:  entry.Password,
+		URL:       entry.URL,
+		Fields:    fields,
+		EntryData: entry.EntryData,
+	}, opts)
+}
+
+// ForToken returns a SendService enforcing API token tokenID's policies.
+func (s *SendService) ForToken(tokenID int64) *SendService {
+	scoped := *s
+	scoped.vault = s.vault.ForToken(tokenID)
+	return &scoped
+}
+
+func (s *SendService) SendText(userID int64, text string, opts SendOptions) (*SendLink, error) {
+	if text == "" {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "text required")
+	}
+	return s.create(userID, nil, models.SendPayload{Text: text}, opts)
+}
+
+func (s *SendService) create(userID int64, entryID *int64, payload models.SendPayload, opts SendOptions) (*SendLink, error) {
+	if opts.MaxViews == 0 {
+		opts.MaxViews = 1
+	}
+	if opts.MaxViews < 0 || opts.MaxViews > maxSendViews {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "maxViews must be between 1 and 100")
+	}
+	if opts.ExpiresIn == 0 {
+		opts.ExpiresIn = defaultSendTTL
+	}
+	if opts.ExpiresIn < 0 || opts.ExpiresIn > maxSendTTL {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "expiry must be at most 30 days")
+	}
+
+	key, err := newDataKey()
+	if err != nil {
+		return nil, err
+	}
+	var salt []byte
+	if opts.Passphrase != "" {
+		salt = make([]byte, sendSaltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+	}
+	cipher, err := sendCipher(key, opts.Passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := cipher.Encrypt(string(plain))
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomURLToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	send := &models.Send{
+		ID:            id,
+		UserID:        userID,
+		EntryID:       entryID,
+		Ciphertext:    ciphertext,
+		HasPassphrase: opts.Passphrase != "",
+		KeyCheck:      sendKeyCheck(key),
+		MaxViews:      opts.MaxViews,
+		ExpiresAt:     now.Add(opts.ExpiresIn),
+		CreatedAt:     now,
+	}
+	if salt != nil {
+		send.PassphraseSalt = base64.StdEncoding.EncodeToString(salt)
+	}
+	if err := s.repo.Create(*send); err != nil {
+		return nil, err
+	}
+
+	var auditEntry int64
+	if entryID != nil {
+		auditEntry = *entryID
+	}
+	s.audit.LogEntryEvent(userID, auditEntry, "send_created", "send="+id)
+
+	encodedKey := base64.RawURLEncoding.EncodeToString(key)
+	return &SendLink{
+		Send: send,
+		URL:  s.baseURL + "/send/" + id + "#" + encodedKey,
+		Key:  encodedKey,
+	}, nil
+}
+
+// Info describes an active send without using up a view, so the recipient's
+// client knows whether to ask for a passphrase.
+func (s *SendService) Info(id string) (*models.Send, error) {
+	send, err := s.repo.GetActive(id, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errSendGone()
+	}
+	return send, err
+}
+
+// Open decrypts a send and counts the view; the send is deleted when its
+// last view is used. A wrong key or passphrase does not use up a view, but
+// after maxSendFailures wrong passphrases with the right key the send is
+// deleted.
+func (s *SendService) Open(id, key, passphrase string) (*models.SendPayload, error) {
+	send, err := s.Info(id)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, errSendKey()
+	}
+	// Only holders of the link key get to try passphrases, so guessing ids
+	// cannot use up anyone's attempts
+	if send.KeyCheck != "" && subtle.ConstantTimeCompare([]byte(sendKeyCheck(raw)), []byte(send.KeyCheck)) != 1 {
+		return nil, errSendKey()
+	}
+	var salt []byte
+	if send.PassphraseSalt != "" {
+		if salt, err = base64.StdEncoding.DecodeString(send.PassphraseSalt); err != nil {
+			return nil, err
+		}
+	}
+	cipher, err := sendCipher(raw, passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := cipher.Decrypt(send.Ciphertext)
+	if err != nil {
+		if send.HasPassphrase && send.KeyCheck != "" {
+			return nil, s.recordFailure(send)
+		}
+		return nil, errSendKey()
+	}
+
+	ok, err := s.repo.ConsumeView(id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errSendGone()
+	}
+
+	var payload models.SendPayload
+	if err := json.Unmarshal([]byte(plain), &payload); err != nil {
+		return nil, err
+	}
+
+	var auditEntry int64
+	if send.EntryID != nil {
+		auditEntry = *send.EntryID
+	}
+	s.audit.LogEntryEvent(send.UserID, auditEntry, "send_opened", "send="+id)
+	return &payload, nil
+}
+
+// recordFailure counts a wrong passphrase for send, deleting the send once
+// too many were given, and returns the error for the recipient.
+func (s *SendService) recordFailure(send *models.Send) error {
+	burned, err := s.repo.RecordFailure(send.ID, maxSendFailures)
+	if err != nil {
+		return err
+	}
+	if !burned {
+		return errSendKey()
+	}
+	var auditEntry int64
+	if send.EntryID != nil {
+		auditEntry = *send.EntryID
+	}
+	s.audit.LogEntryEvent(send.UserID, auditEntry, "send_burned", "send="+send.ID)
+	return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "too many wrong passphrases; the secret has been deleted")
+}
+
+func (s *SendService) List(userID int64) ([]models.Send, error) {
+	return s.repo.ListByUser(userID)
+}
+
+func (s *SendService) Delete(userID int64, id string) error {
+	found, err := s.repo.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "send not found")
+	}
+	return nil
+}
+
+// PurgeExpired deletes sends past their expiry.
+func (s *SendService) PurgeExpired() (int64, error) {
+	return s.repo.DeleteExpired(time.Now())
+}
+
+// sendCipher keys a send from the link key, mixing in the passphrase when
+// set. The passphrase is stretched with Argon2id under salt; sends from
+// before salts were stored have none and mix it in directly.
+func sendCipher(key []byte, passphrase string, salt []byte) (*CryptoService, error) {
+	cipher, err := newCryptoService(key)
+	if err != nil || passphrase == "" {
+		return cipher, err
+	}
+	if salt == nil {
+		return cipher.Derive("send-passphrase:" + passphrase)
+	}
+	stretched := argon2.IDKey([]byte(passphrase), salt, sendKDFTime, sendKDFMemory, sendKDFThreads, 32)
+	return cipher.Derive("send-passphrase:" + base64.StdEncoding.EncodeToString(stretched))
+}
+
+// sendKeyCheck identifies a link key. The key is random, so its hash gives
+// nothing away.
+func sendKeyCheck(key []byte) string {
+	sum := sha256.Sum256(append([]byte("send-key-check:"), key...))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func errSendGone() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "secret not found, expired or already viewed")
+}
+
+func errSendKey() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrUnauthorized, "invalid key or passphrase")
+}
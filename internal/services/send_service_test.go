@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+func TestSendEntryCarriesTypeDataAndFields(t *testing.T) {
+	v := newVaultTest(t)
+	owner := v.register(t, "owner@example.com")
+	id, err := v.vault.Create(owner, models.VaultEntry{
+		Type:      models.EntryTypeCard,
+		Title:     "Corporate card",
+		EntryData: models.EntryData{Card: &models.CardData{Number: "4111111111111111", ExpMonth: 12, ExpYear: 2030, CVV: "123"}},
+		Fields:    []models.CustomField{{Name: "PIN", Type: models.FieldHidden, Value: "4321"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sends := NewSendService(repository.NewSendRepository(v.db), v.vault, newTestAudit(t, v.db), "http://localhost")
+	link, err := sends.SendEntry(owner, id, time.Now(), SendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := sends.Open(link.Send.ID, link.Key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != models.EntryTypeCard || payload.Card == nil || payload.Card.Number != "4111111111111111" || payload.Card.CVV != "123" {
+		t.Fatalf("card data not sent: %+v", payload)
+	}
+	if len(payload.Fields) != 1 || payload.Fields[0].Value != "4321" {
+		t.Fatalf("hidden field not sent: %+v", payload.Fields)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+// ShareService shares single personal entries with other users. The
+// entry's data key is sealed to each recipient's X25519 public key, so a
+// share row is the only way a recipient can decrypt the entry.
+type ShareService struct {
+	vault  *VaultService
+	shares *repository.ShareRepository
+	users  *repository.UserRepository
+	keys   *KeyService
+	audit  *AuditService
+}
+
+func NewShareService(vault *VaultService, shares *repository.ShareRepository, users *repository.UserRepository, keys *KeyService, audit *AuditService) *ShareService {
+	return &ShareService{vault: vault, shares: shares, users: users, keys: keys, audit: audit}
+}
+
+// requireOwnEntry loads a personal entry of userID. Collection entries are
+// shared through organization membership instead. Sharing hands the entry
+// key to someone else, so it needs the reveal capability.
+func (s *ShareService) requireOwnEntry(userID, entryID int64) (*models.VaultEntry, error) {
+	entry, err := s.vault.requireManageable(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.CollectionID != nil {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "collection entries are shared through the organization")
+	}
+	if err := s.vault.authorize(userID, models.CapabilityReveal, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ForToken returns a ShareService enforcing API token tokenID's policies.
+func (s *ShareService) ForToken(tokenID int64) *ShareService {
+	scoped := *s
+	scoped.vault = s.vault.ForToken(tokenID)
+	return &scoped
+}
+
+func (s *ShareService) List(userID, entryID int64) ([]models.EntryShare, error) {
+	if _, err := s.requireOwnEntry(userID, entryID); err != nil {
+		return nil, err
+	}
+	return s.shares.ListByEntry(entryID)
+}
+
+// Share grants the user with email read or edit access to entryID, or
+// changes the permission of an existing share.
+func (s *ShareService) Share(userID, entryID int64, email, permission string) (*models.EntryShare, error) {
+	if permission != models.SharePermissionRead && permission != models.SharePermissionEdit {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "permission must be read or edit")
+	}
+
+	entry, err := s.requireOwnEntry(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient, err := s.users.GetByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if recipient.ID == userID {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "cannot share an entry with yourself")
+	}
+
+	key, err := s.vault.ensureEntryKey(userID, entry)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.keys.SealFor(recipient.ID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.shares.Upsert(models.EntryShare{
+		EntryID:     entryID,
+		OwnerID:     userID,
+		RecipientID: recipient.ID,
+		Permission:  permission,
+		KeySealed:   sealed,
+	}); err != nil {
+		return nil, err
+	}
+	s.audit.LogEntryEvent(userID, entryID, "shared", fmt.Sprintf("recipient=%d permission=%s", recipient.ID, permission))
+
+	return s.shares.GetForRecipient(entryID, recipient.ID)
+}
+
+// Revoke deletes a share. The owner can revoke any share of the entry and a
+// recipient can drop their own.
+func (s *ShareService) Revoke(userID, entryID, shareID int64) error {
+	share, err := s.shares.GetByID(entryID, shareID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "share not found")
+	}
+	if err != nil {
+		return err
+	}
+	if share.OwnerID != userID && share.RecipientID != userID {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "share not found")
+	}
+
+	if err := s.shares.Delete(entryID, shareID); err != nil {
+		return err
+	}
+	s.audit.LogEntryEvent(userID, entryID, "share_revoked", fmt.Sprintf("recipient=%d", share.RecipientID))
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"vault/internal/models"
+)
+
+const (
+	maxEntryTags   = 20
+	maxTagLength   = 50
+	tagSuggestions = 20
+)
+
+// normalizeTags lowercases and trims tags, drops duplicates and sorts them.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength {
+			return nil, errInvalidEntry(fmt.Sprintf("tags must be 1-%d characters", maxTagLength))
+		}
+		if strings.Contains(tag, ",") {
+			return nil, errInvalidEntry("tags cannot contain commas")
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxEntryTags {
+		return nil, errInvalidEntry(fmt.Sprintf("at most %d tags per entry", maxEntryTags))
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// loadLabels fills in the tags of entries and whether userID starred them.
+func (s *VaultService) loadLabels(userID int64, entries []models.VaultEntry) error {
+	ids := make([]int64, len(entries))
+	for i := range entries {
+		ids[i] = entries[i].ID
+	}
+	tags, err := s.repo.ListTags(ids)
+	if err != nil {
+		return err
+	}
+	favorites, err := s.repo.Favorites(userID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Tags = tags[entries[i].ID]
+		entries[i].Favorite = favorites[entries[i].ID]
+	}
+	return nil
+}
+
+// SetTags replaces the tags of entry id and returns them normalized. Tags
+// are shared by everyone who can see the entry, so changing them needs
+// write access.
+func (s *VaultService) SetTags(userID, id int64, tags []string) ([]string, error) {
+	entry, err := s.requireWritable(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(userID, models.CapabilityUpdate, entry); err != nil {
+		return nil, err
+	}
+	tags, err = normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	// Policies may select on tags, so the entry must stay in reach
+	entry.Tags = tags
+	if err := s.authorize(userID, models.CapabilityUpdate, entry); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetTags(id, tags); err != nil {
+		return nil, err
+	}
+	s.audit.LogEntryEvent(userID, id, "tags_changed", strings.Join(tags, ","))
+	return tags, nil
+}
+
+// SetFavorite stars or unstars entry id for userID only.
+func (s *VaultService) SetFavorite(userID, id int64, favorite bool) error {
+	if _, err := s.requireReadable(userID, id); err != nil {
+		return err
+	}
+	return s.repo.SetFavorite(userID, id, favorite, time.Now())
+}
+
+// SuggestTags completes prefix from the tags on entries userID can read.
+// When policies apply, only entries they let the caller list count, so a
+// restricted token cannot learn tags from entries hidden from it.
+func (s *VaultService) SuggestTags(userID int64, prefix string) ([]models.TagCount, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	set, err := s.loadPolicies(userID)
+	if err != nil {
+		return nil, err
+	}
+	if set.Empty() {
+		return s.repo.SuggestTags(userID, prefix, tagSuggestions)
+	}
+
+	entries, _, err := s.repo.ListPage(userID, "", models.EntryFilter{}, models.SortAccessed, true, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for i := range entries {
+		if set.Allows(models.CapabilityList, &entries[i]).Allowed {
+			ids = append(ids, entries[i].ID)
+		}
+	}
+	tags, err := s.repo.ListTags(ids)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, entryTags := range tags {
+		for _, tag := range entryTags {
+			if strings.HasPrefix(tag, prefix) {
+				counts[tag]++
+			}
+		}
+	}
+	suggestions := make([]models.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		suggestions = append(suggestions, models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(suggestions, func(a, b int) bool {
+		if suggestions[a].Count != suggestions[b].Count {
+			return suggestions[a].Count > suggestions[b].Count
+		}
+		return suggestions[a].Tag < suggestions[b].Tag
+	})
+	if len(suggestions) > tagSuggestions {
+		suggestions = suggestions[:tagSuggestions]
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+func TestSuggestTagsFollowsTokenPolicies(t *testing.T) {
+	v := newVaultTest(t)
+	owner := v.register(t, "owner@example.com")
+	for _, entry := range []struct {
+		category string
+		tags     []string
+	}{
+		{"work", []string{"deploy"}},
+		{"private", []string{"deploy", "secret-project"}},
+	} {
+		id, err := v.vault.Create(owner, models.VaultEntry{Title: entry.category, Password: "pw", Category: entry.category})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v.vault.SetTags(owner, id, entry.tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A token limited to work entries
+	audit := newTestAudit(t, v.db)
+	_, token, err := NewTokenService(repository.NewTokenRepository(v.db), audit).Create(owner, "ci", AllScopes, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := NewPolicyService(repository.NewPolicyRepository(v.db), audit)
+	policy, err := policies.Create(owner, "work-only", []models.PolicyRule{{Category: "work", Capabilities: []string{"*"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policies.Attach(owner, policy.ID, models.PolicySubjectToken, token.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := v.vault.SuggestTags(owner, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0] != (models.TagCount{Tag: "deploy", Count: 2}) {
+		t.Fatalf("unrestricted suggestions %+v", all)
+	}
+
+	scoped, err := v.vault.ForToken(token.ID).SuggestTags(owner, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scoped) != 1 || scoped[0] != (models.TagCount{Tag: "deploy", Count: 1}) {
+		t.Fatalf("token suggestions %+v, want only deploy on the work entry", scoped)
+	}
+	if scoped, err := v.vault.ForToken(token.ID).SuggestTags(owner, "sec"); err != nil || len(scoped) != 0 {
+		t.Fatalf("token sees hidden tag: %+v, %v", scoped, err)
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"vault/internal/config"
+	"vault/internal/db"
+	vaulterrors "vault/internal/errors"
+	"vault/internal/repository"
+)
+
+// repoRoot is where migrations are found relative to; tests start in the
+// package directory.
+var repoRoot, _ = filepath.Abs("../..")
+
+// newTestDB opens a migrated database in a temporary directory. The test
+// then runs from the repository root, so read any testdata first.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	chdir(t, repoRoot)
+	database, err := db.Open(config.Config{DBPath: filepath.Join(t.TempDir(), "vault.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.Migrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// chdir changes the working directory for the rest of the test and
+// restores it afterwards. Tests that use it must not run in parallel.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// newTestAudit returns an audit service that is shut down with the test.
+func newTestAudit(t *testing.T, database *sql.DB) *AuditService {
+	t.Helper()
+	audit := NewAuditService(repository.NewVaultRepository(database))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = audit.Shutdown(ctx)
+	})
+	return audit
+}
+
+// vaultErrorCode returns the code of a VaultError, or "" for other errors.
+func vaultErrorCode(err error) string {
+	var vaultErr *vaulterrors.VaultError
+	if errors.As(err, &vaultErr) {
+		return vaultErr.Code
+	}
+	return ""
+}
+
+// newTestCrypto returns a cipher under a random server key.
+func newTestCrypto(t *testing.T) *CryptoService {
+	t.Helper()
+	serverKey := make([]byte, 32)
+	if _, err := rand.Read(serverKey); err != nil {
+		t.Fatal(err)
+	}
+	crypto, err := NewCryptoService(base64.StdEncoding.EncodeToString(serverKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto
+}
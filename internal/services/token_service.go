@@ -0,0 +1,134 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+// APITokenPrefix marks a bearer credential as an API token rather than a JWT.
+const APITokenPrefix = "pvt_"
+
+const (
+	defaultTokenTTL = 90 * 24 * time.Hour
+	maxTokenTTL     = 365 * 24 * time.Hour
+	// tokenPrefixLen is how much of the raw token is kept in clear for display.
+	tokenPrefixLen = len(APITokenPrefix) + 8
+)
+
+var ErrInvalidAPIToken = errors.New("invalid or expired api token")
+
+type TokenService struct {
+	tokens *repository.TokenRepository
+	audit  *AuditService
+}
+
+func NewTokenService(tokens *repository.TokenRepository, audit *AuditService) *TokenService {
+	return &TokenService{tokens: tokens, audit: audit}
+}
+
+// Create mints a new API token. The raw token is returned only once; the
+// database keeps its SHA-256 hash.
+func (s *TokenService) Create(userID int64, name string, scopes []string, ttl time.Duration) (string, *models.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("name required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.New("at least one scope required")
+	}
+	if err := validateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	if ttl > maxTokenTTL {
+		return "", nil, errors.New("token lifetime may not exceed 365 days")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	raw := APITokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:tokenPrefixLen],
+		TokenHash: hashAPIToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: &expiresAt,
+		CreatedAt: now,
+	}
+
+	id, err := s.tokens.Create(token)
+	if err != nil {
+		return "", nil, err
+	}
+	token.ID = id
+
+	s.audit.LogTokenEvent(userID, id, "token.created", token.Prefix)
+	return raw, &token, nil
+}
+
+func (s *TokenService) List(userID int64) ([]models.APIToken, error) {
+	return s.tokens.ListByUser(userID)
+}
+
+func (s *TokenService) Revoke(userID, id int64) error {
+	found, err := s.tokens.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("token not found")
+	}
+	s.audit.LogTokenEvent(userID, id, "token.revoked", "")
+	return nil
+}
+
+// Authenticate resolves a raw bearer token to its stored record, rejecting
+// unknown and expired tokens.
+func (s *TokenService) Authenticate(raw string) (*models.APIToken, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	token, err := s.tokens.GetByHash(hashAPIToken(raw))
+	if err != nil {
+		return nil, ErrInvalidAPIToken
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	// Update last used timestamp asynchronously
+	go func() {
+		_ = s.tokens.TouchLastUsed(token.ID, now)
+	}()
+
+	return token, nil
+}
+
+// RecordUse writes an audit event for a request authenticated by token.
+func (s *TokenService) RecordUse(token *models.APIToken, method, path string) {
+	s.audit.LogTokenEvent(token.UserID, token.ID, "token.used", method+" "+path)
+}
+
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"time"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/models"
+)
+
+// requireTrashed loads a trashed entry userID controls and checks their
+// policies let them delete it, which covers restoring and purging.
+func (s *VaultService) requireTrashed(userID, id int64) (*models.VaultEntry, error) {
+	entry, err := s.repo.GetTrashed(userID, id)
+	if err != nil {
+		return nil, vaulterrors.NewVaultErrorWithErr(vaulterrors.ErrNotFound, "entry not in trash", err)
+	}
+	if err := s.authorize(userID, models.CapabilityDelete, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListTrash returns the trashed entries userID could restore, without their
+// passwords.
+func (s *VaultService) ListTrash(userID int64) ([]models.VaultEntry, error) {
+	entries, err := s.repo.ListTrash(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterListable(userID, entries)
+}
+
+// RestoreTrashed takes an entry back out of the trash.
+func (s *VaultService) RestoreTrashed(userID, id int64) error {
+	if _, err := s.requireTrashed(userID, id); err != nil {
+		return err
+	}
+	if err := s.repo.Untrash(userID, id); err != nil {
+		return err
+	}
+	s.audit.LogEvent(userID, id, "untrashed")
+	return nil
+}
+
+// Purge permanently deletes a trashed entry with its history.
+func (s *VaultService) Purge(userID, id int64) error {
+	if _, err := s.requireTrashed(userID, id); err != nil {
+		return err
+	}
+	if err := s.repo.Purge(userID, id); err != nil {
+		return err
+	}
+	s.audit.LogEvent(userID, id, "purged")
+	return nil
+}
+
+// PurgeTrash permanently deletes entries that have been in the trash longer
+// than the retention period.
+func (s *VaultService) PurgeTrash() (int64, error) {
+	return s.repo.PurgeTrashedBefore(time.Now().Add(-s.trashRetention))
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"vault/internal/models"
+)
+
+const (
+	maxEntryURIs   = 20
+	maxURILength   = 2048
+	maxGroupDomain = 50
+)
+
+// URL match strengths, weakest first. Entries matching a page are listed
+// strongest match first.
+const (
+	matchNone = iota
+	matchEquivalent
+	matchDomain
+	matchHost
+	matchRegex
+	matchStartsWith
+	matchExact
+)
+
+func validURLMatch(mode string) bool {
+	switch mode {
+	case "", models.URLMatchDomain, models.URLMatchHost, models.URLMatchStartsWith,
+		models.URLMatchExact, models.URLMatchRegex, models.URLMatchNever:
+		return true
+	}
+	return false
+}
+
+// validateURIs checks entry's URL match mode and further URIs, trimming
+// the URIs. Regular expressions must compile; domain and host matching
+// need a URI with a host.
+func validateURIs(entry *models.VaultEntry) error {
+	if !validURLMatch(entry.URLMatch) {
+		return errInvalidEntry("unknown URL match mode " + entry.URLMatch)
+	}
+	if entry.URL != "" {
+		if err := validateURI(entry.URL, entry.URLMatch); err != nil {
+			return err
+		}
+	}
+	if len(entry.URIs) > maxEntryURIs {
+		return errInvalidEntry(fmt.Sprintf("at most %d URIs per entry", maxEntryURIs))
+	}
+	for i := range entry.URIs {
+		uri := &entry.URIs[i]
+		uri.URI = strings.TrimSpace(uri.URI)
+		if !validURLMatch(uri.Match) {
+			return errInvalidEntry("unknown URL match mode " + uri.Match)
+		}
+		if uri.URI == "" || len(uri.URI) > maxURILength {
+			return errInvalidEntry(fmt.Sprintf("URIs must be 1-%d characters", maxURILength))
+		}
+		mode := uri.Match
+		if mode == "" {
+			mode = entry.URLMatch
+		}
+		if err := validateURI(uri.URI, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateURI(uri, mode string) error {
+	switch mode {
+	case models.URLMatchRegex:
+		if _, err := regexp.Compile(uri); err != nil {
+			return errInvalidEntry("invalid URL pattern " + uri)
+		}
+	case "", models.URLMatchDomain, models.URLMatchHost:
+		if _, err := parseMatchURL(uri); err != nil {
+			return errInvalidEntry("URI " + uri + " has no host to match")
+		}
+	}
+	return nil
+}
+
+// parseMatchURL parses a stored URI or page URL, reading one without a
+// scheme, such as github.com/login, as https.
+func parseMatchURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("no host in %q", raw)
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u, nil
+}
+
+// baseDomain returns the registrable domain of host by the public suffix
+// list, such as example.co.uk for login.example.co.uk. IP addresses and
+// hosts without a registrable part, such as localhost, are their own base.
+func baseDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// pageMatcher rates stored URIs against the page being filled.
+type pageMatcher struct {
+	raw        string
+	page       *url.URL
+	base       string
+	equivalent map[string]bool // base domains grouped with the page's
+}
+
+func newPageMatcher(raw string, groups []models.DomainGroup) (*pageMatcher, error) {
+	page, err := parseMatchURL(raw)
+	if err != nil {
+		return nil, errInvalidEntry("url must be an address with a host")
+	}
+	m := &pageMatcher{raw: strings.TrimSpace(raw), page: page, base: baseDomain(page.Hostname()), equivalent: map[string]bool{}}
+	for _, group := range groups {
+		for _, domain := range group.Domains {
+			if domain != m.base {
+				continue
+			}
+			for _, other := range group.Domains {
+				m.equivalent[other] = true
+			}
+		}
+	}
+	return m, nil
+}
+
+// rate returns how strongly uri, compared using mode, matches the page, or
+// matchNone.
+func (m *pageMatcher) rate(uri, mode string) int {
+	switch mode {
+	case models.URLMatchNever:
+		return matchNone
+	case models.URLMatchExact:
+		if uri == m.raw {
+			return matchExact
+		}
+		return matchNone
+	case models.URLMatchStartsWith:
+		if strings.HasPrefix(m.raw, uri) {
+			return matchStartsWith
+		}
+		return matchNone
+	case models.URLMatchRegex:
+		if re, err := regexp.Compile(uri); err == nil && re.MatchString(m.raw) {
+			return matchRegex
+		}
+		return matchNone
+	}
+
+	stored, err := parseMatchURL(uri)
+	if err != nil {
+		return matchNone
+	}
+	if mode == models.URLMatchHost {
+		if stored.Host == m.page.Host {
+			return matchHost
+		}
+		return matchNone
+	}
+	base := baseDomain(stored.Hostname())
+	switch {
+	case base == m.base:
+		return matchDomain
+	case m.equivalent[base]:
+		return matchEquivalent
+	}
+	return matchNone
+}
+
+// rateEntry returns the strongest match among entry's URL and URIs.
+func (m *pageMatcher) rateEntry(entry *models.VaultEntry) int {
+	best := matchNone
+	if entry.URL != "" {
+		best = m.rate(entry.URL, entry.URLMatch)
+	}
+	for _, uri := range entry.URIs {
+		mode := uri.Match
+		if mode == "" {
+			mode = entry.URLMatch
+		}
+		best = max(best, m.rate(uri.URI, mode))
+	}
+	return best
+}
+
+// normalizeDomainGroup reduces domains, given as domains or URLs, to
+// distinct base domains.
+func normalizeDomainGroup(domains []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, domain := range domains {
+		u, err := parseMatchURL(domain)
+		if err != nil {
+			return nil, errInvalidEntry("invalid domain " + domain)
+		}
+		base := baseDomain(u.Hostname())
+		if !seen[base] {
+			seen[base] = true
+			normalized = append(normalized, base)
+		}
+	}
+	if len(normalized) < 2 || len(normalized) > maxGroupDomain {
+		return nil, errInvalidEntry(fmt.Sprintf("a group needs 2-%d different domains", maxGroupDomain))
+	}
+	return normalized, nil
+}
+
+// loadURIs fills in the further URIs of entries.
+func (s *VaultService) loadURIs(entries []models.VaultEntry) error {
+	ids := make([]int64, len(entries))
+	for i := range entries {
+		ids[i] = entries[i].ID
+	}
+	uris, err := s.repo.ListURIs(ids)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].URIs = uris[entries[i].ID]
+	}
+	return nil
+}
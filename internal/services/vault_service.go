@@ -2,59 +2,581 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	vaulterrors "vault/internal/errors"
 	"vault/internal/models"
 	"vault/internal/repository"
 )
 
 type VaultService struct {
-	repo   *repository.VaultRepository
-	crypto *CryptoService
-	audit  *AuditService
+	repo      *repository.VaultRepository
+	shares    *repository.ShareRepository
+	access    *repository.AccessRepository
+	checkouts *repository.CheckoutRepository
+	policies  *PolicyService
+	keys      *KeyService
+	crypto    *CryptoService
+	audit     *AuditService
+	generator *PasswordGenerator
+	// tokenID is the API token the caller authenticated with, if any; its
+	// policies apply on top of the user's. See ForToken.
+	tokenID int64
+	// reauthWindow is how recent auth_time must be to reveal sensitive entries
+	reauthWindow time.Duration
+	// historyLimit is how many revisions are kept per entry
+	historyLimit int
+	// trashRetention is how long trashed entries are kept before purging
+	trashRetention time.Duration
 }
 
-func NewVaultService(repo *repository.VaultRepository, crypto *CryptoService, audit *AuditService) *VaultService {
-	return &VaultService{repo: repo, crypto: crypto, audit: audit}
+func NewVaultService(repo *repository.VaultRepository, shares *repository.ShareRepository, access *repository.AccessRepository, checkouts *repository.CheckoutRepository, policies *PolicyService, keys *KeyService, crypto *CryptoService, audit *AuditService, generator *PasswordGenerator, reauthWindow time.Duration, historyLimit int, trashRetention time.Duration) *VaultService {
+	return &VaultService{repo: repo, shares: shares, access: access, checkouts: checkouts, policies: policies, keys: keys, crypto: crypto, audit: audit, generator: generator, reauthWindow: reauthWindow, historyLimit: historyLimit, trashRetention: trashRetention}
 }
 
-func (s *VaultService) List(userID int64) ([]models.VaultEntry, error) {
-	entries, err := s.repo.ListByUser(userID)
+// ForToken returns a VaultService that also enforces the policies attached
+// to API token tokenID. A zero tokenID returns s unchanged.
+func (s *VaultService) ForToken(tokenID int64) *VaultService {
+	if tokenID == 0 {
+		return s
+	}
+	scoped := *s
+	scoped.tokenID = tokenID
+	return &scoped
+}
+
+// authorize fails with ErrForbidden unless the caller's policies grant
+// capability on entry.
+func (s *VaultService) authorize(userID int64, capability string, entry *models.VaultEntry) error {
+	ok, err := s.permits(userID, capability, entry)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.audit.LogEntryEvent(userID, entry.ID, "policy_denied", "capability="+capability)
+		return vaulterrors.NewVaultError(vaulterrors.ErrForbidden, capability+" denied by policy")
+	}
+	return nil
+}
+
+func (s *VaultService) permits(userID int64, capability string, entry *models.VaultEntry) (bool, error) {
+	set, err := s.loadPolicies(userID)
+	if err != nil {
+		return false, err
+	}
+	return set.Allows(capability, entry).Allowed, nil
+}
+
+// loadPolicies returns the policies applying to the caller, able to look up
+// the folder paths and tags of the entries they are evaluated on.
+func (s *VaultService) loadPolicies(userID int64) (*PolicySet, error) {
+	set, err := s.policies.Load(userID, s.tokenID)
+	if err != nil {
+		return nil, err
+	}
+	// Folders belong to an entry's owner; cache each owner's paths for the
+	// lifetime of the set. Tags already on the entry, such as ones about to
+	// be stored, take precedence over the stored ones.
+	paths := map[int64]map[int64]string{}
+	set.labels = func(entry *models.VaultEntry) (string, []string, error) {
+		tags := entry.Tags
+		if tags == nil {
+			stored, err := s.repo.ListTags([]int64{entry.ID})
+			if err != nil {
+				return "", nil, err
+			}
+			tags = stored[entry.ID]
+		}
+		if entry.FolderID == nil {
+			return "", tags, nil
+		}
+		owned, ok := paths[entry.UserID]
+		if !ok {
+			var err error
+			if owned, err = s.repo.FolderPaths(entry.UserID); err != nil {
+				return "", nil, err
+			}
+			paths[entry.UserID] = owned
+		}
+		return owned[*entry.FolderID], tags, nil
+	}
+	return set, nil
+}
+
+// CheckPolicy evaluates capability on entry id as userID sees it, without
+// performing the operation.
+func (s *VaultService) CheckPolicy(userID, id int64, capability string) (*PolicyDecision, error) {
+	if err := validateCheckCapability(capability); err != nil {
+		return nil, err
+	}
+	entry, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, vaulterrors.NewVaultErrorWithErr(vaulterrors.ErrNotFound, "entry not found", err)
+	}
+	set, err := s.loadPolicies(userID)
 	if err != nil {
 		return nil, err
 	}
+	decision := set.Allows(capability, entry)
+	return &decision, nil
+}
+
+// filterListable drops the entries the caller's policies do not let them
+// list and prepares the rest with prepareListed.
+func (s *VaultService) filterListable(userID int64, entries []models.VaultEntry) ([]models.VaultEntry, error) {
+	set, err := s.loadPolicies(userID)
+	if err != nil {
+		return nil, err
+	}
+	listable := entries[:0]
+	for _, entry := range entries {
+		if set.Allows(models.CapabilityList, &entry).Allowed {
+			listable = append(listable, entry)
+		}
+	}
+	if err := s.prepareListed(userID, listable); err != nil {
+		return nil, err
+	}
+	return listable, nil
+}
+
+// prepareListed clears the passwords of entries about to be listed and
+// loads their masked custom fields, URIs, tags and favorite flags.
+func (s *VaultService) prepareListed(userID int64, entries []models.VaultEntry) error {
 	for i := range entries {
 		entries[i].Password = ""
 	}
-	return entries, nil
+	if err := s.maskFields(entries); err != nil {
+		return err
+	}
+	if err := s.loadURIs(entries); err != nil {
+		return err
+	}
+	return s.loadLabels(userID, entries)
 }
 
-func (s *VaultService) Get(userID, id int64) (*models.VaultEntry, error) {
-	entry, err := s.repo.GetByID(userID, id)
+// entryCipher returns the cipher protecting an entry's secret fields.
+func (s *VaultService) entryCipher(userID int64, entry *models.VaultEntry) (*CryptoService, error) {
+	key, err := s.entryKey(userID, entry)
 	if err != nil {
 		return nil, err
 	}
+	if key == nil {
+		return s.crypto, nil
+	}
+	return newCryptoService(key)
+}
+
+// entryKey unwraps an entry's data key. Owners and collection members use
+// the copy wrapped with the server key; share recipients can only use the
+// copy sealed to their own key pair. Entries created before per-entry keys
+// have none and are encrypted with the server key directly.
+func (s *VaultService) entryKey(userID int64, entry *models.VaultEntry) ([]byte, error) {
+	if entry.KeyEnc == "" {
+		return nil, nil
+	}
+
+	if entry.CollectionID == nil && entry.UserID != userID {
+		share, err := s.shares.GetForRecipient(entry.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		return s.keys.OpenFor(userID, share.KeySealed)
+	}
+
+	encoded, err := s.crypto.Decrypt(entry.KeyEnc)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// newEntryKey generates a data key and returns its cipher and server-wrapped form.
+func (s *VaultService) newEntryKey() ([]byte, *CryptoService, string, error) {
+	key, err := newDataKey()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	cipher, err := newCryptoService(key)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	keyEnc, err := s.crypto.Encrypt(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return key, cipher, keyEnc, nil
+}
+
+// ensureEntryKey returns the entry's data key, first moving entries written
+// before per-entry keys onto one. Every secret still under the server key
+// (password, type data and hidden custom fields) is re-encrypted with the
+// new key in the same update.
+func (s *VaultService) ensureEntryKey(userID int64, entry *models.VaultEntry) ([]byte, error) {
+	if entry.KeyEnc != "" {
+		return s.entryKey(userID, entry)
+	}
 
 	plain, err := s.crypto.Decrypt(entry.PasswordEnc)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.openFields(s.crypto, entry); err != nil {
+		return nil, err
+	}
+	key, cipher, keyEnc, err := s.newEntryKey()
+	if err != nil {
+		return nil, err
+	}
+	enc, err := cipher.Encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+	if entry.DataEnc != "" {
+		data, err := s.crypto.Decrypt(entry.DataEnc)
+		if err != nil {
+			return nil, err
+		}
+		if entry.DataEnc, err = cipher.Encrypt(data); err != nil {
+			return nil, err
+		}
+	}
+	fields, err := sealFields(cipher, entry.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.PasswordEnc = enc
+	entry.KeyEnc = keyEnc
+	entry.Fields = fields
+	if err := s.repo.Update(userID, *entry); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// requireRecentAuth fails with ErrReauthRequired unless the caller proved
+// their credentials within the re-authentication window.
+func (s *VaultService) requireRecentAuth(authTime time.Time) error {
+	return requireAuthWithin(authTime, s.reauthWindow)
+}
+
+// requireAuthWithin fails with ErrReauthRequired unless authTime lies within
+// window of now; the zero time, as carried by API tokens, never does.
+func requireAuthWithin(authTime time.Time, window time.Duration) error {
+	if authTime.IsZero() || time.Since(authTime) > window {
+		return vaulterrors.NewVaultError(vaulterrors.ErrReauthRequired, "recent re-authentication required")
+	}
+	return nil
+}
+
+// requireApproval fails with ErrApprovalRequired when entry requires approval
+// and userID is neither an approver for its organization nor holding an
+// approved, unexpired access request.
+func (s *VaultService) requireApproval(userID int64, entry *models.VaultEntry) error {
+	if !entry.RequiresApproval || entry.CollectionID == nil {
+		return nil
+	}
+	ok, err := s.access.IsApprover(userID, entry.ID)
+	if err != nil || ok {
+		return err
+	}
+	ok, err = s.access.HasGrant(entry.ID, userID, time.Now())
+	if err != nil || ok {
+		return err
+	}
+	return vaulterrors.NewVaultError(vaulterrors.ErrApprovalRequired, "an approved access request is required")
+}
+
+// requireCheckout fails with ErrCheckoutRequired when entry must be checked
+// out and userID does not hold an unexpired checkout of it.
+func (s *VaultService) requireCheckout(userID int64, entry *models.VaultEntry) error {
+	if !entry.CheckoutRequired {
+		return nil
+	}
+	ok, err := s.checkouts.Holds(entry.ID, userID, time.Now())
+	if err != nil || ok {
+		return err
+	}
+	return vaulterrors.NewVaultError(vaulterrors.ErrCheckoutRequired, "entry must be checked out first")
+}
+
+// requireNotCheckedOut fails with ErrConflict while entry id has an
+// unexpired checkout, unless allowHolder is set and userID holds it.
+func (s *VaultService) requireNotCheckedOut(userID, id int64, allowHolder bool) error {
+	checkout, err := s.checkouts.Active(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !checkout.ExpiresAt.After(time.Now()) || (allowHolder && checkout.UserID == userID) {
+		return nil
+	}
+	if checkout.UserID == userID {
+		return vaulterrors.NewVaultError(vaulterrors.ErrConflict, "check the entry in first")
+	}
+	return vaulterrors.NewVaultError(vaulterrors.ErrConflict, "entry is checked out by "+checkout.UserEmail)
+}
+
+// requireApprover checks userID is an owner or admin of the organization
+// holding entry id.
+func (s *VaultService) requireApprover(userID, id int64, message string) error {
+	ok, err := s.access.IsApprover(userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrForbidden, message)
+	}
+	return nil
+}
+
+// requireProtector checks that userID may remove a protection from entry:
+// the owner of a personal entry, or an owner or admin of the organization
+// holding a collection entry.
+func (s *VaultService) requireProtector(userID int64, entry *models.VaultEntry, message string) error {
+	if entry.CollectionID == nil {
+		if entry.UserID != userID {
+			return vaulterrors.NewVaultError(vaulterrors.ErrForbidden, message)
+		}
+		return nil
+	}
+	return s.requireApprover(userID, entry.ID, message)
+}
+
+// requireReadable loads an entry the user can see.
+func (s *VaultService) requireReadable(userID, id int64) (*models.VaultEntry, error) {
+	entry, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, vaulterrors.NewVaultErrorWithErr(vaulterrors.ErrNotFound, "entry not found", err)
+	}
+	if err := s.authorize(userID, models.CapabilityRead, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// requireWritable loads an entry the user can see and checks they may change
+// it; read-only organization members get ErrForbidden.
+func (s *VaultService) requireWritable(userID, id int64) (*models.VaultEntry, error) {
+	entry, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, vaulterrors.NewVaultErrorWithErr(vaulterrors.ErrNotFound, "entry not found", err)
+	}
+	ok, err := s.repo.CanWrite(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrForbidden, "read-only access to this entry")
+	}
+	return entry, nil
+}
+
+// requireManageable loads an entry the user may delete, move or share: their
+// own personal entry or one in a collection they can write. Share recipients
+// get ErrForbidden even with edit permission.
+func (s *VaultService) requireManageable(userID, id int64) (*models.VaultEntry, error) {
+	entry, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, vaulterrors.NewVaultErrorWithErr(vaulterrors.ErrNotFound, "entry not found", err)
+	}
+	ok, err := s.repo.CanManage(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vaulterrors.NewVaultError(vaulterrors.ErrForbidden, "only the owner can do this")
+	}
+	return entry, nil
+}
+
+func (s *VaultService) requireWritableCollection(userID, collectionID int64) error {
+	ok, err := s.repo.CanWriteCollection(userID, collectionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrForbidden, "no write access to collection")
+	}
+	return nil
+}
+
+// List returns a page of the entries userID can see that match filter,
+// without their passwords or type-specific fields.
+func (s *VaultService) List(userID int64, filter models.EntryFilter, page models.PageRequest) (*models.EntryPage, error) {
+	return s.listPage(userID, nil, filter, page)
+}
+
+// Get returns an entry with its decrypted password. authTime is when the
+// caller last presented credentials; sensitive entries need it to be recent.
+// Entries that require approval also need an approved access request.
+// Callers whose policies grant read but not reveal get the entry without
+// its password.
+func (s *VaultService) Get(userID, id int64, authTime time.Time) (*models.VaultEntry, error) {
+	entry, err := s.requireReadable(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.permits(userID, models.CapabilityReveal, entry)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		entry.Password = ""
+		entries := []models.VaultEntry{*entry}
+		if err := s.maskFields(entries); err != nil {
+			return nil, err
+		}
+		if err := s.loadURIs(entries); err != nil {
+			return nil, err
+		}
+		if err := s.loadLabels(userID, entries); err != nil {
+			return nil, err
+		}
+		return &entries[0], nil
+	}
+
+	if err := s.requireUnlocked(userID, entry, authTime); err != nil {
+		return nil, err
+	}
+	if err := s.requireCheckout(userID, entry); err != nil {
+		return nil, err
+	}
+	entry, err = s.reveal(userID, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resolveLinks(userID, entry, authTime); err != nil {
+		return nil, err
+	}
+	entries := []models.VaultEntry{*entry}
+	if err := s.loadURIs(entries); err != nil {
+		return nil, err
+	}
+	if err := s.loadLabels(userID, entries); err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// requireRevealable applies the policy, approval and step-up checks that
+// guard an entry's secret.
+func (s *VaultService) requireRevealable(userID int64, entry *models.VaultEntry, authTime time.Time) error {
+	if err := s.authorize(userID, models.CapabilityReveal, entry); err != nil {
+		return err
+	}
+	return s.requireUnlocked(userID, entry, authTime)
+}
+
+// requireUnlocked applies the approval and step-up checks.
+func (s *VaultService) requireUnlocked(userID int64, entry *models.VaultEntry, authTime time.Time) error {
+	if err := s.requireApproval(userID, entry); err != nil {
+		s.audit.LogEvent(userID, entry.ID, "approval_required")
+		return err
+	}
+
+	if entry.Sensitive {
+		if err := s.requireRecentAuth(authTime); err != nil {
+			s.audit.LogEvent(userID, entry.ID, "reauth_required")
+			return err
+		}
+	}
+	return nil
+}
+
+// reveal decrypts entry's password for userID and records the access.
+func (s *VaultService) reveal(userID int64, entry *models.VaultEntry) (*models.VaultEntry, error) {
+	cipher, err := s.entryCipher(userID, entry)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := cipher.Decrypt(entry.PasswordEnc)
+	if err != nil {
+		return nil, err
+	}
 	entry.Password = plain
+	if err := openEntryData(cipher, entry); err != nil {
+		return nil, err
+	}
+	if err := s.openFields(cipher, entry); err != nil {
+		return nil, err
+	}
 
 	// Log access in background using goroutine (non-blocking)
-	s.audit.LogEvent(userID, id, "accessed")
+	s.audit.LogEvent(userID, entry.ID, "accessed")
 
 	// Update last accessed timestamp asynchronously
 	go func() {
-		_ = s.repo.TouchLastAccessed(userID, id, time.Now().UTC())
+		_ = s.repo.TouchLastAccessed(userID, entry.ID, time.Now().UTC())
 	}()
 
 	return entry, nil
 }
 
-// Search finds vault entries by website/URL/username with context support
-func (s *VaultService) Search(ctx context.Context, userID int64, query string) ([]models.VaultEntry, error) {
+// ListPersonal lists ownerID's personal entries without secrets, for a
+// trusted contact with emergency access.
+func (s *VaultService) ListPersonal(ownerID int64) ([]models.VaultEntry, error) {
+	entries, err := s.repo.ListPersonal(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Password = ""
+	}
+	if err := s.maskFields(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetPersonal decrypts one of ownerID's personal entries for readerID. The
+// caller has already checked readerID's right to it; sensitive entries
+// still need the reader to have authenticated recently.
+func (s *VaultService) GetPersonal(ownerID, readerID, id int64, authTime time.Time) (*models.VaultEntry, error) {
+	entry, err := s.repo.GetPersonal(ownerID, id)
+	if err != nil {
+		return nil, vaulterrors.NewVaultErrorWithErr(vaulterrors.ErrNotFound, "entry not found", err)
+	}
+
+	if entry.Sensitive {
+		if err := s.requireRecentAuth(authTime); err != nil {
+			return nil, err
+		}
+	}
+
+	cipher, err := s.entryCipher(ownerID, entry)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := cipher.Decrypt(entry.PasswordEnc)
+	if err != nil {
+		return nil, err
+	}
+	entry.Password = plain
+	if err := openEntryData(cipher, entry); err != nil {
+		return nil, err
+	}
+	// Linked fields are left unresolved: they point into the owner's view
+	if err := s.openFields(cipher, entry); err != nil {
+		return nil, err
+	}
+
+	s.audit.LogEntryEvent(readerID, id, "accessed_for_owner", fmt.Sprintf("owner=%d", ownerID))
+	return entry, nil
+}
+
+// Search finds vault entries by title, username, URL, category and notes
+// with context support, tolerating typos. See parseSearchQuery for the
+// query syntax; an empty query lists the entries.
+func (s *VaultService) Search(ctx context.Context, userID int64, query string, filter models.EntryFilter, page models.PageRequest) (*models.EntryPage, error) {
 	// Use context for potential cancellation
 	select {
 	case <-ctx.Done():
@@ -62,48 +584,158 @@ func (s *VaultService) Search(ctx context.Context, userID int64, query string) (
 	default:
 	}
 
-	if query == "" {
-		return s.List(userID)
+	if strings.TrimSpace(query) == "" {
+		return s.listPage(userID, nil, filter, page)
 	}
-
-	entries, err := s.repo.SearchByWebsite(userID, query)
+	terms, err := parseSearchQuery(query)
 	if err != nil {
 		return nil, err
 	}
-
-	// Clear passwords from search results
-	for i := range entries {
-		entries[i].Password = ""
-	}
-
-	return entries, nil
+	return s.listPage(userID, terms, filter, page)
 }
 
+// Create stores a new entry. Logins need a password; the other types carry
+// their fields in the member of entry.EntryData matching entry.Type.
 func (s *VaultService) Create(userID int64, entry models.VaultEntry) (int64, error) {
-	if entry.Title == "" || entry.Password == "" {
+	if entry.Type == "" {
+		entry.Type = models.EntryTypeLogin
+	}
+	if !ValidEntryType(entry.Type) {
+		return 0, errInvalidEntry("unknown entry type " + entry.Type)
+	}
+	if entry.Title == "" {
+		return 0, errors.New("title required")
+	}
+	if err := s.generateEntryPassword(&entry); err != nil {
+		return 0, err
+	}
+	if entry.Type == models.EntryTypeLogin && entry.Password == "" {
 		return 0, errors.New("title and password required")
 	}
+	if err := validateEntryShape(entry.Type, &entry, false); err != nil {
+		return 0, err
+	}
+	if err := validateFields(entry.Fields); err != nil {
+		return 0, err
+	}
+	if err := validateURIs(&entry); err != nil {
+		return 0, err
+	}
+	if err := s.checkLinks(userID, 0, entry.Fields); err != nil {
+		return 0, err
+	}
+	if entry.CollectionID != nil {
+		if err := s.requireWritableCollection(userID, *entry.CollectionID); err != nil {
+			return 0, err
+		}
+	} else if entry.RequiresApproval {
+		return 0, errApprovalNeedsCollection()
+	}
+	if entry.FolderID != nil {
+		if entry.CollectionID != nil {
+			return 0, errFolderNeedsPersonal()
+		}
+		if err := s.requireFolder(userID, *entry.FolderID); err != nil {
+			return 0, err
+		}
+	}
+	// Set before authorizing so folder rules can find the owner's folders
+	entry.UserID = userID
+	if err := s.authorize(userID, models.CapabilityCreate, &entry); err != nil {
+		return 0, err
+	}
 
-	enc, err := s.crypto.Encrypt(entry.Password)
+	_, cipher, keyEnc, err := s.newEntryKey()
+	if err != nil {
+		return 0, err
+	}
+	enc, err := cipher.Encrypt(entry.Password)
 	if err != nil {
 		return 0, err
 	}
+	if err := sealEntryData(cipher, &entry); err != nil {
+		return 0, err
+	}
+	if entry.Fields, err = sealFields(cipher, entry.Fields); err != nil {
+		return 0, err
+	}
 
 	now := time.Now().UTC()
 	entry.UserID = userID
 	entry.PasswordEnc = enc
+	entry.KeyEnc = keyEnc
 	entry.CreatedAt = now
 	entry.UpdatedAt = now
 
 	return s.repo.Create(entry)
 }
 
-func (s *VaultService) Update(userID, id int64, entry models.VaultEntry) error {
+// Update replaces the contents of entry id with entry. The protection flags
+// come from flags rather than entry, and those left nil stay as they are.
+func (s *VaultService) Update(userID, id int64, entry models.VaultEntry, flags models.EntryFlags, authTime time.Time) error {
 	if entry.Title == "" {
 		return errors.New("title required")
 	}
 
-	current, err := s.repo.GetByID(userID, id)
+	current, err := s.requireWritable(userID, id)
+	if err != nil {
+		return err
+	}
+	entry.Sensitive = flagOr(flags.Sensitive, current.Sensitive)
+	entry.RequiresApproval = flagOr(flags.RequiresApproval, current.RequiresApproval)
+	entry.CheckoutRequired = flagOr(flags.CheckoutRequired, current.CheckoutRequired)
+	if err := s.authorize(userID, models.CapabilityUpdate, current); err != nil {
+		return err
+	}
+	if entry.Type != "" && entry.Type != current.Type {
+		return errInvalidEntry("entry type cannot be changed")
+	}
+	if err := s.generateEntryPassword(&entry); err != nil {
+		return err
+	}
+	if err := validateEntryShape(current.Type, &entry, true); err != nil {
+		return err
+	}
+	if err := validateFields(entry.Fields); err != nil {
+		return err
+	}
+	if err := validateURIs(&entry); err != nil {
+		return err
+	}
+	if err := s.checkLinks(userID, id, entry.Fields); err != nil {
+		return err
+	}
+	// Only the holder may change a checked out entry, and the requirement
+	// can only be dropped once it is checked in
+	if current.CheckoutRequired {
+		if err := s.requireNotCheckedOut(userID, id, entry.CheckoutRequired); err != nil {
+			return err
+		}
+	}
+
+	// Clearing the flag would otherwise be a way around the step-up check
+	if current.Sensitive && !entry.Sensitive {
+		if err := s.requireRecentAuth(authTime); err != nil {
+			return err
+		}
+	}
+	if entry.RequiresApproval && current.CollectionID == nil {
+		return errApprovalNeedsCollection()
+	}
+	if current.RequiresApproval && !entry.RequiresApproval {
+		if err := s.requireApprover(userID, id, "only organization owners and admins can remove the approval requirement"); err != nil {
+			return err
+		}
+	}
+	// Otherwise any editor could drop the requirement between leases and
+	// read the password without checking it out
+	if current.CheckoutRequired && !entry.CheckoutRequired {
+		if err := s.requireProtector(userID, current, "only the owner, or organization owners and admins, can remove the checkout requirement"); err != nil {
+			return err
+		}
+	}
+
+	revision, err := s.snapshot(userID, current, &userID)
 	if err != nil {
 		return err
 	}
@@ -111,21 +743,315 @@ func (s *VaultService) Update(userID, id int64, entry models.VaultEntry) error {
 	current.Title = entry.Title
 	current.Username = entry.Username
 	current.URL = entry.URL
+	current.URLMatch = entry.URLMatch
+	current.URIs = entry.URIs
 	current.Category = entry.Category
 	current.Notes = entry.Notes
+	current.Sensitive = entry.Sensitive
+	current.RequiresApproval = entry.RequiresApproval
+	current.CheckoutRequired = entry.CheckoutRequired
 	current.UpdatedAt = time.Now().UTC()
+	// Renaming or recategorizing must not move the entry out of policy reach
+	if err := s.authorize(userID, models.CapabilityUpdate, current); err != nil {
+		return err
+	}
 
 	if entry.Password != "" {
-		enc, err := s.crypto.Encrypt(entry.Password)
+		cipher, err := s.entryCipher(userID, current)
+		if err != nil {
+			return err
+		}
+		enc, err := cipher.Encrypt(entry.Password)
 		if err != nil {
 			return err
 		}
 		current.PasswordEnc = enc
+		current.PasswordVersion++
+	}
+	// Type-specific fields are replaced as a whole when given
+	if entry.EntryData != (models.EntryData{}) {
+		cipher, err := s.entryCipher(userID, current)
+		if err != nil {
+			return err
+		}
+		current.EntryData = entry.EntryData
+		if err := sealEntryData(cipher, current); err != nil {
+			return err
+		}
+	}
+	// Custom fields are replaced as a whole unless omitted
+	if entry.Fields != nil {
+		cipher, err := s.entryCipher(userID, current)
+		if err != nil {
+			return err
+		}
+		if current.Fields, err = sealFields(cipher, entry.Fields); err != nil {
+			return err
+		}
 	}
 
-	return s.repo.Update(*current)
+	return s.repo.UpdateWithRevision(userID, *current, revision, s.historyLimit)
+}
+
+// flagOr returns *flag, or stored when the flag was not given.
+func flagOr(flag *bool, stored bool) bool {
+	if flag == nil {
+		return stored
+	}
+	return *flag
 }
 
+// Delete moves an entry to the trash; see Purge.
 func (s *VaultService) Delete(userID, id int64) error {
-	return s.repo.Delete(userID, id)
+	entry, err := s.requireManageable(userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(userID, models.CapabilityDelete, entry); err != nil {
+		return err
+	}
+	if err := s.repo.Trash(userID, id, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.audit.LogEvent(userID, id, "trashed")
+	return nil
+}
+
+// SealNoteBodies moves the bodies of secure notes written before they were
+// encrypted out of the plain notes column and into their sealed data. It
+// runs at startup, before any request can read them.
+func (s *VaultService) SealNoteBodies() (int, error) {
+	entries, err := s.repo.ListUnsealedNotes()
+	if err != nil {
+		return 0, err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		cipher, err := s.entryCipher(entry.UserID, entry)
+		if err != nil {
+			return i, err
+		}
+		if err := openEntryData(cipher, entry); err != nil {
+			return i, err
+		}
+		entry.Note = &models.NoteData{Text: entry.Notes}
+		if err := sealEntryData(cipher, entry); err != nil {
+			return i, err
+		}
+		if err := s.repo.SealNote(entry.ID, entry.DataEnc); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// rotatePassword ends checkout with status and replaces its entry's
+// password with a generated one, atomically. It acts for no particular
+// user, so it uses the server-wrapped entry key. ok is false when the
+// checkout had already ended and nothing was changed.
+func (s *VaultService) rotatePassword(checkout *models.Checkout, status string) (version int, ok bool, err error) {
+	entry, err := s.repo.GetForSystem(checkout.EntryID)
+	if err != nil {
+		return 0, false, err
+	}
+	password, err := generatePassword(rotatedPasswordLength, passwordAlphabet)
+	if err != nil {
+		return 0, false, err
+	}
+	cipher, err := s.entryCipher(entry.UserID, entry)
+	if err != nil {
+		return 0, false, err
+	}
+	enc, err := cipher.Encrypt(password)
+	if err != nil {
+		return 0, false, err
+	}
+	revision, err := s.snapshot(entry.UserID, entry, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	ok, err = s.repo.RotatePassword(checkout.ID, status, enc, time.Now().UTC(), revision, s.historyLimit)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	return entry.PasswordVersion + 1, true, nil
+}
+
+// Move puts an entry into an organization collection, or back into the
+// caller's personal vault when collectionID is nil. The caller needs write
+// access on both sides, and only owners and admins of the organization may
+// move its entries out of it.
+func (s *VaultService) Move(userID, id int64, collectionID *int64) error {
+	current, err := s.requireManageable(userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(userID, models.CapabilityUpdate, current); err != nil {
+		return err
+	}
+	// Taking an entry out of its organization removes every other member's
+	// access, so it is not left to whoever can write the collection
+	if current.CollectionID != nil {
+		leaving := collectionID == nil
+		if !leaving {
+			same, err := s.repo.SameOrganization(*current.CollectionID, *collectionID)
+			if err != nil {
+				return err
+			}
+			leaving = !same
+		}
+		if leaving {
+			if err := s.requireApprover(userID, id, "only organization owners and admins can move entries out of the organization"); err != nil {
+				return err
+			}
+		}
+	}
+	// Moving an entry is otherwise a way around its approval requirement
+	if current.RequiresApproval {
+		if err := s.requireApprover(userID, id, "only organization owners and admins can move entries that require approval"); err != nil {
+			return err
+		}
+	}
+
+	if collectionID != nil {
+		if err := s.requireWritableCollection(userID, *collectionID); err != nil {
+			return err
+		}
+	} else {
+		current.UserID = userID
+		current.RequiresApproval = false
+	}
+	// Folders only organize personal vaults
+	if collectionID != nil {
+		current.FolderID = nil
+	}
+	current.CollectionID = collectionID
+	current.UpdatedAt = time.Now().UTC()
+	if err := s.authorize(userID, models.CapabilityUpdate, current); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(userID, *current); err != nil {
+		return err
+	}
+	// Personal shares do not carry over into an organization
+	if collectionID != nil {
+		if err := s.shares.DeleteByEntry(id); err != nil {
+			return err
+		}
+	}
+	s.audit.LogEvent(userID, id, "moved")
+	return nil
+}
+
+// SetFolder files one of userID's personal entries in folderID, or takes it
+// out of its folder when folderID is nil.
+func (s *VaultService) SetFolder(userID, id int64, folderID *int64) error {
+	current, err := s.requireManageable(userID, id)
+	if err != nil {
+		return err
+	}
+	if current.CollectionID != nil || current.UserID != userID {
+		return errFolderNeedsPersonal()
+	}
+	if err := s.authorize(userID, models.CapabilityUpdate, current); err != nil {
+		return err
+	}
+	if folderID != nil {
+		if err := s.requireFolder(userID, *folderID); err != nil {
+			return err
+		}
+	}
+
+	current.FolderID = folderID
+	current.UpdatedAt = time.Now().UTC()
+	// Policies may select on folders, so the entry must stay in reach
+	if err := s.authorize(userID, models.CapabilityUpdate, current); err != nil {
+		return err
+	}
+	return s.repo.Update(userID, *current)
+}
+
+// generateEntryPassword sets entry's password to one made to
+// entry.Generate, so a generated password is stored without passing
+// through the client. It does nothing when Generate is nil.
+func (s *VaultService) generateEntryPassword(entry *models.VaultEntry) error {
+	if entry.Generate == nil {
+		return nil
+	}
+	if entry.Password != "" {
+		return errInvalidEntry("give either password or generate, not both")
+	}
+	res, err := s.generator.Generate(*entry.Generate)
+	if err != nil {
+		return err
+	}
+	entry.Password = res.Password
+	return nil
+}
+
+// validateEntryShape checks the fields of entry against entryType. With
+// partial set, as on update, entry.EntryData may be empty to keep the
+// stored fields.
+func validateEntryShape(entryType string, entry *models.VaultEntry, partial bool) error {
+	if entryType != models.EntryTypeLogin {
+		if entry.Password != "" {
+			return errInvalidEntry("only logins have a password")
+		}
+		if entry.CheckoutRequired {
+			return errInvalidEntry("only logins can require checkout")
+		}
+	}
+	// A note's body is sealed with the rest of its data rather than kept in
+	// the plain notes column, which is indexed for search
+	if entryType == models.EntryTypeNote && entry.Notes != "" {
+		if entry.Note != nil {
+			return errInvalidEntry("give a note's text in note or notes, not both")
+		}
+		entry.Note = &models.NoteData{Text: entry.Notes}
+		entry.Notes = ""
+	}
+	if partial && entry.EntryData == (models.EntryData{}) {
+		return nil
+	}
+	return validateEntryData(entryType, &entry.EntryData)
+}
+
+// validateEntryFilter checks filter and normalizes its tags.
+func (s *VaultService) validateEntryFilter(userID int64, filter *models.EntryFilter) error {
+	if filter.Type != "" && !ValidEntryType(filter.Type) {
+		return errInvalidEntry("unknown entry type " + filter.Type)
+	}
+	if len(filter.Tags) > 0 {
+		tags, err := normalizeTags(filter.Tags)
+		if err != nil {
+			return err
+		}
+		filter.Tags = tags
+	}
+	if filter.FolderID != 0 {
+		return s.requireFolder(userID, filter.FolderID)
+	}
+	return nil
+}
+
+// requireFolder fails with ErrNotFound unless folderID is one of userID's
+// folders.
+func (s *VaultService) requireFolder(userID, folderID int64) error {
+	ok, err := s.repo.HasFolder(userID, folderID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vaulterrors.NewVaultError(vaulterrors.ErrNotFound, "folder not found")
+	}
+	return nil
+}
+
+func errFolderNeedsPersonal() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "only entries in your personal vault can be filed in folders")
+}
+
+func errApprovalNeedsCollection() error {
+	return vaulterrors.NewVaultError(vaulterrors.ErrInvalidInput, "only collection entries can require approval")
 }
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+type vaultTest struct {
+	db     *sql.DB
+	crypto *CryptoService
+	auth   *AuthService
+	keys   *KeyService
+	vault  *VaultService
+	shares *ShareService
+}
+
+// newVaultTest returns a vault and the services around it over a fresh
+// database.
+func newVaultTest(t *testing.T) *vaultTest {
+	t.Helper()
+	database := newTestDB(t)
+	crypto := newTestCrypto(t)
+	audit := newTestAudit(t, database)
+	users := repository.NewUserRepository(database)
+	shareRepo := repository.NewShareRepository(database)
+	keys := NewKeyService(repository.NewKeyRepository(database), crypto, time.Hour, 5*time.Minute)
+	vault := NewVaultService(
+		repository.NewVaultRepository(database),
+		shareRepo,
+		repository.NewAccessRepository(database),
+		repository.NewCheckoutRepository(database),
+		NewPolicyService(repository.NewPolicyRepository(database), audit),
+		keys,
+		crypto,
+		audit,
+		NewPasswordGenerator(EFFWordlist()),
+		5*time.Minute,
+		20,
+		30*24*time.Hour,
+	)
+	return &vaultTest{
+		db:     database,
+		crypto: crypto,
+		auth:   NewAuthService(users, "test-secret", time.Hour),
+		keys:   keys,
+		vault:  vault,
+		shares: NewShareService(vault, shareRepo, users, keys, audit),
+	}
+}
+
+// register creates a user with a password of "password".
+func (v *vaultTest) register(t *testing.T, email string) int64 {
+	t.Helper()
+	id, err := v.auth.Register(email, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// createLegacy stores a login the way entries were written before per-entry
+// keys: no key_enc and the password under the server key.
+func (v *vaultTest) createLegacy(t *testing.T, userID int64, title, password string) int64 {
+	t.Helper()
+	enc, err := v.crypto.Encrypt(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := v.db.Exec(
+		`INSERT INTO vault_entries (user_id, type, title, username, password_enc, url, category, notes, created_at, updated_at)
+		VALUES (?, 'login', ?, '', ?, '', '', '', ?, ?)`,
+		userID, title, enc, now, now,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestSharingLegacyEntryKeepsHiddenFields(t *testing.T) {
+	v := newVaultTest(t)
+	owner := v.register(t, "owner@example.com")
+	recipient := v.register(t, "recipient@example.com")
+	if err := v.keys.SetPassphrase(recipient, "", "recipient passphrase", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	id := v.createLegacy(t, owner, "Legacy", "hunter2")
+	// Added after the series, while the entry still has no key of its own
+	if err := v.vault.Update(owner, id, models.VaultEntry{
+		Title:  "Legacy",
+		Fields: []models.CustomField{{Name: "PIN", Type: models.FieldHidden, Value: "4321"}},
+	}, models.EntryFlags{}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.shares.Share(owner, id, "recipient@example.com", models.SharePermissionEdit); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, userID := range map[string]int64{"owner": owner, "recipient": recipient} {
+		entry, err := v.vault.Get(userID, id, time.Now())
+		if err != nil {
+			t.Fatalf("%s get: %v", name, err)
+		}
+		if entry.Password != "hunter2" || len(entry.Fields) != 1 || entry.Fields[0].Value != "4321" {
+			t.Fatalf("%s read password %q, fields %+v", name, entry.Password, entry.Fields)
+		}
+	}
+
+	// Writing snapshots the entry, so history and restore must open it too
+	if err := v.vault.Update(recipient, id, models.VaultEntry{Title: "Renamed"}, models.EntryFlags{}, time.Now()); err != nil {
+		t.Fatalf("update after sharing: %v", err)
+	}
+	history, err := v.vault.History(owner, id, time.Now())
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("%d revisions, want 2", len(history))
+	}
+	if err := v.vault.Restore(owner, id, history[len(history)-1].Revision, time.Now()); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"vault/internal/models"
+	"vault/internal/repository"
+)
+
+const webauthnSessionTTL = 5 * time.Minute
+
+const (
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+	// ReauthWindow is how recent auth_time must be to add or remove passkeys
+	// or change the second-factor setting
+	ReauthWindow time.Duration
+}
+
+// webauthnSession is the server half of a ceremony between begin and finish.
+type webauthnSession struct {
+	ceremony string
+	data     webauthn.SessionData
+	// userID is zero for discoverable (usernameless) logins
+	userID int64
+	// mfaToken is set when the login completes a password login
+	mfaToken  string
+	expiresAt time.Time
+}
+
+// webauthnUser adapts a models.User to the webauthn.User interface.
+type webauthnUser struct {
+	user        *models.User
+	credentials []webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	handle, _ := base64.RawURLEncoding.DecodeString(u.user.WebAuthnHandle)
+	return handle
+}
+
+func (u *webauthnUser) WebAuthnName() string                       { return u.user.Email }
+func (u *webauthnUser) WebAuthnDisplayName() string                { return u.user.Email }
+func (u *webauthnUser) WebAuthnIcon() string                       { return "" }
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// WebAuthnService runs passkey registration and assertion ceremonies.
+// Passkeys work as a passwordless primary login or as a second factor
+// after a password login when the user enables it.
+type WebAuthnService struct {
+	wa    *webauthn.WebAuthn
+	users *repository.UserRepository
+	creds *repository.WebAuthnRepository
+	auth  *AuthService
+	audit *AuditService
+	// reauthWindow guards credential management against stolen old tokens
+	reauthWindow time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]webauthnSession
+}
+
+func NewWebAuthnService(cfg WebAuthnConfig, users *repository.UserRepository, creds *repository.WebAuthnRepository, auth *AuthService, audit *AuditService) (*WebAuthnService, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnService{
+		wa:           wa,
+		users:        users,
+		creds:        creds,
+		auth:         auth,
+		audit:        audit,
+		reauthWindow: cfg.ReauthWindow,
+		sessions:     map[string]webauthnSession{},
+	}, nil
+}
+
+// BeginRegistration returns creation options for a new passkey and the id
+// of the session that FinishRegistration must be called with. Like the other
+// credential changes it needs a recent login (authTime).
+func (s *WebAuthnService) BeginRegistration(userID int64, authTime time.Time) (string, *protocol.CredentialCreation, error) {
+	if err := requireAuthWithin(authTime, s.reauthWindow); err != nil {
+		return "", nil, err
+	}
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if user.WebAuthnHandle == "" {
+		handle, err := randomURLToken(32)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := s.users.SetWebAuthnHandle(userID, handle); err != nil {
+			return "", nil, err
+		}
+		user.WebAuthnHandle = handle
+	}
+
+	wu, err := s.loadUser(user)
+	if err != nil {
+		return "", nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(wu.credentials))
+	for _, cred := range wu.credentials {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	creation, data, err := s.wa.BeginRegistration(
+		wu,
+		webauthn.WithExclusions(exclusions),
+		// Discoverable credentials are what make passwordless login possible
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sessionID, err := s.saveSession(webauthnSession{ceremony: ceremonyRegister, data: *data, userID: userID})
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionID, creation, nil
+}
+
+// FinishRegistration verifies the authenticator's attestation response and
+// stores the new credential.
+func (s *WebAuthnService) FinishRegistration(userID int64, sessionID, name string, response []byte) (*models.WebAuthnCredential, error) {
+	session, ok := s.takeSession(sessionID, ceremonyRegister)
+	if !ok || session.userID != userID {
+		return nil, errors.New("invalid or expired registration session")
+	}
+
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	wu, err := s.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return nil, err
+	}
+	cred, err := s.wa.CreateCredential(wu, session.data, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	record := models.WebAuthnCredential{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: base64.RawURLEncoding.EncodeToString(cred.ID),
+		Data:         string(data),
+		CreatedAt:    time.Now().UTC(),
+	}
+	id, err := s.creds.Create(record)
+	if err != nil {
+		return nil, err
+	}
+	record.ID = id
+
+	s.audit.LogEvent(userID, 0, "webauthn.registered")
+	return &record, nil
+}
+
+// BeginLogin starts an assertion. With an mfaToken from Login the passkey
+// completes that password login; with an email only that user's passkeys
+// are offered; with neither the authenticator picks a discoverable passkey.
+func (s *WebAuthnService) BeginLogin(email, mfaToken string) (string, *protocol.CredentialAssertion, error) {
+	var user *models.User
+	switch {
+	case mfaToken != "":
+		userID, err := s.auth.MFAChallengeUser(mfaToken)
+		if err != nil {
+			return "", nil, err
+		}
+		if user, err = s.users.GetByID(userID); err != nil {
+			return "", nil, err
+		}
+	case email != "":
+		// Unknown emails fall through to a discoverable login so the
+		// response does not reveal which accounts exist
+		user, _ = s.users.GetByEmail(email)
+	}
+
+	var wu *webauthnUser
+	if user != nil {
+		loaded, err := s.loadUser(user)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(loaded.credentials) > 0 {
+			wu = loaded
+		} else if mfaToken != "" {
+			return "", nil, errors.New("no passkeys registered")
+		}
+	}
+
+	// A passkey used on its own must prove user verification (PIN or
+	// biometric) to count as more than one factor
+	verification := protocol.VerificationRequired
+	if mfaToken != "" {
+		verification = protocol.VerificationPreferred
+	}
+
+	var (
+		assertion *protocol.CredentialAssertion
+		data      *webauthn.SessionData
+		err       error
+	)
+	session := webauthnSession{ceremony: ceremonyLogin, mfaToken: mfaToken}
+	if wu != nil {
+		assertion, data, err = s.wa.BeginLogin(wu, webauthn.WithUserVerification(verification))
+		session.userID = user.ID
+	} else {
+		assertion, data, err = s.wa.BeginDiscoverableLogin(webauthn.WithUserVerification(verification))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	session.data = *data
+
+	sessionID, err := s.saveSession(session)
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionID, assertion, nil
+}
+
+// FinishLogin verifies an assertion and returns a vault JWT.
+func (s *WebAuthnService) FinishLogin(sessionID string, response []byte) (string, *models.User, error) {
+	session, ok := s.takeSession(sessionID, ceremonyLogin)
+	if !ok {
+		return "", nil, errors.New("invalid or expired login session")
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return "", nil, errInvalidCredentials
+	}
+
+	var user *models.User
+	var cred *webauthn.Credential
+	if session.userID != 0 {
+		if user, err = s.users.GetByID(session.userID); err != nil {
+			return "", nil, errInvalidCredentials
+		}
+		wu, err := s.loadUser(user)
+		if err != nil {
+			return "", nil, err
+		}
+		cred, err = s.wa.ValidateLogin(wu, session.data, parsed)
+	} else {
+		cred, err = s.wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			found, err := s.users.GetByWebAuthnHandle(base64.RawURLEncoding.EncodeToString(userHandle))
+			if err != nil {
+				return nil, err
+			}
+			user = found
+			return s.loadUser(found)
+		}, session.data, parsed)
+	}
+	if err != nil || user == nil {
+		return "", nil, errInvalidCredentials
+	}
+	if cred.Authenticator.CloneWarning {
+		return "", nil, errors.New("authenticator sign count went backwards; it may be cloned")
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.creds.UpdateAfterLogin(base64.RawURLEncoding.EncodeToString(cred.ID), string(data), time.Now().UTC()); err != nil {
+		return "", nil, err
+	}
+
+	if session.mfaToken != "" {
+		token, user, err := s.auth.CompleteMFA(session.mfaToken, user.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		s.audit.LogEvent(user.ID, 0, "webauthn.mfa")
+		return token, user, nil
+	}
+
+	token, err := s.auth.IssueToken(user, AllScopes)
+	if err != nil {
+		return "", nil, err
+	}
+	s.audit.LogEvent(user.ID, 0, "webauthn.login")
+	return token, user, nil
+}
+
+func (s *WebAuthnService) ListCredentials(userID int64) ([]models.WebAuthnCredential, error) {
+	return s.creds.ListByUser(userID)
+}
+
+// DeleteCredential removes a passkey; removing the last one also turns the
+// second-factor requirement off so the user is not locked out.
+func (s *WebAuthnService) DeleteCredential(userID, id int64, authTime time.Time) error {
+	if err := requireAuthWithin(authTime, s.reauthWindow); err != nil {
+		return err
+	}
+	found, err := s.creds.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("credential not found")
+	}
+
+	remaining, err := s.creds.CountByUser(userID)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		if err := s.users.SetWebAuthnMFA(userID, false); err != nil {
+			return err
+		}
+	}
+
+	s.audit.LogEvent(userID, 0, "webauthn.removed")
+	return nil
+}
+
+// SetMFA turns the passkey second-factor requirement for password logins on or off.
+func (s *WebAuthnService) SetMFA(userID int64, enabled bool, authTime time.Time) error {
+	if err := requireAuthWithin(authTime, s.reauthWindow); err != nil {
+		return err
+	}
+	if enabled {
+		count, err := s.creds.CountByUser(userID)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("register a passkey before enabling it as a second factor")
+		}
+	}
+	if err := s.users.SetWebAuthnMFA(userID, enabled); err != nil {
+		return err
+	}
+
+	action := "webauthn.mfa_disabled"
+	if enabled {
+		action = "webauthn.mfa_enabled"
+	}
+	s.audit.LogEvent(userID, 0, action)
+	return nil
+}
+
+func (s *WebAuthnService) loadUser(user *models.User) (*webauthnUser, error) {
+	records, err := s.creds.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	wu := &webauthnUser{user: user}
+	for _, record := range records {
+		var cred webauthn.Credential
+		if err := json.Unmarshal([]byte(record.Data), &cred); err != nil {
+			return nil, err
+		}
+		wu.credentials = append(wu.credentials, cred)
+	}
+	return wu, nil
+}
+
+func (s *WebAuthnService) saveSession(session webauthnSession) (string, error) {
+	id, err := randomURLToken(24)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session.expiresAt = now.Add(webauthnSessionTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, sess := range s.sessions {
+		if now.After(sess.expiresAt) {
+			delete(s.sessions, key)
+		}
+	}
+	s.sessions[id] = session
+	return id, nil
+}
+
+func (s *WebAuthnService) takeSession(id, ceremony string) (webauthnSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	delete(s.sessions, id)
+	if !ok || session.ceremony != ceremony || time.Now().After(session.expiresAt) {
+		return webauthnSession{}, false
+	}
+	return session, true
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+
+	vaulterrors "vault/internal/errors"
+	"vault/internal/repository"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:8080"
+)
+
+// softAuthenticator is a software passkey: a P-256 key that answers
+// ceremonies with "none" attestation, always reporting user presence and
+// verification.
+type softAuthenticator struct {
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{origin: testOrigin, key: key, credentialID: id}
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, challenge []byte) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// authData encodes authenticator data for rpID, with the attested
+// credential when attested is set.
+func (a *softAuthenticator) authData(t *testing.T, rpID string, attested bool) []byte {
+	t.Helper()
+	rpHash := sha256.Sum256([]byte(rpID))
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified
+	if attested {
+		flags |= protocol.FlagAttestedCredentialData
+	}
+	var buf bytes.Buffer
+	buf.Write(rpHash[:])
+	buf.WriteByte(byte(flags))
+	_ = binary.Write(&buf, binary.BigEndian, a.signCount)
+	if attested {
+		buf.Write(make([]byte, 16)) // AAGUID
+		_ = binary.Write(&buf, binary.BigEndian, uint16(len(a.credentialID)))
+		buf.Write(a.credentialID)
+		key, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+			PublicKeyData: webauthncose.PublicKeyData{
+				KeyType:   int64(webauthncose.EllipticKey),
+				Algorithm: int64(webauthncose.AlgES256),
+			},
+			Curve:  int64(webauthncose.P256),
+			XCoord: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+			YCoord: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(key)
+	}
+	return buf.Bytes()
+}
+
+// create answers a registration ceremony.
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation) []byte {
+	t.Helper()
+	options := creation.Response
+	handle, ok := options.User.ID.(protocol.URLEncodedBase64)
+	if !ok {
+		t.Fatalf("user id is %T", options.User.ID)
+	}
+	a.userHandle = handle
+
+	attestation, err := webauthncbor.Marshal(struct {
+		Format       string         `cbor:"fmt"`
+		AttStatement map[string]any `cbor:"attStmt"`
+		AuthData     []byte         `cbor:"authData"`
+	}{"none", map[string]any{}, a.authData(t, options.RelyingParty.ID, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := json.Marshal(protocol.CredentialCreationResponse{
+		PublicKeyCredential: a.publicKeyCredential(),
+		AttestationResponse: protocol.AuthenticatorAttestationResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{
+				ClientDataJSON: a.clientData(t, "webauthn.create", options.Challenge),
+			},
+			AttestationObject: attestation,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// get answers an assertion ceremony, counting the signature.
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion) []byte {
+	t.Helper()
+	a.signCount++
+	options := assertion.Response
+	authData := a.authData(t, options.RelyingPartyID, false)
+	clientData := a.clientData(t, "webauthn.get", options.Challenge)
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := json.Marshal(protocol.CredentialAssertionResponse{
+		PublicKeyCredential: a.publicKeyCredential(),
+		AssertionResponse: protocol.AuthenticatorAssertionResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: clientData},
+			AuthenticatorData:     authData,
+			Signature:             signature,
+			UserHandle:            a.userHandle,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func (a *softAuthenticator) publicKeyCredential() protocol.PublicKeyCredential {
+	return protocol.PublicKeyCredential{
+		Credential: protocol.Credential{
+			ID:   base64.RawURLEncoding.EncodeToString(a.credentialID),
+			Type: "public-key",
+		},
+		RawID: a.credentialID,
+	}
+}
+
+type webauthnTest struct {
+	webauthn *WebAuthnService
+	auth     *AuthService
+	userID   int64
+}
+
+// newWebAuthnTest returns a passkey service with one local user,
+// user@example.com, whose password is "password".
+func newWebAuthnTest(t *testing.T) *webauthnTest {
+	t.Helper()
+	database := newTestDB(t)
+	users := repository.NewUserRepository(database)
+	auth := NewAuthService(users, "test-secret", time.Hour)
+	svc, err := NewWebAuthnService(
+		WebAuthnConfig{RPID: testRPID, RPDisplayName: "Vault", RPOrigins: []string{testOrigin}, ReauthWindow: 5 * time.Minute},
+		users,
+		repository.NewWebAuthnRepository(database),
+		auth,
+		newTestAudit(t, database),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := auth.Register("user@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &webauthnTest{webauthn: svc, auth: auth, userID: id}
+}
+
+// register runs a registration ceremony for the test user with authenticator.
+func (w *webauthnTest) register(t *testing.T, authenticator *softAuthenticator) {
+	t.Helper()
+	sessionID, creation, err := w.webauthn.BeginRegistration(w.userID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := w.webauthn.FinishRegistration(w.userID, sessionID, "Laptop", authenticator.create(t, creation))
+	if err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+	if cred.Name != "Laptop" || cred.CredentialID != base64.RawURLEncoding.EncodeToString(authenticator.credentialID) {
+		t.Fatalf("stored %+v", cred)
+	}
+}
+
+// login runs an assertion ceremony started with email and mfaToken.
+func (w *webauthnTest) login(t *testing.T, authenticator *softAuthenticator, email, mfaToken string) (string, error) {
+	t.Helper()
+	sessionID, assertion, err := w.webauthn.BeginLogin(email, mfaToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, user, err := w.webauthn.FinishLogin(sessionID, authenticator.get(t, assertion))
+	if err == nil && user.ID != w.userID {
+		t.Fatalf("logged in as user %d, want %d", user.ID, w.userID)
+	}
+	return token, err
+}
+
+func TestWebAuthnPasswordlessLogin(t *testing.T) {
+	w := newWebAuthnTest(t)
+	authenticator := newSoftAuthenticator(t)
+	w.register(t, authenticator)
+
+	// Discoverable: the authenticator names the user through its handle
+	if token, err := w.login(t, authenticator, "", ""); err != nil || token == "" {
+		t.Fatalf("discoverable login: %q, %v", token, err)
+	}
+	// With an email only that user's passkeys are allowed
+	if token, err := w.login(t, authenticator, "user@example.com", ""); err != nil || token == "" {
+		t.Fatalf("login by email: %q, %v", token, err)
+	}
+}
+
+func TestWebAuthnRejectsUnregisteredKey(t *testing.T) {
+	w := newWebAuthnTest(t)
+	authenticator := newSoftAuthenticator(t)
+	w.register(t, authenticator)
+
+	// Same credential id and handle, different key
+	impostor := newSoftAuthenticator(t)
+	impostor.credentialID = authenticator.credentialID
+	impostor.userHandle = authenticator.userHandle
+	if _, err := w.login(t, impostor, "", ""); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("got %v, want invalid credentials", err)
+	}
+}
+
+func TestWebAuthnRejectsWrongOrigin(t *testing.T) {
+	w := newWebAuthnTest(t)
+	authenticator := newSoftAuthenticator(t)
+	authenticator.origin = "https://phish.example"
+
+	sessionID, creation, err := w.webauthn.BeginRegistration(w.userID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.webauthn.FinishRegistration(w.userID, sessionID, "", authenticator.create(t, creation)); err == nil {
+		t.Fatal("registered a passkey from another origin")
+	}
+}
+
+func TestWebAuthnSessionsAreSingleUse(t *testing.T) {
+	w := newWebAuthnTest(t)
+	authenticator := newSoftAuthenticator(t)
+	w.register(t, authenticator)
+
+	sessionID, assertion, err := w.webauthn.BeginLogin("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := authenticator.get(t, assertion)
+	if _, _, err := w.webauthn.FinishLogin(sessionID, response); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.webauthn.FinishLogin(sessionID, response); err == nil {
+		t.Fatal("replayed an assertion")
+	}
+}
+
+func TestWebAuthnDetectsClonedAuthenticator(t *testing.T) {
+	w := newWebAuthnTest(t)
+	authenticator := newSoftAuthenticator(t)
+	w.register(t, authenticator)
+	if _, err := w.login(t, authenticator, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.login(t, authenticator, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// A copy of the key still at an earlier signature count
+	clone := *authenticator
+	clone.signCount = 0
+	if _, err := w.login(t, &clone, "", ""); err == nil {
+		t.Fatal("accepted an assertion whose sign count went backwards")
+	}
+}
+
+func TestWebAuthnSecondFactor(t *testing.T) {
+	w := newWebAuthnTest(t)
+	authenticator := newSoftAuthenticator(t)
+	w.register(t, authenticator)
+	if err := w.webauthn.SetMFA(w.userID, true, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := w.auth.Login("user@example.com", "password", nil)
+	var mfa *MFARequiredError
+	if !errors.As(err, &mfa) {
+		t.Fatalf("password login: got %v, want a second factor challenge", err)
+	}
+	token, err := w.login(t, authenticator, "", mfa.Token)
+	if err != nil || token == "" {
+		t.Fatalf("second factor: %q, %v", token, err)
+	}
+	// The challenge is consumed
+	if _, err := w.auth.MFAChallengeUser(mfa.Token); err == nil {
+		t.Fatal("mfa token still valid after use")
+	}
+}
+
+func TestWebAuthnSecondFactorNeedsPasskey(t *testing.T) {
+	w := newWebAuthnTest(t)
+	if err := w.webauthn.SetMFA(w.userID, true, time.Now()); err == nil {
+		t.Fatal("enabled the second factor without a passkey")
+	}
+}
+
+func TestWebAuthnCredentialChangesNeedRecentAuth(t *testing.T) {
+	w := newWebAuthnTest(t)
+	authenticator := newSoftAuthenticator(t)
+	w.register(t, authenticator)
+	creds, err := w.webauthn.ListCredentials(w.userID)
+	if err != nil || len(creds) != 1 {
+		t.Fatalf("credentials: %v, %v", creds, err)
+	}
+
+	for name, authTime := range map[string]time.Time{
+		"stale":     time.Now().Add(-time.Hour),
+		"api token": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := w.webauthn.BeginRegistration(w.userID, authTime); vaultErrorCode(err) != vaulterrors.ErrReauthRequired {
+				t.Fatalf("begin registration: got %v, want re-authentication required", err)
+			}
+			if err := w.webauthn.SetMFA(w.userID, true, authTime); vaultErrorCode(err) != vaulterrors.ErrReauthRequired {
+				t.Fatalf("enable mfa: got %v, want re-authentication required", err)
+			}
+			if err := w.webauthn.DeleteCredential(w.userID, creds[0].ID, authTime); vaultErrorCode(err) != vaulterrors.ErrReauthRequired {
+				t.Fatalf("delete credential: got %v, want re-authentication required", err)
+			}
+		})
+	}
+
+	// Nothing changed, and a fresh login may go ahead
+	if creds, err := w.webauthn.ListCredentials(w.userID); err != nil || len(creds) != 1 {
+		t.Fatalf("credentials after refused changes: %v, %v", creds, err)
+	}
+	if err := w.webauthn.DeleteCredential(w.userID, creds[0].ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+}
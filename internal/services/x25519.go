@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const sealInfo = "vault x25519 entry key v1"
+
+// sealX25519 encrypts msg so only the holder of pub's private key can read
+// it: an ephemeral X25519 agreement, HKDF-SHA256 over the shared secret and
+// both public keys, then AES-256-GCM. The result is
+// base64(ephemeral public key || nonce || ciphertext).
+func sealX25519(pub *ecdh.PublicKey, msg []byte) (string, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	shared, err := ephemeral.ECDH(pub)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := sealCipher(shared, ephemeral.PublicKey().Bytes(), pub.Bytes())
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	out := append(ephemeral.PublicKey().Bytes(), nonce...)
+	out = gcm.Seal(out, nonce, msg, nil)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+func openX25519(priv *ecdh.PrivateKey, sealed string) ([]byte, error) {
+	payload, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < 32 {
+		return nil, errors.New("sealed key too short")
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(payload[:32])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := priv.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := sealCipher(shared, payload[:32], priv.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	rest := payload[32:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, errors.New("sealed key too short")
+	}
+	return gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+}
+
+func sealCipher(shared, ephemeralPub, recipientPub []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeralPub...), recipientPub...)
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(sealInfo)), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -40,6 +40,18 @@ func main() {
 
 	userRepo := repository.NewUserRepository(database)
 	vaultRepo := repository.NewVaultRepository(database)
+	tokenRepo := repository.NewTokenRepository(database)
+	webauthnRepo := repository.NewWebAuthnRepository(database)
+	orgRepo := repository.NewOrgRepository(database)
+	keyRepo := repository.NewKeyRepository(database)
+	shareRepo := repository.NewShareRepository(database)
+	sendRepo := repository.NewSendRepository(database)
+	emergencyRepo := repository.NewEmergencyRepository(database)
+	accessRepo := repository.NewAccessRepository(database)
+	checkoutRepo := repository.NewCheckoutRepository(database)
+	folderRepo := repository.NewFolderRepository(database)
+	domainRepo := repository.NewDomainRepository(database)
+	policyRepo := repository.NewPolicyRepository(database)
 
 	cryptoSvc, err := services.NewCryptoService(cfg.EncryptionKey)
 	if err != nil {
@@ -51,27 +63,250 @@ func main() {
 	workerPool := services.NewWorkerPool(cfg.WorkerPoolSize)
 
 	authSvc := services.NewAuthService(userRepo, cfg.JWTSecret, cfg.TokenTTL)
-	vaultSvc := services.NewVaultService(vaultRepo, cryptoSvc, auditSvc)
+	if cfg.LDAPURL != "" {
+		authSvc.AddBackend(services.AuthBackendLDAP, services.NewLDAPAuthenticator(services.LDAPConfig{
+			URL:               cfg.LDAPURL,
+			StartTLS:          cfg.LDAPStartTLS,
+			BindDN:            cfg.LDAPBindDN,
+			BindPassword:      cfg.LDAPBindPassword,
+			UserDNTemplate:    cfg.LDAPUserDNTemplate,
+			BaseDN:            cfg.LDAPBaseDN,
+			UserFilter:        cfg.LDAPUserFilter,
+			EmailAttribute:    cfg.LDAPEmailAttribute,
+			RequiredGroup:     cfg.LDAPRequiredGroup,
+			LinkExistingUsers: cfg.AuthBackend == services.AuthBackendLDAP,
+		}, userRepo, auditSvc))
+	}
+	if err := authSvc.SetDefaultBackend(cfg.AuthBackend); err != nil {
+		log.Fatalf("auth config error: %v", err)
+	}
+	keySvc := services.NewKeyService(keyRepo, cryptoSvc, cfg.TokenTTL, cfg.ReauthWindow)
+	policySvc := services.NewPolicyService(policyRepo, auditSvc)
+	wordlist := services.EFFWordlist()
+	if cfg.PassphraseWordlist != "" {
+		if wordlist, err = services.LoadWordlist(cfg.PassphraseWordlist); err != nil {
+			log.Fatalf("passphrase wordlist error: %v", err)
+		}
+	}
+	generator := services.NewPasswordGenerator(wordlist)
+	vaultSvc := services.NewVaultService(vaultRepo, shareRepo, accessRepo, checkoutRepo, policySvc, keySvc, cryptoSvc, auditSvc, generator, cfg.ReauthWindow, cfg.EntryHistoryLimit, cfg.TrashRetention)
+	if _, err := vaultSvc.SealNoteBodies(); err != nil {
+		log.Fatalf("note migration error: %v", err)
+	}
+	shareSvc := services.NewShareService(vaultSvc, shareRepo, userRepo, keySvc, auditSvc)
+	sendSvc := services.NewSendService(sendRepo, vaultSvc, auditSvc, cfg.SendBaseURL)
+	tokenSvc := services.NewTokenService(tokenRepo, auditSvc)
+	adminSvc := services.NewAdminService(userRepo, webauthnRepo, auditSvc)
+	orgSvc := services.NewOrgService(orgRepo, userRepo, auditSvc)
+
+	var notifier services.Notifier = services.LogNotifier{}
+	if cfg.NotifyWebhookURL != "" {
+		notifier = services.NewWebhookNotifier(cfg.NotifyWebhookURL)
+	}
+	emergencySvc := services.NewEmergencyService(emergencyRepo, userRepo, vaultSvc, auditSvc, notifier)
+	accessSvc := services.NewAccessService(accessRepo, vaultSvc, auditSvc, notifier)
+	checkoutSvc := services.NewCheckoutService(checkoutRepo, vaultSvc, auditSvc, notifier)
+	folderSvc := services.NewFolderService(folderRepo, vaultSvc, auditSvc)
+	domainSvc := services.NewDomainService(domainRepo, vaultSvc, auditSvc)
+
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], adminSvc, auditSvc); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	webauthnSvc, err := services.NewWebAuthnService(services.WebAuthnConfig{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     cfg.WebAuthnRPOrigins,
+		ReauthWindow:  cfg.ReauthWindow,
+	}, userRepo, webauthnRepo, authSvc, auditSvc)
+	if err != nil {
+		log.Fatalf("webauthn config error: %v", err)
+	}
+
+	var oidcSvc *services.OIDCService
+	if cfg.OIDCIssuer != "" {
+		oidcSvc = services.NewOIDCService(services.OIDCConfig{
+			Issuer:         cfg.OIDCIssuer,
+			ClientID:       cfg.OIDCClientID,
+			ClientSecret:   cfg.OIDCClientSecret,
+			RedirectURL:    cfg.OIDCRedirectURL,
+			AllowedDomains: cfg.OIDCAllowedDomains,
+		}, userRepo, authSvc, auditSvc)
+		authSvc.ReserveDomainsForSSO(cfg.OIDCAllowedDomains)
+	}
 
 	app := fiber.New()
 	app.Use(recover.New())
 	app.Use(logger.New())
 
-	handler := handlers.NewHandler(authSvc, vaultSvc, workerPool)
+	handler := handlers.NewHandler(authSvc, vaultSvc, tokenSvc, oidcSvc, webauthnSvc, adminSvc, orgSvc, shareSvc, keySvc, sendSvc, emergencySvc, accessSvc, checkoutSvc, policySvc, folderSvc, domainSvc, generator, workerPool)
 
 	app.Get("/health", handlers.Health)
 
 	api := app.Group("/api")
 	api.Post("/auth/register", handler.Register)
 	api.Post("/auth/login", handler.Login)
+	if oidcSvc != nil {
+		api.Get("/auth/oidc/login", handler.OIDCLogin)
+		api.Get("/auth/oidc/callback", handler.OIDCCallback)
+	}
+
+	// One-time secret links are opened by people without an account
+	api.Get("/send/:sendId", handler.SendInfo)
+	api.Post("/send/:sendId/open", handler.OpenSend)
+
+	api.Post("/auth/webauthn/login/begin", handler.BeginPasskeyLogin)
+	api.Post("/auth/webauthn/login/finish", handler.FinishPasskeyLogin)
+
+	// Account security routes take a login JWT only, never an API token
+	requireLogin := middleware.JWT(cfg.JWTSecret, authSvc)
+	api.Post("/auth/webauthn/register/begin", requireLogin, handler.BeginPasskeyRegistration)
+	api.Post("/auth/webauthn/register/finish", requireLogin, handler.FinishPasskeyRegistration)
+	api.Get("/auth/webauthn/credentials", requireLogin, handler.ListPasskeys)
+	api.Delete("/auth/webauthn/credentials/:id", requireLogin, handler.DeletePasskey)
+	api.Put("/auth/webauthn/mfa", requireLogin, handler.SetPasskeyMFA)
+	api.Get("/auth/keys", requireLogin, handler.GetKeyStatus)
+	api.Put("/auth/keys", requireLogin, handler.SetKeyPassphrase)
+	api.Post("/auth/keys/unlock", requireLogin, handler.UnlockKey)
+	api.Delete("/auth/keys/unlock", requireLogin, handler.LockKey)
+	api.Post("/auth/reauth", requireLogin, handler.Reauth)
+
+	// Token management requires an interactive login; API tokens cannot mint more tokens
+	tokens := api.Group("/auth/tokens", requireLogin)
+	tokens.Get("/", handler.ListTokens)
+	tokens.Post("/", handler.CreateToken)
+	tokens.Delete("/:id", handler.DeleteToken)
+
+	// User management; the admin role is checked against the database per request
+	admin := api.Group("/admin", requireLogin, middleware.RequireAdmin(authSvc))
+	admin.Get("/users", handler.AdminListUsers)
+	admin.Post("/users/:id/disable", handler.AdminDisableUser)
+	admin.Post("/users/:id/enable", handler.AdminEnableUser)
+	admin.Delete("/users/:id", handler.AdminDeleteUser)
+	admin.Post("/users/:id/logout", handler.AdminForceLogout)
+	admin.Delete("/users/:id/mfa", handler.AdminResetMFA)
+	admin.Get("/users/:id/stats", handler.AdminUserStats)
+	admin.Put("/users/:id/auth-backend", handler.AdminSetAuthBackend)
+
+	// Organization management takes a login JWT; shared entries themselves are
+	// reached through /vault with the caller's membership role applied
+	orgs := api.Group("/orgs", requireLogin)
+	orgs.Get("/", handler.ListOrgs)
+	orgs.Post("/", handler.CreateOrg)
+	orgs.Get("/:id", handler.GetOrg)
+	orgs.Put("/:id", handler.RenameOrg)
+	orgs.Delete("/:id", handler.DeleteOrg)
+	orgs.Post("/:id/members", handler.AddOrgMember)
+	orgs.Put("/:id/members/:userId", handler.UpdateOrgMember)
+	orgs.Delete("/:id/members/:userId", handler.RemoveOrgMember)
+	orgs.Get("/:id/teams", handler.ListTeams)
+	orgs.Post("/:id/teams", handler.CreateTeam)
+	orgs.Delete("/:id/teams/:teamId", handler.DeleteTeam)
+	orgs.Put("/:id/teams/:teamId/members/:userId", handler.AddTeamMember)
+	orgs.Delete("/:id/teams/:teamId/members/:userId", handler.RemoveTeamMember)
+	orgs.Get("/:id/collections", handler.ListCollections)
+	orgs.Post("/:id/collections", handler.CreateCollection)
+	orgs.Put("/:id/collections/:collectionId", handler.RenameCollection)
+	orgs.Delete("/:id/collections/:collectionId", handler.DeleteCollection)
+	orgs.Put("/:id/collections/:collectionId/teams", handler.SetCollectionTeams)
 
-	vault := api.Group("/vault", middleware.JWT(cfg.JWTSecret))
-	vault.Get("/entries", handler.ListEntries)
-	vault.Post("/entries", handler.CreateEntry)
-	vault.Get("/entries/:id", handler.GetEntry)
-	vault.Put("/entries/:id", handler.UpdateEntry)
-	vault.Delete("/entries/:id", handler.DeleteEntry)
-	vault.Get("/search", handler.SearchEntries)
+	// Scopes are enforced per route: fiber group middleware applies to the whole
+	// prefix, so read and write routes cannot be split into sibling groups
+	canRead := middleware.RequireScope(services.ScopeEntriesRead)
+	canWrite := middleware.RequireScope(services.ScopeEntriesWrite)
+
+	vault := api.Group("/vault", middleware.Auth(cfg.JWTSecret, authSvc, tokenSvc))
+	vault.Get("/entries", canRead, handler.ListEntries)
+	vault.Post("/entries", canWrite, handler.CreateEntry)
+	vault.Get("/entries/:id", canRead, handler.GetEntry)
+	vault.Put("/entries/:id", canWrite, handler.UpdateEntry)
+	vault.Delete("/entries/:id", canWrite, handler.DeleteEntry)
+	vault.Put("/entries/:id/collection", canWrite, handler.MoveEntry)
+	vault.Put("/entries/:id/folder", canWrite, handler.SetEntryFolder)
+	vault.Put("/entries/:id/tags", canWrite, handler.SetEntryTags)
+	vault.Put("/entries/:id/favorite", canRead, handler.FavoriteEntry)
+	vault.Delete("/entries/:id/favorite", canRead, handler.FavoriteEntry)
+	vault.Get("/tags", canRead, handler.SuggestTags)
+	vault.Get("/folders", canRead, handler.ListFolders)
+	vault.Post("/folders", canWrite, handler.CreateFolder)
+	vault.Put("/folders/:id", canWrite, handler.RenameFolder)
+	vault.Put("/folders/:id/parent", canWrite, handler.MoveFolder)
+	vault.Delete("/folders/:id", canWrite, handler.DeleteFolder)
+	vault.Post("/generate", canRead, handler.GeneratePassword)
+	vault.Get("/match", canRead, handler.MatchEntries)
+	vault.Get("/domains", canRead, handler.ListDomainGroups)
+	vault.Post("/domains", canWrite, handler.CreateDomainGroup)
+	vault.Delete("/domains/:id", canWrite, handler.DeleteDomainGroup)
+	vault.Get("/trash", canRead, handler.ListTrash)
+	vault.Post("/trash/:id/restore", canWrite, handler.RestoreTrashed)
+	vault.Delete("/trash/:id", canWrite, handler.PurgeEntry)
+	vault.Get("/entries/:id/history", canRead, handler.EntryHistory)
+	vault.Post("/entries/:id/history/:rev/restore", canWrite, handler.RestoreEntry)
+	vault.Get("/entries/:id/shares", canRead, handler.ListShares)
+	vault.Post("/entries/:id/shares", canWrite, handler.CreateShare)
+	vault.Delete("/entries/:id/shares/:shareId", canWrite, handler.DeleteShare)
+	vault.Post("/entries/:id/send", canRead, handler.SendEntry)
+	vault.Post("/send", canWrite, handler.SendText)
+	vault.Get("/sends", canRead, handler.ListSends)
+	vault.Delete("/sends/:sendId", canWrite, handler.DeleteSend)
+	vault.Post("/entries/:id/access-requests", canRead, handler.RequestAccess)
+	vault.Get("/access-requests", canRead, handler.ListAccessRequests)
+	vault.Get("/entries/:id/checkout", canRead, handler.GetCheckout)
+	vault.Post("/entries/:id/checkout", canWrite, handler.CheckOutEntry)
+	vault.Post("/entries/:id/checkin", canWrite, handler.CheckInEntry)
+	vault.Get("/search", canRead, handler.SearchEntries)
+
+	// Policies narrow entry access per user, team or API token. Managing them
+	// takes an admin login JWT; the dry-run check also accepts API tokens so
+	// integrations can test their own access
+	api.Post("/sys/policy/check", middleware.Auth(cfg.JWTSecret, authSvc, tokenSvc), canRead, handler.CheckPolicy)
+	policies := api.Group("/sys/policies", requireLogin, middleware.RequireAdmin(authSvc))
+	policies.Get("/", handler.ListPolicies)
+	policies.Post("/", handler.CreatePolicy)
+	policies.Get("/:id", handler.GetPolicy)
+	policies.Put("/:id", handler.UpdatePolicy)
+	policies.Delete("/:id", handler.DeletePolicy)
+	policies.Post("/:id/attachments", handler.AttachPolicy)
+	policies.Delete("/:id/attachments/:subjectType/:subjectId", handler.DetachPolicy)
+
+	// Approving access to an entry takes a login JWT; the organization owner
+	// or admin role is checked per request
+	approvals := api.Group("/access-requests", requireLogin)
+	approvals.Get("/pending", handler.ListPendingAccessRequests)
+	approvals.Post("/:id/approve", handler.ApproveAccessRequest)
+	approvals.Post("/:id/deny", handler.DenyAccessRequest)
+
+	// Emergency access is managed with a login JWT. Grantors manage their
+	// contacts; grantees request and then read the grantor's personal entries
+	emergency := api.Group("/emergency", requireLogin)
+	emergency.Get("/contacts", handler.ListEmergencyContacts)
+	emergency.Post("/contacts", handler.AddEmergencyContact)
+	emergency.Put("/contacts/:id", handler.UpdateEmergencyContact)
+	emergency.Delete("/contacts/:id", handler.DeleteEmergencyContact)
+	emergency.Post("/contacts/:id/approve", handler.ApproveEmergencyAccess)
+	emergency.Post("/contacts/:id/reject", handler.RejectEmergencyAccess)
+	emergency.Get("/grants", handler.ListEmergencyGrants)
+	emergency.Post("/grants/:id/request", handler.RequestEmergencyAccess)
+	emergency.Get("/grants/:id/entries", handler.ListEmergencyEntries)
+	emergency.Get("/grants/:id/entries/:entryId", handler.GetEmergencyEntry)
+
+	scheduler := services.NewScheduler()
+	// Expired sends can no longer be opened; this only reclaims their rows
+	scheduler.Every("purge-sends", time.Hour, func() error {
+		_, err := sendSvc.PurgeExpired()
+		return err
+	})
+	scheduler.Every("purge-trash", time.Hour, func() error {
+		_, err := vaultSvc.PurgeTrash()
+		return err
+	})
+	scheduler.Every("emergency-grants", time.Minute, emergencySvc.GrantDue)
+	scheduler.Every("expire-access-grants", time.Minute, accessSvc.ExpireDue)
+	scheduler.Every("expire-checkouts", time.Minute, checkoutSvc.ExpireDue)
+	scheduler.Start()
 
 	// Graceful shutdown with context
 	go func() {
@@ -85,6 +320,10 @@ func main() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
+		if err := scheduler.Shutdown(shutdownCtx); err != nil {
+			log.Printf("scheduler shutdown error: %v", err)
+		}
+
 		if err := auditSvc.Shutdown(shutdownCtx); err != nil {
 			log.Printf("audit shutdown error: %v", err)
 		}
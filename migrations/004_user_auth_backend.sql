@@ -0,0 +1,2 @@
+-- NULL means the user follows the global AUTH_BACKEND setting.
+ALTER TABLE users ADD COLUMN auth_backend TEXT;
@@ -0,0 +1,19 @@
+-- Random WebAuthn user handle; assigned on first passkey registration.
+ALTER TABLE users ADD COLUMN webauthn_handle TEXT;
+-- When set, password logins must be completed with a passkey assertion.
+ALTER TABLE users ADD COLUMN webauthn_mfa INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_webauthn_handle ON users(webauthn_handle);
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  credential_id TEXT NOT NULL UNIQUE,
+  credential_json TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  last_used_at TEXT,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_id);
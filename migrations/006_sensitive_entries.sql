@@ -0,0 +1,2 @@
+-- Sensitive entries are only decrypted for sessions that re-authenticated recently.
+ALTER TABLE vault_entries ADD COLUMN sensitive INTEGER NOT NULL DEFAULT 0;
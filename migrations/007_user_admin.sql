@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN disabled_at TEXT;
+-- Tokens issued at or before this instant are rejected (forced logout).
+ALTER TABLE users ADD COLUMN sessions_valid_after TEXT;
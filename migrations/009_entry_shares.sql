@@ -0,0 +1,30 @@
+-- Per-entry data key, wrapped with the server key. NULL for entries written
+-- before per-entry keys, whose fields are encrypted with the server key itself.
+ALTER TABLE vault_entries ADD COLUMN key_enc TEXT;
+
+-- X25519 key pair per user; the private key is wrapped with a key derived
+-- for that user.
+CREATE TABLE IF NOT EXISTS user_keys (
+  user_id INTEGER PRIMARY KEY,
+  public_key TEXT NOT NULL,
+  private_key_enc TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- key_sealed is the entry's data key sealed to the recipient's public key.
+CREATE TABLE IF NOT EXISTS entry_shares (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  owner_id INTEGER NOT NULL,
+  recipient_id INTEGER NOT NULL,
+  permission TEXT NOT NULL,
+  key_sealed TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  UNIQUE (entry_id, recipient_id),
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE,
+  FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (recipient_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_entry_shares_recipient ON entry_shares(recipient_id);
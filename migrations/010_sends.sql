@@ -0,0 +1,17 @@
+-- One-time secret links. The key needed to decrypt ciphertext is only ever
+-- part of the link's URL fragment and is not stored.
+CREATE TABLE IF NOT EXISTS sends (
+  id TEXT PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  entry_id INTEGER,
+  ciphertext TEXT NOT NULL,
+  has_passphrase INTEGER NOT NULL DEFAULT 0,
+  max_views INTEGER NOT NULL,
+  views INTEGER NOT NULL DEFAULT 0,
+  expires_at TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sends_user ON sends(user_id);
+CREATE INDEX IF NOT EXISTS idx_sends_expires ON sends(expires_at);
@@ -0,0 +1,19 @@
+-- status is idle, requested or granted. A request is granted automatically
+-- at grant_at unless the grantor rejects it first.
+CREATE TABLE IF NOT EXISTS emergency_contacts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  grantor_id INTEGER NOT NULL,
+  grantee_id INTEGER NOT NULL,
+  wait_days INTEGER NOT NULL,
+  status TEXT NOT NULL DEFAULT 'idle',
+  requested_at TEXT,
+  grant_at TEXT,
+  granted_at TEXT,
+  created_at TEXT NOT NULL,
+  UNIQUE (grantor_id, grantee_id),
+  FOREIGN KEY (grantor_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (grantee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_emergency_contacts_grantee ON emergency_contacts(grantee_id);
+CREATE INDEX IF NOT EXISTS idx_emergency_contacts_due ON emergency_contacts(status, grant_at);
@@ -0,0 +1,24 @@
+-- Collection entries with requires_approval can only be read by organization
+-- owners and admins, or by members holding an approved, unexpired request.
+ALTER TABLE vault_entries ADD COLUMN requires_approval INTEGER NOT NULL DEFAULT 0;
+
+-- user_id is the requester. status is pending, approved, denied or expired;
+-- an approved request grants access until expires_at.
+CREATE TABLE IF NOT EXISTS access_requests (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  justification TEXT NOT NULL,
+  duration_minutes INTEGER NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',
+  decided_by INTEGER,
+  decision_note TEXT NOT NULL DEFAULT '',
+  decided_at TEXT,
+  expires_at TEXT,
+  created_at TEXT NOT NULL,
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_requests_entry ON access_requests(entry_id, user_id, status);
+CREATE INDEX IF NOT EXISTS idx_access_requests_user ON access_requests(user_id);
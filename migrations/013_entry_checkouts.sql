@@ -0,0 +1,21 @@
+-- Entries with checkout_required are revealed only to the user holding an
+-- active checkout; the password is rotated when the checkout ends.
+-- password_version counts password changes.
+ALTER TABLE vault_entries ADD COLUMN checkout_required INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE vault_entries ADD COLUMN password_version INTEGER NOT NULL DEFAULT 1;
+
+-- status is active, returned or expired. At most one active checkout per entry.
+CREATE TABLE IF NOT EXISTS entry_checkouts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  status TEXT NOT NULL DEFAULT 'active',
+  checked_out_at TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  checked_in_at TEXT,
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_entry_checkouts_active ON entry_checkouts(entry_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_entry_checkouts_expiry ON entry_checkouts(status, expires_at);
@@ -0,0 +1,21 @@
+-- document is the policy's JSON rules. Policies narrow what their subjects
+-- may do with the entries they can reach.
+CREATE TABLE IF NOT EXISTS policies (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  document TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+-- subject_type is user, team or token.
+CREATE TABLE IF NOT EXISTS policy_attachments (
+  policy_id INTEGER NOT NULL,
+  subject_type TEXT NOT NULL,
+  subject_id INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (policy_id, subject_type, subject_id),
+  FOREIGN KEY (policy_id) REFERENCES policies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_attachments_subject ON policy_attachments(subject_type, subject_id);
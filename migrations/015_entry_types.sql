@@ -0,0 +1,7 @@
+-- type selects the shape of an entry: login, note, card, identity, ssh_key
+-- or api_key. Type-specific fields are stored as JSON encrypted with the
+-- entry key in data_enc; logins keep using password_enc.
+ALTER TABLE vault_entries ADD COLUMN type TEXT NOT NULL DEFAULT 'login';
+ALTER TABLE vault_entries ADD COLUMN data_enc TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_vault_entries_type ON vault_entries(type);
@@ -0,0 +1,16 @@
+-- Custom fields on entries, in display order. value holds text, "true" or
+-- "false", or a URL; for hidden fields it is encrypted with the entry key.
+-- Linked fields store no value and name a field of another entry instead.
+CREATE TABLE IF NOT EXISTS entry_fields (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  position INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  type TEXT NOT NULL,
+  value TEXT NOT NULL DEFAULT '',
+  linked_entry_id INTEGER,
+  linked_field TEXT,
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_entry_fields_entry ON entry_fields(entry_id, position);
@@ -0,0 +1,17 @@
+-- Earlier versions of entries. Each row is the entry as it was before a
+-- change, serialized with its secrets and encrypted as a whole. key_enc is
+-- the entry key the snapshot is encrypted with, NULL for entries without one,
+-- whose snapshots use the server key. changed_by is NULL when the change was
+-- a password rotation at check-in.
+CREATE TABLE IF NOT EXISTS entry_revisions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  revision INTEGER NOT NULL,
+  snapshot_enc TEXT NOT NULL,
+  key_enc TEXT,
+  changed_by INTEGER,
+  created_at TEXT NOT NULL,
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_entry_revisions_entry ON entry_revisions(entry_id, revision);
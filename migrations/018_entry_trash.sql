@@ -0,0 +1,5 @@
+-- Deleted entries go to the trash first: deleted_at is set and they vanish
+-- from every view except the trash until restored or purged.
+ALTER TABLE vault_entries ADD COLUMN deleted_at TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_vault_entries_deleted ON vault_entries(deleted_at) WHERE deleted_at IS NOT NULL;
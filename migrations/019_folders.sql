@@ -0,0 +1,18 @@
+-- Nested folders organizing a user's personal vault. Folder names are unique
+-- among their siblings. Entries in collections are not filed in folders.
+CREATE TABLE IF NOT EXISTS folders (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  parent_id INTEGER,
+  name TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (parent_id) REFERENCES folders(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folders_name ON folders(user_id, COALESCE(parent_id, 0), name COLLATE NOCASE);
+
+ALTER TABLE vault_entries ADD COLUMN folder_id INTEGER REFERENCES folders(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_vault_entries_folder ON vault_entries(folder_id);
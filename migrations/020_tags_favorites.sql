@@ -0,0 +1,19 @@
+-- Tags label entries for everyone who can see them; tags are stored
+-- lowercased. Favorites are personal: each user stars entries for themselves.
+CREATE TABLE IF NOT EXISTS entry_tags (
+  entry_id INTEGER NOT NULL,
+  tag TEXT NOT NULL,
+  PRIMARY KEY (entry_id, tag),
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_entry_tags_tag ON entry_tags(tag);
+
+CREATE TABLE IF NOT EXISTS entry_favorites (
+  user_id INTEGER NOT NULL,
+  entry_id INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (user_id, entry_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE
+);
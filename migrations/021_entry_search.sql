@@ -0,0 +1,28 @@
+-- Full-text index over the plain-text columns of vault_entries. It keeps no
+-- copy of the text (external content) and is kept in step by the triggers
+-- below, so every write path updates it.
+CREATE VIRTUAL TABLE IF NOT EXISTS entry_search USING fts5(
+  title, username, url, category, notes,
+  content='vault_entries', content_rowid='id',
+  tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS entry_search_insert AFTER INSERT ON vault_entries BEGIN
+  INSERT INTO entry_search(rowid, title, username, url, category, notes)
+  VALUES (new.id, new.title, new.username, new.url, new.category, new.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS entry_search_delete AFTER DELETE ON vault_entries BEGIN
+  INSERT INTO entry_search(entry_search, rowid, title, username, url, category, notes)
+  VALUES ('delete', old.id, old.title, old.username, old.url, old.category, old.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS entry_search_update AFTER UPDATE OF title, username, url, category, notes ON vault_entries BEGIN
+  INSERT INTO entry_search(entry_search, rowid, title, username, url, category, notes)
+  VALUES ('delete', old.id, old.title, old.username, old.url, old.category, old.notes);
+  INSERT INTO entry_search(rowid, title, username, url, category, notes)
+  VALUES (new.id, new.title, new.username, new.url, new.category, new.notes);
+END;
+
+-- Index the entries that already exist
+INSERT INTO entry_search(entry_search) VALUES ('rebuild');
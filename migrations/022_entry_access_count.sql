@@ -0,0 +1,3 @@
+-- How often each entry was opened, counted alongside last_accessed_at, so
+-- searches can rank the credentials people use most first.
+ALTER TABLE vault_entries ADD COLUMN access_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,30 @@
+-- Autofill matching. url_match is how an entry's URL is compared with the
+-- page being filled (NULL for the default, base domain). Entries can carry
+-- further URIs, each with its own match mode or NULL for the entry's.
+ALTER TABLE vault_entries ADD COLUMN url_match TEXT;
+
+CREATE TABLE IF NOT EXISTS entry_uris (
+  entry_id INTEGER NOT NULL,
+  position INTEGER NOT NULL,
+  uri TEXT NOT NULL,
+  match_mode TEXT,
+  PRIMARY KEY (entry_id, position),
+  FOREIGN KEY (entry_id) REFERENCES vault_entries(id) ON DELETE CASCADE
+);
+
+-- Groups of base domains a user treats as the same site, such as
+-- google.com and youtube.com. A domain is in at most one of a user's groups.
+CREATE TABLE IF NOT EXISTS domain_groups (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS domain_group_members (
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  domain TEXT NOT NULL,
+  PRIMARY KEY (user_id, domain),
+  FOREIGN KEY (group_id) REFERENCES domain_groups(id) ON DELETE CASCADE
+);
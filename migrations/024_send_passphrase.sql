@@ -0,0 +1,8 @@
+-- Passphrase-protected sends. key_check identifies the link key without
+-- revealing it, so a wrong passphrase can be told from a wrong key;
+-- passphrase_salt stretches the passphrase with Argon2id. Sends are deleted
+-- after too many wrong passphrases. Sends created before this keep NULLs
+-- and their original passphrase derivation.
+ALTER TABLE sends ADD COLUMN key_check TEXT;
+ALTER TABLE sends ADD COLUMN passphrase_salt TEXT;
+ALTER TABLE sends ADD COLUMN failed_attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- The distinct words of the search index per column. Typo-tolerant search
+-- compares query words with these rather than with every entry, then
+-- matches the close ones through the index.
+CREATE VIRTUAL TABLE IF NOT EXISTS entry_search_terms USING fts5vocab(entry_search, 'col');
@@ -0,0 +1,5 @@
+-- Private keys are wrapped with a key stretched from the user's own key
+-- passphrase with Argon2id under kdf_salt. Pairs created before this keep a
+-- NULL salt and their server-derived wrapping until the user sets a
+-- passphrase, which rewraps them in place.
+ALTER TABLE user_keys ADD COLUMN kdf_salt TEXT;
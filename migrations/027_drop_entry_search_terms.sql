@@ -0,0 +1,3 @@
+-- Typo-tolerant search now takes its candidate words from the caller's own
+-- entries, so the index-wide vocabulary is no longer read.
+DROP TABLE IF EXISTS entry_search_terms;